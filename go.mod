@@ -5,6 +5,7 @@ go 1.14
 require (
 	github.com/GoogleCloudPlatform/opentelemetry-operations-go/exporter/trace v0.1.1-0.20200514210843-966afdc5d38c
 	github.com/GoogleContainerTools/kpt v0.24.0
+	github.com/cncf/udpa/go v0.0.0-20200313221541-5f7e5dd04533
 	github.com/envoyproxy/go-control-plane v0.9.6-0.20200515231342-7f3793182f0e
 	github.com/go-delve/delve v1.4.0
 	github.com/go-logr/logr v0.1.0
@@ -13,9 +14,11 @@ require (
 	github.com/google/go-cmp v0.4.0
 	github.com/google/uuid v1.1.1
 	github.com/kelseyhightower/envconfig v1.4.0
+	github.com/prometheus/client_golang v1.0.0
 	go.opentelemetry.io/otel v0.5.0
 	go.opentelemetry.io/otel/exporters/trace/jaeger v0.5.0
 	go.uber.org/zap v1.14.0
+	google.golang.org/genproto v0.0.0-20200303153909-beee998c1893
 	google.golang.org/grpc v1.29.1
 	google.golang.org/protobuf v1.23.0
 	k8s.io/api v0.18.2
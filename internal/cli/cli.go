@@ -0,0 +1,138 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/110y/bootes/internal/server"
+)
+
+const usage = `Usage: bootes [command]
+
+Commands:
+  server    Run the xDS server, which is run without any commands as well.
+  render    Print the resources which a pod would get from the manifests.
+  validate  Validate the Bootes resources in the manifests.
+  diff      Compare the resources which each workload would get from two manifests.
+
+Run 'bootes <command> -h' for the flags of each command.
+`
+
+// Exit codes of the commands.
+const (
+	exitOK = 0
+	// exitFailure is returned on errors, or when the manifests are invalid or different.
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// Run runs the command given by the arguments, and returns the exit code.
+func Run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		server.Run()
+		return exitOK
+	}
+
+	switch args[0] {
+	case "server":
+		server.Run()
+		return exitOK
+	case "render":
+		return runRender(ctx, args[1:], stdin, stdout, stderr)
+	case "validate":
+		return runValidate(args[1:], stdin, stdout, stderr)
+	case "diff":
+		return runDiff(ctx, args[1:], stdin, stdout, stderr)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown command: %s\n\n%s", args[0], usage)
+		return exitUsage
+	}
+}
+
+// filesFlag is the flag which can be given multiple times like `-f a.yaml -f b.yaml`.
+type filesFlag []string
+
+func (f *filesFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *filesFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+// parseFlags parses the flags, and returns the exit code if the command should exit without running.
+func parseFlags(fs *flag.FlagSet, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK, true
+		}
+		return exitUsage, true
+	}
+
+	return 0, false
+}
+
+func runRender(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var files filesFlag
+	fs.Var(&files, "f", "Manifest file or directory, or - for stdin. Can be given multiple times.")
+	pod := fs.String("pod", "", "Pod or WorkloadEntry to render the resources of, as [namespace/]name.")
+	selector := fs.String("labels", "", "Labels of the pod like app=envoy,tier=frontend, which are used instead of the ones in the manifests.")
+	typeURL := fs.String("type-url", "", "Type URL to render the resources of. All types are rendered if empty.")
+
+	if code, exit := parseFlags(fs, args); exit {
+		return code
+	}
+
+	if len(files) == 0 || *pod == "" {
+		fmt.Fprintln(stderr, "-f and --pod are required")
+		fs.Usage()
+		return exitUsage
+	}
+
+	objects, err := loadManifests(files, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load manifests: %s\n", err)
+		return exitFailure
+	}
+
+	m, err := newManifests(objects)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to read manifests: %s\n", err)
+		return exitFailure
+	}
+
+	w, err := m.workload(ctx, *pod, *selector)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to find workload: %s\n", err)
+		return exitFailure
+	}
+
+	dump, err := m.render(ctx, w, *typeURL)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to render resources: %s\n", err)
+		return exitFailure
+	}
+	m.warnInvalid(stderr)
+
+	b, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to encode resources: %s\n", err)
+		return exitFailure
+	}
+
+	fmt.Fprintln(stdout, string(b))
+
+	return exitOK
+}
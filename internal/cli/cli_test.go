@@ -0,0 +1,187 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/110y/bootes/internal/cli"
+	"github.com/110y/bootes/internal/xds/debug"
+)
+
+func TestRunRender(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		args             []string
+		expectedCode     int
+		expectedClusters []string
+		expectedLabels   map[string]string
+	}{
+		"should render resources selected by the labels of the pod": {
+			args:             []string{"render", "-f", "testdata/old", "--pod", "envoy/envoy-1"},
+			expectedCode:     0,
+			expectedClusters: []string{"cluster-1", "cluster-2"},
+			expectedLabels:   map[string]string{"app": "envoy"},
+		},
+		"should render resources selected by the labels of the workload entry": {
+			args:             []string{"render", "-f", "testdata/old", "--pod", "envoy/vm-1"},
+			expectedCode:     0,
+			expectedClusters: []string{"cluster-1"},
+			expectedLabels:   map[string]string{"app": "vm"},
+		},
+		"should render resources selected by the given labels": {
+			args:             []string{"render", "-f", "testdata/old/cluster.yaml", "--pod", "envoy/envoy-2", "--labels", "app=envoy"},
+			expectedCode:     0,
+			expectedClusters: []string{"cluster-1", "cluster-2"},
+			expectedLabels:   map[string]string{"app": "envoy"},
+		},
+		"should render resources exported from other namespaces": {
+			args:             []string{"render", "-f", "testdata/old", "-f", "testdata/exported.yaml", "--pod", "envoy/envoy-1"},
+			expectedCode:     0,
+			expectedClusters: []string{"cluster-1", "cluster-2", "cluster-3"},
+			expectedLabels:   map[string]string{"app": "envoy"},
+		},
+		"should fail if the pod is not in the manifests": {
+			args:         []string{"render", "-f", "testdata/old/cluster.yaml", "--pod", "envoy/envoy-1"},
+			expectedCode: 1,
+		},
+		"should fail without the pod": {
+			args:         []string{"render", "-f", "testdata/old"},
+			expectedCode: 2,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			code := cli.Run(context.Background(), append(test.args, "--type-url", resourcev3.ClusterType), nil, &stdout, &stderr)
+			if code != test.expectedCode {
+				t.Fatalf("unexpected exit code: %d, stderr: %s", code, stderr.String())
+			}
+
+			if code != 0 {
+				return
+			}
+
+			var dump debug.ConfigDump
+			if err := json.Unmarshal(stdout.Bytes(), &dump); err != nil {
+				t.Fatalf("failed to decode output: %s", err)
+			}
+
+			if diff := cmp.Diff(test.expectedLabels, dump.Workload.Labels); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+
+			clusters := []string{}
+			for name := range dump.Resources[0].Resources {
+				clusters = append(clusters, name)
+			}
+			sort.Strings(clusters)
+			if diff := cmp.Diff(test.expectedClusters, clusters); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		files          []string
+		expectedCode   int
+		expectedOutput string
+	}{
+		"should succeed if all resources are valid": {
+			files:        []string{"testdata/old"},
+			expectedCode: 0,
+			expectedOutput: "Cluster envoy/cluster-1: valid\n" +
+				"Cluster envoy/cluster-2: valid\n",
+		},
+		"should fail if any resource is invalid": {
+			files:        []string{"testdata/invalid.yaml", "testdata/old/cluster.yaml"},
+			expectedCode: 1,
+			expectedOutput: "Cluster envoy/cluster-1: invalid: spec.config.name: value length must be at least 1 bytes\n" +
+				"Cluster envoy/cluster-1: valid\n" +
+				"Cluster envoy/cluster-2: valid\n",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			args := []string{"validate"}
+			for _, f := range test.files {
+				args = append(args, "-f", f)
+			}
+
+			var stdout, stderr bytes.Buffer
+			if code := cli.Run(context.Background(), args, nil, &stdout, &stderr); code != test.expectedCode {
+				t.Errorf("unexpected exit code: %d, stderr: %s", code, stderr.String())
+			}
+
+			if diff := cmp.Diff(test.expectedOutput, stdout.String()); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunDiff(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		args           []string
+		expectedCode   int
+		expectedOutput string
+	}{
+		"should report resources added and removed for each workload": {
+			args:         []string{"diff", "testdata/old", "testdata/new"},
+			expectedCode: 1,
+			expectedOutput: "workload envoy-1.envoy:\n" +
+				"  " + resourcev3.ClusterType + ":\n" +
+				"    - cluster-2\n" +
+				"workload vm-1.envoy:\n" +
+				"  " + resourcev3.ClusterType + ":\n" +
+				"    + cluster-2\n",
+		},
+		"should compare only the given pod": {
+			args:         []string{"diff", "--pod", "envoy/vm-1", "testdata/old", "testdata/new"},
+			expectedCode: 1,
+			expectedOutput: "workload vm-1.envoy:\n" +
+				"  " + resourcev3.ClusterType + ":\n" +
+				"    + cluster-2\n",
+		},
+		"should succeed if nothing has changed": {
+			args:         []string{"diff", "testdata/old", "testdata/old"},
+			expectedCode: 0,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+			if code := cli.Run(context.Background(), test.args, nil, &stdout, &stderr); code != test.expectedCode {
+				t.Errorf("unexpected exit code: %d, stderr: %s", code, stderr.String())
+			}
+
+			if diff := cmp.Diff(test.expectedOutput, stdout.String()); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/110y/bootes/internal/xds/debug"
+	"github.com/110y/bootes/internal/xds/workload"
+)
+
+// runDiff compares the resources which each workload would get from the old and the new manifests,
+// and exits with exitFailure if any of them differ in the same way as diff(1).
+func runDiff(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: bootes diff [flags] OLD NEW")
+		fs.PrintDefaults()
+	}
+
+	pod := fs.String("pod", "", "Pod or WorkloadEntry to compare the resources of, as [namespace/]name. All pods and WorkloadEntries in the manifests are compared if empty.")
+	selector := fs.String("labels", "", "Labels of the pod like app=envoy,tier=frontend, which are used instead of the ones in the manifests.")
+	typeURL := fs.String("type-url", "", "Type URL to compare the resources of. All types are compared if empty.")
+
+	if code, exit := parseFlags(fs, args); exit {
+		return code
+	}
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	before, err := loadDiffManifests(fs.Arg(0), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load %s: %s\n", fs.Arg(0), err)
+		return exitUsage
+	}
+
+	after, err := loadDiffManifests(fs.Arg(1), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load %s: %s\n", fs.Arg(1), err)
+		return exitUsage
+	}
+
+	pairs, err := diffWorkloads(ctx, before, after, *pod, *selector)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to find workloads: %s\n", err)
+		return exitUsage
+	}
+
+	code := exitOK
+	for _, p := range pairs {
+		beforeDump, err := before.render(ctx, p.before, *typeURL)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to render resources of %s from %s: %s\n", p.before.Node, fs.Arg(0), err)
+			return exitUsage
+		}
+
+		afterDump, err := after.render(ctx, p.after, *typeURL)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to render resources of %s from %s: %s\n", p.after.Node, fs.Arg(1), err)
+			return exitUsage
+		}
+
+		diff, err := diffConfigDumps(beforeDump, afterDump)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to compare resources of %s: %s\n", p.after.Node, err)
+			return exitUsage
+		}
+
+		if diff == "" {
+			continue
+		}
+
+		fmt.Fprintf(stdout, "workload %s:\n%s", p.after.Node, diff)
+		code = exitFailure
+	}
+
+	before.warnInvalid(stderr)
+	after.warnInvalid(stderr)
+
+	return code
+}
+
+func loadDiffManifests(path string, stdin io.Reader) (*manifests, error) {
+	objects, err := loadManifests([]string{path}, stdin)
+	if err != nil {
+		return nil, err
+	}
+
+	return newManifests(objects)
+}
+
+// workloadPair is the workload of a node in the old and the new manifests, whose labels may differ.
+type workloadPair struct {
+	before *workload.Workload
+	after  *workload.Workload
+}
+
+// diffWorkloads returns the workloads of the pod, or the ones of all pods and WorkloadEntries in either manifests sorted by their nodes.
+// Workloads which exist only in one of the manifests are compared with the same labels.
+func diffWorkloads(ctx context.Context, before, after *manifests, pod, selector string) ([]workloadPair, error) {
+	if pod != "" {
+		b, berr := before.workload(ctx, pod, selector)
+		a, aerr := after.workload(ctx, pod, selector)
+		switch {
+		case berr != nil && aerr != nil:
+			return nil, aerr
+		case berr != nil:
+			b = a
+		case aerr != nil:
+			a = b
+		}
+
+		return []workloadPair{{before: b, after: a}}, nil
+	}
+
+	beforeWorkloads, err := before.workloads(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	afterWorkloads, err := after.workloads(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := map[string]*workloadPair{}
+	for _, w := range beforeWorkloads {
+		pairs[w.Node] = &workloadPair{before: w, after: w}
+	}
+	for _, w := range afterWorkloads {
+		if p, ok := pairs[w.Node]; ok {
+			p.after = w
+			continue
+		}
+		pairs[w.Node] = &workloadPair{before: w, after: w}
+	}
+
+	nodes := make([]string, 0, len(pairs))
+	for node := range pairs {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	results := make([]workloadPair, 0, len(nodes))
+	for _, node := range nodes {
+		results = append(results, *pairs[node])
+	}
+
+	return results, nil
+}
+
+// diffConfigDumps returns the resources added (+), removed (-) and changed (~) by type URL, or an empty string if nothing has changed.
+// Versions are not compared since they are derived from the resources.
+func diffConfigDumps(before, after *debug.ConfigDump) (string, error) {
+	beforeResources := map[string]map[string]json.RawMessage{}
+	for _, r := range before.Resources {
+		beforeResources[r.TypeURL] = r.Resources
+	}
+
+	var buf bytes.Buffer
+	for _, r := range after.Resources {
+		diff, err := diffResources(beforeResources[r.TypeURL], r.Resources)
+		if err != nil {
+			return "", fmt.Errorf("failed to compare %s: %w", r.TypeURL, err)
+		}
+
+		if diff == "" {
+			continue
+		}
+
+		fmt.Fprintf(&buf, "  %s:\n%s", r.TypeURL, diff)
+	}
+
+	return buf.String(), nil
+}
+
+func diffResources(before, after map[string]json.RawMessage) (string, error) {
+	names := make([]string, 0, len(before)+len(after))
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		o, inOld := before[name]
+		n, inNew := after[name]
+
+		switch {
+		case !inOld:
+			fmt.Fprintf(&buf, "    + %s\n", name)
+		case !inNew:
+			fmt.Fprintf(&buf, "    - %s\n", name)
+		default:
+			// NOTE: compares decoded JSON since the output of protojson is not stable.
+			var ov, nv interface{}
+			if err := json.Unmarshal(o, &ov); err != nil {
+				return "", err
+			}
+			if err := json.Unmarshal(n, &nv); err != nil {
+				return "", err
+			}
+
+			diff := cmp.Diff(ov, nv)
+			if diff == "" {
+				continue
+			}
+
+			fmt.Fprintf(&buf, "    ~ %s (-old, +new)\n", name)
+			for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+				fmt.Fprintf(&buf, "      %s\n", line)
+			}
+		}
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,292 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+)
+
+const defaultNamespace = "default"
+
+// loadManifests reads objects from the files, the files in the directories and stdin given by `-`, in the same way as `kubectl apply -f`.
+// Objects without namespaces are put in the default namespace.
+func loadManifests(paths []string, stdin io.Reader) ([]*unstructured.Unstructured, error) {
+	objects := []*unstructured.Unstructured{}
+	for _, path := range paths {
+		if path == "-" {
+			objs, err := decodeManifest(stdin)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode manifest from stdin: %w", err)
+			}
+			objects = append(objects, objs...)
+			continue
+		}
+
+		files, err := manifestFiles(path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			objs, err := decodeManifestFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode manifest %s: %w", file, err)
+			}
+			objects = append(objects, objs...)
+		}
+	}
+
+	for _, o := range objects {
+		if o.GetNamespace() == "" {
+			o.SetNamespace(defaultNamespace)
+		}
+	}
+
+	return objects, nil
+}
+
+// manifestFiles returns the path if it is a file, or the YAML and JSON files right under it if it is a directory.
+func manifestFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		switch filepath.Ext(e.Name()) {
+		case ".yaml", ".yml", ".json":
+			files = append(files, filepath.Join(path, e.Name()))
+		}
+	}
+
+	return files, nil
+}
+
+func decodeManifestFile(path string) ([]*unstructured.Unstructured, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return decodeManifest(f)
+}
+
+// decodeManifest decodes the YAML documents or the JSON objects, expanding items of `List`s.
+func decodeManifest(r io.Reader) ([]*unstructured.Unstructured, error) {
+	d := yaml.NewYAMLOrJSONDecoder(r, 4096)
+
+	objects := []*unstructured.Unstructured{}
+	for {
+		var object map[string]interface{}
+		if err := d.Decode(&object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, err
+		}
+
+		// NOTE: empty documents, e.g. the one before the first `---`, are decoded into nil.
+		if object == nil {
+			continue
+		}
+
+		u := &unstructured.Unstructured{Object: object}
+		if !u.IsList() {
+			objects = append(objects, u)
+			continue
+		}
+
+		list, err := u.ToList()
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range list.Items {
+			objects = append(objects, &list.Items[i])
+		}
+	}
+}
+
+func newScheme() (*runtime.Scheme, error) {
+	s := runtime.NewScheme()
+	if err := scheme.AddToScheme(s); err != nil {
+		return nil, fmt.Errorf("failed to create new scheme: %w", err)
+	}
+	if err := api.AddToScheme(s); err != nil {
+		return nil, fmt.Errorf("failed to add scheme to apiv1: %w", err)
+	}
+
+	return s, nil
+}
+
+var (
+	_ client.Reader       = (*manifestClient)(nil)
+	_ client.FieldIndexer = (*manifestClient)(nil)
+)
+
+// manifestClient reads the objects loaded from manifests instead of the API server, so that the store can be used offline.
+// Only the methods which the store uses to read objects are implemented.
+type manifestClient struct {
+	client.Client
+	scheme  *runtime.Scheme
+	objects []*unstructured.Unstructured
+	indexes map[indexKey]client.IndexerFunc
+}
+
+type indexKey struct {
+	gvk   schema.GroupVersionKind
+	field string
+}
+
+func newManifestClient(s *runtime.Scheme, objects []*unstructured.Unstructured) *manifestClient {
+	return &manifestClient{
+		scheme:  s,
+		objects: objects,
+		indexes: map[indexKey]client.IndexerFunc{},
+	}
+}
+
+// IndexField registers the field which lists can be filtered by with exact matches, in the same way as the informer cache.
+func (c *manifestClient) IndexField(_ context.Context, obj runtime.Object, field string, extractValue client.IndexerFunc) error {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return err
+	}
+
+	c.indexes[indexKey{gvk: gvk, field: field}] = extractValue
+
+	return nil
+}
+
+func (c *manifestClient) Get(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+	gvk, err := apiutil.GVKForObject(obj, c.scheme)
+	if err != nil {
+		return err
+	}
+
+	for _, o := range c.objects {
+		if o.GroupVersionKind() != gvk || o.GetNamespace() != key.Namespace || o.GetName() != key.Name {
+			continue
+		}
+
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			u.Object = o.DeepCopy().Object
+			return nil
+		}
+
+		return runtime.DefaultUnstructuredConverter.FromUnstructured(o.DeepCopy().Object, obj)
+	}
+
+	return apierrors.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: gvk.Kind}, key.Name)
+}
+
+func (c *manifestClient) List(_ context.Context, list runtime.Object, opts ...client.ListOption) error {
+	gvk, err := apiutil.GVKForObject(list, c.scheme)
+	if err != nil {
+		return err
+	}
+	gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+
+	lo := &client.ListOptions{}
+	lo.ApplyOptions(opts)
+
+	var matches func(runtime.Object) bool
+	if lo.FieldSelector != nil {
+		matches, err = c.fieldMatcher(gvk, lo.FieldSelector)
+		if err != nil {
+			return err
+		}
+	}
+
+	items := []unstructured.Unstructured{}
+	for _, o := range c.objects {
+		if o.GroupVersionKind() != gvk {
+			continue
+		}
+
+		if lo.Namespace != "" && o.GetNamespace() != lo.Namespace {
+			continue
+		}
+
+		if lo.LabelSelector != nil && !lo.LabelSelector.Matches(labels.Set(o.GetLabels())) {
+			continue
+		}
+
+		if matches != nil && !matches(o) {
+			continue
+		}
+
+		items = append(items, *o.DeepCopy())
+	}
+
+	if u, ok := list.(*unstructured.UnstructuredList); ok {
+		u.Items = items
+		return nil
+	}
+
+	content := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		content = append(content, item.Object)
+	}
+
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(map[string]interface{}{"items": content}, list)
+}
+
+// fieldMatcher returns the function which reports whether the object has the indexed value of the field selector.
+// Only selectors of exact matches on indexed fields are supported, in the same way as the informer cache.
+func (c *manifestClient) fieldMatcher(gvk schema.GroupVersionKind, selector fields.Selector) (func(runtime.Object) bool, error) {
+	requirements := selector.Requirements()
+	if len(requirements) != 1 || (requirements[0].Operator != selection.Equals && requirements[0].Operator != selection.DoubleEquals) {
+		return nil, fmt.Errorf("non-exact field matches are not supported: %s", selector)
+	}
+	r := requirements[0]
+
+	extract, ok := c.indexes[indexKey{gvk: gvk, field: r.Field}]
+	if !ok {
+		return nil, fmt.Errorf("field %s of %s is not indexed", r.Field, gvk.Kind)
+	}
+
+	return func(obj runtime.Object) bool {
+		for _, v := range extract(obj) {
+			if v == r.Value {
+				return true
+			}
+		}
+
+		return false
+	}, nil
+}
@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	xdscache "github.com/envoyproxy/go-control-plane/pkg/cache/v2"
+	xdscachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/xds/ack"
+	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/debug"
+	"github.com/110y/bootes/internal/xds/nodeid"
+	"github.com/110y/bootes/internal/xds/snapshot"
+	"github.com/110y/bootes/internal/xds/workload"
+)
+
+// manifests are the objects loaded from manifests, and the store and the resolver reading them.
+type manifests struct {
+	store    store.Store
+	resolver *workload.Resolver
+
+	// invalid is the errors of the resources skipped by the store since they are invalid, by `Kind namespace/name`.
+	invalid map[string]error
+}
+
+func newManifests(objects []*unstructured.Unstructured) (*manifests, error) {
+	s, err := newScheme()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &manifests{invalid: map[string]error{}}
+
+	c := newManifestClient(s, objects)
+	if err := store.IndexFields(context.Background(), c); err != nil {
+		return nil, err
+	}
+
+	m.store = store.New(c, c, store.WithInvalidResourceHandler(func(_ context.Context, object *unstructured.Unstructured, err *store.InvalidResourceError) {
+		m.invalid[fmt.Sprintf("%s %s/%s", object.GetKind(), object.GetNamespace(), object.GetName())] = err
+	}))
+	m.resolver = workload.NewResolver(m.store, workload.NewRegistry(), nodeid.NameNamespace{})
+
+	return m, nil
+}
+
+// workload returns the workload of the pod or the WorkloadEntry given by `namespace/name`.
+// The labels are used instead of the ones of the pod if they are not empty, in which case the pod does not have to be in the manifests.
+func (m *manifests) workload(ctx context.Context, pod, selector string) (*workload.Workload, error) {
+	namespace, name, err := parsePod(pod)
+	if err != nil {
+		return nil, err
+	}
+
+	key := m.resolver.Key(name, namespace)
+
+	if selector != "" {
+		l, err := labels.ConvertSelectorToLabelsMap(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid labels %q: %w", selector, err)
+		}
+
+		return &workload.Workload{
+			Node:      key,
+			Namespace: namespace,
+			Labels:    l,
+		}, nil
+	}
+
+	w, err := m.resolver.Lookup(ctx, key)
+	if errors.Is(err, workload.ErrNotFound) {
+		return nil, fmt.Errorf("pod %s not found in manifests, specify its labels by --labels", pod)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// workloads returns the workloads of all pods and WorkloadEntries in the manifests.
+func (m *manifests) workloads(ctx context.Context) ([]*workload.Workload, error) {
+	return m.resolver.List(ctx, "")
+}
+
+// render builds the resources of the workload in the same way as the xDS server does, and dumps them in the same format as its config dump.
+func (m *manifests) render(ctx context.Context, w *workload.Workload, typeURL string) (*debug.ConfigDump, error) {
+	c := cache.New(
+		xdscache.NewSnapshotCache(false, xdscache.IDHash{}, nil),
+		xdscachev3.NewSnapshotCache(false, xdscachev3.IDHash{}, nil),
+	)
+
+	if err := snapshot.NewBuilder(m.store, c).Build(ctx, w.Node, w.Namespace, w.Labels); err != nil {
+		return nil, fmt.Errorf("failed to build resources: %w", err)
+	}
+
+	r := workload.NewRegistry()
+	r.Set(w)
+	wr := workload.NewResolver(registeredOnly{Store: m.store}, r, nodeid.NameNamespace{})
+
+	t, err := ack.NewTracker(prometheus.NewRegistry())
+	if err != nil {
+		return nil, err
+	}
+
+	return debug.New(noNodes{}, c, m.store, wr, t).ConfigDump(ctx, w.Node, typeURL)
+}
+
+// warnInvalid writes the resources skipped by the store since they are invalid.
+func (m *manifests) warnInvalid(w io.Writer) {
+	keys := make([]string, 0, len(m.invalid))
+	for key := range m.invalid {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(w, "warning: skipped invalid %s: %s\n", key, m.invalid[key])
+	}
+}
+
+// registeredOnly hides pods and WorkloadEntries from the resolver, so that it finds the registered workload,
+// whose labels may be given instead of the ones of its pod.
+type registeredOnly struct {
+	store.Store
+}
+
+func (registeredOnly) GetPod(context.Context, string, string) (*corev1.Pod, error) {
+	return nil, store.ErrNotFound
+}
+
+func (registeredOnly) GetWorkloadEntry(context.Context, string, string) (*api.WorkloadEntry, error) {
+	return nil, store.ErrNotFound
+}
+
+// noNodes has no connected nodes since nothing is served offline.
+type noNodes struct{}
+
+func (noNodes) ConnectedNodes() map[string][]ack.StreamID {
+	return map[string][]ack.StreamID{}
+}
+
+// parsePod parses `namespace/name` in the same way as kubectl, in which the namespace can be omitted.
+func parsePod(pod string) (string, string, error) {
+	parts := strings.Split(pod, "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		return defaultNamespace, parts[0], nil
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return parts[0], parts[1], nil
+	default:
+		return "", "", fmt.Errorf("invalid pod %q, which must be namespace/name", pod)
+	}
+}
@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/k8s/store"
+)
+
+// runValidate validates the Bootes resources in the manifests in the same way as the validating webhook.
+// Other objects, including WorkloadEntries which have no envoy configurations, are ignored.
+func runValidate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var files filesFlag
+	fs.Var(&files, "f", "Manifest file or directory, or - for stdin. Can be given multiple times.")
+
+	if code, exit := parseFlags(fs, args); exit {
+		return code
+	}
+
+	if len(files) == 0 {
+		fmt.Fprintln(stderr, "-f is required")
+		fs.Usage()
+		return exitUsage
+	}
+
+	objects, err := loadManifests(files, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to load manifests: %s\n", err)
+		return exitFailure
+	}
+
+	code := exitOK
+	for _, o := range objects {
+		gvk := o.GroupVersionKind()
+		if gvk.Group != api.GroupVersion.Group || gvk.Kind == api.WorkloadEntryKind {
+			continue
+		}
+
+		if err := store.Validate(o.Object); err != nil {
+			fmt.Fprintf(stdout, "%s %s/%s: invalid: %s\n", gvk.Kind, o.GetNamespace(), o.GetName(), err)
+			code = exitFailure
+			continue
+		}
+
+		fmt.Fprintf(stdout, "%s %s/%s: valid\n", gvk.Kind, o.GetNamespace(), o.GetName())
+	}
+
+	return code
+}
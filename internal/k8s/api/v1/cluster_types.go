@@ -2,6 +2,7 @@ package v1
 
 import (
 	envoyapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -24,18 +25,26 @@ type Cluster struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec ClusterSpec
+	Spec   ClusterSpec
+	Status Status
 }
 
 type ClusterSpec struct {
 	WorkloadSelector *WorkloadSelector `json:"workloadSelector,omitempty"`
+	XDSVersion       XDSVersion        `json:"xdsVersion,omitempty"`
+	ExportTo         []string          `json:"exportTo,omitempty"`
 	Config           *envoyapi.Cluster
+	ConfigV3         *clusterv3.Cluster
 }
 
 func (c *Cluster) GetWorkloadSelector() *WorkloadSelector {
 	return c.Spec.WorkloadSelector
 }
 
+func (c *Cluster) GetExportTo() []string {
+	return c.Spec.ExportTo
+}
+
 func init() {
 	SchemeBuilder.Register(&Cluster{}, &ClusterList{})
 }
@@ -2,6 +2,7 @@ package v1
 
 import (
 	envoyapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -24,18 +25,26 @@ type Endpoint struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec EndpointSpec
+	Spec   EndpointSpec
+	Status Status
 }
 
 type EndpointSpec struct {
 	WorkloadSelector *WorkloadSelector `json:"workloadSelector,omitempty"`
+	XDSVersion       XDSVersion        `json:"xdsVersion,omitempty"`
+	ExportTo         []string          `json:"exportTo,omitempty"`
 	Config           *envoyapi.ClusterLoadAssignment
+	ConfigV3         *endpointv3.ClusterLoadAssignment
 }
 
 func (l *Endpoint) GetWorkloadSelector() *WorkloadSelector {
 	return l.Spec.WorkloadSelector
 }
 
+func (l *Endpoint) GetExportTo() []string {
+	return l.Spec.ExportTo
+}
+
 func init() {
 	SchemeBuilder.Register(&Endpoint{}, &EndpointList{})
 }
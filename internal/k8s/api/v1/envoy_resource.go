@@ -1,5 +1,9 @@
 package v1
 
+// ExportToAllNamespaces is the value of spec.exportTo which exports the resource to all namespaces.
+const ExportToAllNamespaces = "*"
+
 type EnvoyResource interface {
 	GetWorkloadSelector() *WorkloadSelector
+	GetExportTo() []string
 }
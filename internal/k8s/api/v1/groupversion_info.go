@@ -14,8 +14,13 @@ var (
 
 	AddToScheme = SchemeBuilder.AddToScheme
 
-	ClusterKind  = "Cluster"
-	ListenerKind = "Listener"
-	RouteKind    = "Route"
-	EndpointKind = "Endpoint"
+	ClusterKind       = "Cluster"
+	ListenerKind      = "Listener"
+	RouteKind         = "Route"
+	EndpointKind      = "Endpoint"
+	SecretKind        = "Secret"
+	RuntimeKind       = "Runtime"
+	ScopedRouteKind   = "ScopedRoute"
+	VirtualHostKind   = "VirtualHost"
+	WorkloadEntryKind = "WorkloadEntry"
 )
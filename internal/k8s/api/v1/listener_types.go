@@ -2,6 +2,7 @@ package v1
 
 import (
 	envoyapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -24,18 +25,26 @@ type Listener struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec ListenerSpec
+	Spec   ListenerSpec
+	Status Status
 }
 
 type ListenerSpec struct {
 	WorkloadSelector *WorkloadSelector `json:"workloadSelector,omitempty"`
+	XDSVersion       XDSVersion        `json:"xdsVersion,omitempty"`
+	ExportTo         []string          `json:"exportTo,omitempty"`
 	Config           *envoyapi.Listener
+	ConfigV3         *listenerv3.Listener
 }
 
 func (l *Listener) GetWorkloadSelector() *WorkloadSelector {
 	return l.Spec.WorkloadSelector
 }
 
+func (l *Listener) GetExportTo() []string {
+	return l.Spec.ExportTo
+}
+
 func init() {
 	SchemeBuilder.Register(&Listener{}, &ListenerList{})
 }
@@ -2,6 +2,7 @@ package v1
 
 import (
 	envoyapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -24,18 +25,26 @@ type Route struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec RouteSpec
+	Spec   RouteSpec
+	Status Status
 }
 
 type RouteSpec struct {
 	WorkloadSelector *WorkloadSelector `json:"workloadSelector,omitempty"`
+	XDSVersion       XDSVersion        `json:"xdsVersion,omitempty"`
+	ExportTo         []string          `json:"exportTo,omitempty"`
 	Config           *envoyapi.RouteConfiguration
+	ConfigV3         *routev3.RouteConfiguration
 }
 
 func (c *Route) GetWorkloadSelector() *WorkloadSelector {
 	return c.Spec.WorkloadSelector
 }
 
+func (c *Route) GetExportTo() []string {
+	return c.Spec.ExportTo
+}
+
 func init() {
 	SchemeBuilder.Register(&Route{}, &RouteList{})
 }
@@ -0,0 +1,50 @@
+package v1
+
+import (
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
+	runtimev3 "github.com/envoyproxy/go-control-plane/envoy/service/runtime/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+var _ EnvoyResource = (*Runtime)(nil)
+
+// RuntimeList contains a list of Runtime
+type RuntimeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []*Runtime `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Runtime is the Schema for the runtimes API
+// +k8s:openapi-gen=true
+type Runtime struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RuntimeSpec
+	Status Status
+}
+
+type RuntimeSpec struct {
+	WorkloadSelector *WorkloadSelector `json:"workloadSelector,omitempty"`
+	XDSVersion       XDSVersion        `json:"xdsVersion,omitempty"`
+	ExportTo         []string          `json:"exportTo,omitempty"`
+	Config           *discovery.Runtime
+	ConfigV3         *runtimev3.Runtime
+}
+
+func (r *Runtime) GetWorkloadSelector() *WorkloadSelector {
+	return r.Spec.WorkloadSelector
+}
+
+func (r *Runtime) GetExportTo() []string {
+	return r.Spec.ExportTo
+}
+
+func init() {
+	SchemeBuilder.Register(&Runtime{}, &RuntimeList{})
+}
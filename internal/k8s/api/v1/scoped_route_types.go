@@ -0,0 +1,50 @@
+package v1
+
+import (
+	envoyapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+var _ EnvoyResource = (*ScopedRoute)(nil)
+
+// ScopedRouteList contains a list of ScopedRoute
+type ScopedRouteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []*ScopedRoute `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ScopedRoute is the Schema for the scopedroutes API
+// +k8s:openapi-gen=true
+type ScopedRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ScopedRouteSpec
+	Status Status
+}
+
+type ScopedRouteSpec struct {
+	WorkloadSelector *WorkloadSelector `json:"workloadSelector,omitempty"`
+	XDSVersion       XDSVersion        `json:"xdsVersion,omitempty"`
+	ExportTo         []string          `json:"exportTo,omitempty"`
+	Config           *envoyapi.ScopedRouteConfiguration
+	ConfigV3         *routev3.ScopedRouteConfiguration
+}
+
+func (s *ScopedRoute) GetWorkloadSelector() *WorkloadSelector {
+	return s.Spec.WorkloadSelector
+}
+
+func (s *ScopedRoute) GetExportTo() []string {
+	return s.Spec.ExportTo
+}
+
+func init() {
+	SchemeBuilder.Register(&ScopedRoute{}, &ScopedRouteList{})
+}
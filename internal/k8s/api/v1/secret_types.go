@@ -0,0 +1,61 @@
+package v1
+
+import (
+	auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TLSSecretLabel is the label which `kubernetes.io/tls` Secrets referred by spec.tlsSecretRef must have,
+// so that Bootes watches only them rather than all Secrets in the cluster.
+const TLSSecretLabel = "bootes.io/tls-secret"
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+var _ EnvoyResource = (*Secret)(nil)
+
+// SecretList contains a list of Secret
+type SecretList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []*Secret `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Secret is the Schema for the secrets API
+// +k8s:openapi-gen=true
+type Secret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SecretSpec
+	Status Status
+}
+
+type SecretSpec struct {
+	WorkloadSelector *WorkloadSelector   `json:"workloadSelector,omitempty"`
+	XDSVersion       XDSVersion          `json:"xdsVersion,omitempty"`
+	ExportTo         []string            `json:"exportTo,omitempty"`
+	TLSSecretRef     *TLSSecretReference `json:"tlsSecretRef,omitempty"`
+	Config           *auth.Secret
+	ConfigV3         *tlsv3.Secret
+}
+
+// TLSSecretReference refers to a `kubernetes.io/tls` Secret labeled with TLSSecretLabel in the same namespace,
+// whose certificate and private key are used as the tls_certificate of the Secret.
+type TLSSecretReference struct {
+	Name string `json:"name"`
+}
+
+func (s *Secret) GetWorkloadSelector() *WorkloadSelector {
+	return s.Spec.WorkloadSelector
+}
+
+func (s *Secret) GetExportTo() []string {
+	return s.Spec.ExportTo
+}
+
+func init() {
+	SchemeBuilder.Register(&Secret{}, &SecretList{})
+}
@@ -0,0 +1,15 @@
+package v1
+
+// Status is the observed state of Bootes resources.
+type Status struct {
+	// Parsed reports whether spec.config has been parsed as an envoy configuration.
+	Parsed bool `json:"parsed"`
+	// ParseError is the reason why spec.config could not be parsed.
+	ParseError string `json:"parseError,omitempty"`
+	// Nodes is the number of nodes the resource has been pushed to, which counts only the nodes connected to the leader replica.
+	Nodes int32 `json:"nodes"`
+	// LastPushedVersion is the version of the configuration which was last applied to the resources served to the nodes, derived from its contents.
+	LastPushedVersion string `json:"lastPushedVersion,omitempty"`
+	// NACKError is the error which Envoy returned on rejecting the resource.
+	NACKError string `json:"nackError,omitempty"`
+}
@@ -0,0 +1,52 @@
+package v1
+
+import (
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+var _ EnvoyResource = (*VirtualHost)(nil)
+
+// VirtualHostList contains a list of VirtualHost
+type VirtualHostList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []*VirtualHost `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// VirtualHost is the Schema for the virtualhosts API
+// +k8s:openapi-gen=true
+type VirtualHost struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VirtualHostSpec
+	Status Status
+}
+
+type VirtualHostSpec struct {
+	WorkloadSelector *WorkloadSelector `json:"workloadSelector,omitempty"`
+	XDSVersion       XDSVersion        `json:"xdsVersion,omitempty"`
+	ExportTo         []string          `json:"exportTo,omitempty"`
+	// RouteConfigurationName is the name of the RouteConfiguration which fetches this virtual host via VHDS.
+	RouteConfigurationName string `json:"routeConfigurationName"`
+	Config                 *route.VirtualHost
+	ConfigV3               *routev3.VirtualHost
+}
+
+func (v *VirtualHost) GetWorkloadSelector() *WorkloadSelector {
+	return v.Spec.WorkloadSelector
+}
+
+func (v *VirtualHost) GetExportTo() []string {
+	return v.Spec.ExportTo
+}
+
+func init() {
+	SchemeBuilder.Register(&VirtualHost{}, &VirtualHostList{})
+}
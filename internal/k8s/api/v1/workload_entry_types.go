@@ -0,0 +1,35 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// WorkloadEntryList contains a list of WorkloadEntry
+type WorkloadEntryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WorkloadEntry `json:"items"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// WorkloadEntry registers a workload running outside Kubernetes, e.g. Envoy on a VM,
+// whose node ID names the entry in the same way as the ones of pods.
+// +k8s:openapi-gen=true
+type WorkloadEntry struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec WorkloadEntrySpec `json:"spec"`
+}
+
+type WorkloadEntrySpec struct {
+	// Labels are matched against workloadSelector of resources in the same way as the labels of pods.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WorkloadEntry{}, &WorkloadEntryList{})
+}
@@ -1,5 +1,19 @@
 package v1
 
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
 type WorkloadSelector struct {
-	Labels map[string]string `json:"labels"`
+	Labels           map[string]string                 `json:"labels,omitempty"`
+	MatchExpressions []metav1.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+}
+
+// AsSelector converts the WorkloadSelector into a labels.Selector, which matches pods in the same way as metav1.LabelSelector.
+func (w *WorkloadSelector) AsSelector() (labels.Selector, error) {
+	return metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+		MatchLabels:      w.Labels,
+		MatchExpressions: w.MatchExpressions,
+	})
 }
@@ -0,0 +1,9 @@
+package v1
+
+// XDSVersion is the version of the Envoy API which spec.config is written in.
+type XDSVersion string
+
+const (
+	XDSVersionV2 XDSVersion = "v2"
+	XDSVersionV3 XDSVersion = "v3"
+)
@@ -1,6 +1,11 @@
 package k8s
 
 type ManagerConfig struct {
-	HealthzServerPort int
-	MetricsServerPort int
+	HealthzServerPort       int
+	MetricsServerPort       int
+	WebhookServerPort       int
+	WebhookCertDir          string
+	LeaderElection          bool
+	LeaderElectionNamespace string
+	LeaderElectionID        string
 }
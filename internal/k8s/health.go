@@ -0,0 +1,128 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	informersCheckName  = "informers"
+	reconcilesCheckName = "reconciles"
+)
+
+// AddHealthChecks registers the readiness and the liveness of a component running alongside the manager, e.g. the xDS server.
+func AddHealthChecks(mgr manager.Manager, name string, ready, alive func() error) error {
+	if err := mgr.AddReadyzCheck(name, func(*http.Request) error { return ready() }); err != nil {
+		return fmt.Errorf("failed to register readyz checker of %s: %w", name, err)
+	}
+
+	if err := mgr.AddHealthzCheck(name, func(*http.Request) error { return alive() }); err != nil {
+		return fmt.Errorf("failed to register healthz checker of %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// syncedInformers checks that the informers of the objects watched by the controllers have synced,
+// so that resources of nodes are not built from a part of the resources.
+type syncedInformers struct {
+	informers map[string]cache.Informer
+}
+
+// newSyncedInformers gets the informers of the objects beforehand since the controllers create them only when they start.
+func newSyncedInformers(c cache.Cache, objects map[string]runtime.Object) (*syncedInformers, error) {
+	informers := make(map[string]cache.Informer, len(objects))
+	for name, obj := range objects {
+		i, err := c.GetInformer(context.Background(), obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get informer of %s: %w", name, err)
+		}
+
+		informers[name] = i
+	}
+
+	return &syncedInformers{informers: informers}, nil
+}
+
+func (s *syncedInformers) check(*http.Request) error {
+	for name, i := range s.informers {
+		if !i.HasSynced() {
+			return fmt.Errorf("informer of %s has not synced yet", name)
+		}
+	}
+
+	return nil
+}
+
+// reconcileMonitor tracks reconciles in progress to detect the ones which never finish, e.g. by deadlocks,
+// since the controller stops processing the resource of a wedged reconcile silently.
+type reconcileMonitor struct {
+	threshold time.Duration
+
+	mu       sync.Mutex
+	lastID   uint64
+	inFlight map[uint64]*inFlightReconcile
+}
+
+type inFlightReconcile struct {
+	controller string
+	request    reconcile.Request
+	startedAt  time.Time
+}
+
+func newReconcileMonitor(threshold time.Duration) *reconcileMonitor {
+	return &reconcileMonitor{
+		threshold: threshold,
+		inFlight:  map[uint64]*inFlightReconcile{},
+	}
+}
+
+func (m *reconcileMonitor) wrap(controller string, r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(req reconcile.Request) (reconcile.Result, error) {
+		done := m.start(controller, req)
+		defer done()
+
+		return r.Reconcile(req)
+	})
+}
+
+func (m *reconcileMonitor) start(controller string, req reconcile.Request) func() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastID++
+	id := m.lastID
+	m.inFlight[id] = &inFlightReconcile{
+		controller: controller,
+		request:    req,
+		startedAt:  time.Now(),
+	}
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		delete(m.inFlight, id)
+	}
+}
+
+func (m *reconcileMonitor) check(*http.Request) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.inFlight {
+		if d := time.Since(r.startedAt); d > m.threshold {
+			return fmt.Errorf("%s has been reconciling %s for %s", r.controller, r.request.NamespacedName, d)
+		}
+	}
+
+	return nil
+}
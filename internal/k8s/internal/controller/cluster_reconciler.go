@@ -6,77 +6,113 @@ import (
 	"fmt"
 
 	"github.com/go-logr/logr"
-	"github.com/google/uuid"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	api "github.com/110y/bootes/internal/k8s/api/v1"
 	"github.com/110y/bootes/internal/k8s/store"
 	"github.com/110y/bootes/internal/observer/trace"
 	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/workload"
 )
 
 var _ reconcile.Reconciler = (*ClusterReconciler)(nil)
 
-func NewClusterReconciler(s store.Store, c cache.Cache, l logr.Logger) reconcile.Reconciler {
+func NewClusterReconciler(s store.Store, c cache.Cache, q *cache.Queue, wr *workload.Resolver, elected <-chan struct{}, l logr.Logger) reconcile.Reconciler {
 	return &ClusterReconciler{
-		store:  s,
-		cache:  c,
-		logger: l,
+		store:     s,
+		cache:     c,
+		queue:     q,
+		workloads: wr,
+		exports:   newExportedNamespaces(),
+		elected:   elected,
+		logger:    l,
 	}
 }
 
 type ClusterReconciler struct {
-	store  store.Store
-	cache  cache.Cache
-	logger logr.Logger
+	store     store.Store
+	cache     cache.Cache
+	queue     *cache.Queue
+	workloads *workload.Resolver
+	exports   *exportedNamespaces
+	elected   <-chan struct{}
+	logger    logr.Logger
 }
 
 func (r *ClusterReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx, span := trace.NewSpan(context.Background(), "ClusterReconciler.Reconcile")
 	defer span.End()
 
-	version := uuid.New().String()
-	logger := r.logger.WithValues("version", version)
-
-	logger.Info(fmt.Sprintf("Reconciling %s", req.NamespacedName))
+	r.logger.Info(fmt.Sprintf("Reconciling %s", req.NamespacedName))
 
 	opts := []store.ListOption{}
+	var exportTo []string
 	cluster, err := r.store.GetCluster(ctx, req.Name, req.Namespace)
 	if err != nil {
+		var invalid *store.InvalidResourceError
+		if errors.As(err, &invalid) {
+			r.logger.Error(err, "invalid cluster")
+			if err := updateInvalidStatus(ctx, r.store, r.elected, api.ClusterKind, req, invalid); err != nil {
+				r.logger.Error(err, "failed to update status")
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{}, nil
+		}
+
 		if !errors.Is(err, store.ErrNotFound) {
-			logger.Error(err, "failed to get cluster")
+			r.logger.Error(err, "failed to get cluster")
 			return ctrl.Result{}, err
 		}
+
+		// NOTE: the deleted cluster may never be listed again to be pruned.
+		r.store.Forget(api.ClusterKind, req.Name, req.Namespace)
 	} else {
 		if cluster.Spec.WorkloadSelector != nil {
-			opts = append(opts, store.WithLabelFilter(cluster.Spec.WorkloadSelector.Labels))
+			opts = append(opts, store.WithWorkloadSelector(cluster.Spec.WorkloadSelector))
 		}
-	}
 
-	pods, err := r.store.ListPodsByNamespace(ctx, req.Namespace, opts...)
-	if err != nil {
-		logger.Error(err, "failed to list pods")
-		return ctrl.Result{}, err
+		exportTo = cluster.Spec.ExportTo
 	}
 
-	clusters, err := r.store.ListClustersByNamespace(ctx, req.Namespace)
+	workloads, err := listWorkloadsByNamespaces(ctx, r.workloads, r.exports.namespaces(req, exportTo), opts...)
 	if err != nil {
-		logger.Error(err, "failed to list clusters")
+		r.logger.Error(err, "failed to list workloads")
 		return ctrl.Result{}, err
 	}
 
-	for _, pod := range pods.Items {
-		err := r.cache.UpdateClusters(
-			ctx,
-			store.ToNodeName(pod.Name, pod.Namespace),
-			version,
-			store.FilterClustersByLabels(clusters.Items, pod.Labels),
-		)
-		if err != nil {
-			logger.Error(err, "failed to update clusuters")
-			return ctrl.Result{}, err
+	workloads = filterCachedWorkloads(r.cache, workloads)
+
+	clustersByNamespace := map[string][]*api.Cluster{}
+	updates := make(map[string]func(s *cache.Snapshot), len(workloads))
+	for _, w := range workloads {
+		clusters, ok := clustersByNamespace[w.Namespace]
+		if !ok {
+			list, err := r.store.ListClustersByNamespace(ctx, w.Namespace)
+			if err != nil {
+				r.logger.Error(err, "failed to list clusters")
+				return ctrl.Result{}, err
+			}
+
+			clusters = list.Items
+			clustersByNamespace[w.Namespace] = clusters
+		}
+
+		filtered := store.FilterClustersByLabels(clusters, w.Labels)
+		updates[w.Node] = func(s *cache.Snapshot) {
+			s.SetClusters(filtered)
 		}
 	}
 
+	r.exports.set(req, exportTo)
+
+	// NOTE: status is updated once the updates have been applied, rather than when they are enqueued.
+	var onFlushed func(nodes int)
+	if cluster != nil {
+		onFlushed = pushedStatusUpdater(r.store, r.elected, api.ClusterKind, req, cluster.Spec.ConfigV3, r.logger)
+	}
+	r.queue.EnqueueAll(updates, onFlushed)
+
 	return ctrl.Result{}, nil
 }
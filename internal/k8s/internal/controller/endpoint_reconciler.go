@@ -6,77 +6,113 @@ import (
 	"fmt"
 
 	"github.com/go-logr/logr"
-	"github.com/google/uuid"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	api "github.com/110y/bootes/internal/k8s/api/v1"
 	"github.com/110y/bootes/internal/k8s/store"
 	"github.com/110y/bootes/internal/observer/trace"
 	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/workload"
 )
 
 var _ reconcile.Reconciler = (*EndpointReconciler)(nil)
 
-func NewEndpointReconciler(s store.Store, c cache.Cache, l logr.Logger) reconcile.Reconciler {
+func NewEndpointReconciler(s store.Store, c cache.Cache, q *cache.Queue, wr *workload.Resolver, elected <-chan struct{}, l logr.Logger) reconcile.Reconciler {
 	return &EndpointReconciler{
-		store:  s,
-		cache:  c,
-		logger: l,
+		store:     s,
+		cache:     c,
+		queue:     q,
+		workloads: wr,
+		exports:   newExportedNamespaces(),
+		elected:   elected,
+		logger:    l,
 	}
 }
 
 type EndpointReconciler struct {
-	store  store.Store
-	cache  cache.Cache
-	logger logr.Logger
+	store     store.Store
+	cache     cache.Cache
+	queue     *cache.Queue
+	workloads *workload.Resolver
+	exports   *exportedNamespaces
+	elected   <-chan struct{}
+	logger    logr.Logger
 }
 
 func (r *EndpointReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx, span := trace.NewSpan(context.Background(), "EndpointReconciler.Reconcile")
 	defer span.End()
 
-	version := uuid.New().String()
-	logger := r.logger.WithValues("version", version)
-
-	logger.Info(fmt.Sprintf("Reconciling %s", req.NamespacedName))
+	r.logger.Info(fmt.Sprintf("Reconciling %s", req.NamespacedName))
 
 	opts := []store.ListOption{}
+	var exportTo []string
 	endpoint, err := r.store.GetEndpoint(ctx, req.Name, req.Namespace)
 	if err != nil {
+		var invalid *store.InvalidResourceError
+		if errors.As(err, &invalid) {
+			r.logger.Error(err, "invalid endpoint")
+			if err := updateInvalidStatus(ctx, r.store, r.elected, api.EndpointKind, req, invalid); err != nil {
+				r.logger.Error(err, "failed to update status")
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{}, nil
+		}
+
 		if !errors.Is(err, store.ErrNotFound) {
-			logger.Error(err, "failed to get endpoint")
+			r.logger.Error(err, "failed to get endpoint")
 			return ctrl.Result{}, err
 		}
+
+		// NOTE: the deleted endpoint may never be listed again to be pruned.
+		r.store.Forget(api.EndpointKind, req.Name, req.Namespace)
 	} else {
 		if endpoint.Spec.WorkloadSelector != nil {
-			opts = append(opts, store.WithLabelFilter(endpoint.Spec.WorkloadSelector.Labels))
+			opts = append(opts, store.WithWorkloadSelector(endpoint.Spec.WorkloadSelector))
 		}
-	}
 
-	pods, err := r.store.ListPodsByNamespace(ctx, req.Namespace, opts...)
-	if err != nil {
-		logger.Error(err, "failed to list pods")
-		return ctrl.Result{}, err
+		exportTo = endpoint.Spec.ExportTo
 	}
 
-	endpoints, err := r.store.ListEndpointsByNamespace(ctx, req.Namespace)
+	workloads, err := listWorkloadsByNamespaces(ctx, r.workloads, r.exports.namespaces(req, exportTo), opts...)
 	if err != nil {
-		logger.Error(err, "failed to list endpoints")
+		r.logger.Error(err, "failed to list workloads")
 		return ctrl.Result{}, err
 	}
 
-	for _, pod := range pods.Items {
-		err := r.cache.UpdateEndpoints(
-			ctx,
-			store.ToNodeName(pod.Name, pod.Namespace),
-			version,
-			store.FilterEndpointsByLabels(endpoints.Items, pod.Labels),
-		)
-		if err != nil {
-			logger.Error(err, "failed to update clusuters")
-			return ctrl.Result{}, err
+	workloads = filterCachedWorkloads(r.cache, workloads)
+
+	endpointsByNamespace := map[string][]*api.Endpoint{}
+	updates := make(map[string]func(s *cache.Snapshot), len(workloads))
+	for _, w := range workloads {
+		endpoints, ok := endpointsByNamespace[w.Namespace]
+		if !ok {
+			list, err := r.store.ListEndpointsByNamespace(ctx, w.Namespace)
+			if err != nil {
+				r.logger.Error(err, "failed to list endpoints")
+				return ctrl.Result{}, err
+			}
+
+			endpoints = list.Items
+			endpointsByNamespace[w.Namespace] = endpoints
+		}
+
+		filtered := store.FilterEndpointsByLabels(endpoints, w.Labels)
+		updates[w.Node] = func(s *cache.Snapshot) {
+			s.SetEndpoints(filtered)
 		}
 	}
 
+	r.exports.set(req, exportTo)
+
+	// NOTE: status is updated once the updates have been applied, rather than when they are enqueued.
+	var onFlushed func(nodes int)
+	if endpoint != nil {
+		onFlushed = pushedStatusUpdater(r.store, r.elected, api.EndpointKind, req, endpoint.Spec.ConfigV3, r.logger)
+	}
+	r.queue.EnqueueAll(updates, onFlushed)
+
 	return ctrl.Result{}, nil
 }
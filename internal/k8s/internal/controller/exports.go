@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+)
+
+// exportedNamespaces remembers the namespaces each resource has been exported to by spec.exportTo,
+// so that nodes in the namespaces which the resource is no longer exported to are updated as well.
+type exportedNamespaces struct {
+	mu    sync.Mutex
+	items map[types.NamespacedName][]string
+}
+
+func newExportedNamespaces() *exportedNamespaces {
+	return &exportedNamespaces{
+		items: map[types.NamespacedName][]string{},
+	}
+}
+
+// namespaces returns the namespaces of nodes to be updated by reconciling the requested resource,
+// which contain the namespaces the resource was previously exported to.
+func (e *exportedNamespaces) namespaces(req ctrl.Request, exportTo []string) []string {
+	e.mu.Lock()
+	prev := e.items[req.NamespacedName]
+	e.mu.Unlock()
+
+	namespaces := []string{req.Namespace}
+	seen := map[string]struct{}{req.Namespace: {}}
+	for _, ns := range append(prev, exportTo...) {
+		if ns == api.ExportToAllNamespaces {
+			return []string{metav1.NamespaceAll}
+		}
+
+		if _, ok := seen[ns]; ok {
+			continue
+		}
+
+		seen[ns] = struct{}{}
+		namespaces = append(namespaces, ns)
+	}
+
+	return namespaces
+}
+
+// set records spec.exportTo of the requested resource after the nodes have been updated.
+func (e *exportedNamespaces) set(req ctrl.Request, exportTo []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(exportTo) == 0 {
+		delete(e.items, req.NamespacedName)
+		return
+	}
+
+	e.items[req.NamespacedName] = exportTo
+}
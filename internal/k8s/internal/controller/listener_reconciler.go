@@ -5,77 +5,113 @@ import (
 	"errors"
 	"fmt"
 
+	api "github.com/110y/bootes/internal/k8s/api/v1"
 	"github.com/110y/bootes/internal/k8s/store"
 	"github.com/110y/bootes/internal/observer/trace"
 	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/workload"
 	"github.com/go-logr/logr"
-	"github.com/google/uuid"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 var _ reconcile.Reconciler = (*ListenerReconciler)(nil)
 
-func NewListenerReconciler(s store.Store, c cache.Cache, l logr.Logger) reconcile.Reconciler {
+func NewListenerReconciler(s store.Store, c cache.Cache, q *cache.Queue, wr *workload.Resolver, elected <-chan struct{}, l logr.Logger) reconcile.Reconciler {
 	return &ListenerReconciler{
-		store:  s,
-		cache:  c,
-		logger: l,
+		store:     s,
+		cache:     c,
+		queue:     q,
+		workloads: wr,
+		exports:   newExportedNamespaces(),
+		elected:   elected,
+		logger:    l,
 	}
 }
 
 type ListenerReconciler struct {
-	store  store.Store
-	cache  cache.Cache
-	logger logr.Logger
+	store     store.Store
+	cache     cache.Cache
+	queue     *cache.Queue
+	workloads *workload.Resolver
+	exports   *exportedNamespaces
+	elected   <-chan struct{}
+	logger    logr.Logger
 }
 
 func (r *ListenerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx, span := trace.NewSpan(context.Background(), "ListenerReconciler.Reconcile")
 	defer span.End()
 
-	version := uuid.New().String()
-	logger := r.logger.WithValues("version", version)
-
-	logger.Info(fmt.Sprintf("Reconciling %s", req.NamespacedName))
+	r.logger.Info(fmt.Sprintf("Reconciling %s", req.NamespacedName))
 
 	opts := []store.ListOption{}
+	var exportTo []string
 	listener, err := r.store.GetListener(ctx, req.Name, req.Namespace)
 	if err != nil {
+		var invalid *store.InvalidResourceError
+		if errors.As(err, &invalid) {
+			r.logger.Error(err, "invalid listener")
+			if err := updateInvalidStatus(ctx, r.store, r.elected, api.ListenerKind, req, invalid); err != nil {
+				r.logger.Error(err, "failed to update status")
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{}, nil
+		}
+
 		if !errors.Is(err, store.ErrNotFound) {
-			logger.Error(err, "failed to get listener")
+			r.logger.Error(err, "failed to get listener")
 			return ctrl.Result{}, err
 		}
+
+		// NOTE: the deleted listener may never be listed again to be pruned.
+		r.store.Forget(api.ListenerKind, req.Name, req.Namespace)
 	} else {
 		if listener.Spec.WorkloadSelector != nil {
-			opts = append(opts, store.WithLabelFilter(listener.Spec.WorkloadSelector.Labels))
+			opts = append(opts, store.WithWorkloadSelector(listener.Spec.WorkloadSelector))
 		}
-	}
 
-	pods, err := r.store.ListPodsByNamespace(ctx, req.Namespace, opts...)
-	if err != nil {
-		logger.Error(err, "failed to list pods")
-		return ctrl.Result{}, err
+		exportTo = listener.Spec.ExportTo
 	}
 
-	listeners, err := r.store.ListListenersByNamespace(ctx, req.Namespace)
+	workloads, err := listWorkloadsByNamespaces(ctx, r.workloads, r.exports.namespaces(req, exportTo), opts...)
 	if err != nil {
-		logger.Error(err, "failed to list listeners")
+		r.logger.Error(err, "failed to list workloads")
 		return ctrl.Result{}, err
 	}
 
-	for _, pod := range pods.Items {
-		err := r.cache.UpdateListeners(
-			ctx,
-			store.ToNodeName(pod.Name, pod.Namespace),
-			version,
-			store.FilterListenersByLabels(listeners.Items, pod.Labels),
-		)
-		if err != nil {
-			logger.Error(err, "failed to update clusuters")
-			return ctrl.Result{}, err
+	workloads = filterCachedWorkloads(r.cache, workloads)
+
+	listenersByNamespace := map[string][]*api.Listener{}
+	updates := make(map[string]func(s *cache.Snapshot), len(workloads))
+	for _, w := range workloads {
+		listeners, ok := listenersByNamespace[w.Namespace]
+		if !ok {
+			list, err := r.store.ListListenersByNamespace(ctx, w.Namespace)
+			if err != nil {
+				r.logger.Error(err, "failed to list listeners")
+				return ctrl.Result{}, err
+			}
+
+			listeners = list.Items
+			listenersByNamespace[w.Namespace] = listeners
+		}
+
+		filtered := store.FilterListenersByLabels(listeners, w.Labels)
+		updates[w.Node] = func(s *cache.Snapshot) {
+			s.SetListeners(filtered)
 		}
 	}
 
+	r.exports.set(req, exportTo)
+
+	// NOTE: status is updated once the updates have been applied, rather than when they are enqueued.
+	var onFlushed func(nodes int)
+	if listener != nil {
+		onFlushed = pushedStatusUpdater(r.store, r.elected, api.ListenerKind, req, listener.Spec.ConfigV3, r.logger)
+	}
+	r.queue.EnqueueAll(updates, onFlushed)
+
 	return ctrl.Result{}, nil
 }
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/observer/trace"
+	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/workload"
+)
+
+var _ reconcile.Reconciler = (*RuntimeReconciler)(nil)
+
+func NewRuntimeReconciler(s store.Store, c cache.Cache, q *cache.Queue, wr *workload.Resolver, elected <-chan struct{}, l logr.Logger) reconcile.Reconciler {
+	return &RuntimeReconciler{
+		store:     s,
+		cache:     c,
+		queue:     q,
+		workloads: wr,
+		exports:   newExportedNamespaces(),
+		elected:   elected,
+		logger:    l,
+	}
+}
+
+type RuntimeReconciler struct {
+	store     store.Store
+	cache     cache.Cache
+	queue     *cache.Queue
+	workloads *workload.Resolver
+	exports   *exportedNamespaces
+	elected   <-chan struct{}
+	logger    logr.Logger
+}
+
+func (r *RuntimeReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := trace.NewSpan(context.Background(), "RuntimeReconciler.Reconcile")
+	defer span.End()
+
+	r.logger.Info(fmt.Sprintf("Reconciling %s", req.NamespacedName))
+
+	opts := []store.ListOption{}
+	var exportTo []string
+	runtime, err := r.store.GetRuntime(ctx, req.Name, req.Namespace)
+	if err != nil {
+		var invalid *store.InvalidResourceError
+		if errors.As(err, &invalid) {
+			r.logger.Error(err, "invalid runtime")
+			if err := updateInvalidStatus(ctx, r.store, r.elected, api.RuntimeKind, req, invalid); err != nil {
+				r.logger.Error(err, "failed to update status")
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{}, nil
+		}
+
+		if !errors.Is(err, store.ErrNotFound) {
+			r.logger.Error(err, "failed to get runtime")
+			return ctrl.Result{}, err
+		}
+
+		// NOTE: the deleted runtime may never be listed again to be pruned.
+		r.store.Forget(api.RuntimeKind, req.Name, req.Namespace)
+	} else {
+		if runtime.Spec.WorkloadSelector != nil {
+			opts = append(opts, store.WithWorkloadSelector(runtime.Spec.WorkloadSelector))
+		}
+
+		exportTo = runtime.Spec.ExportTo
+	}
+
+	workloads, err := listWorkloadsByNamespaces(ctx, r.workloads, r.exports.namespaces(req, exportTo), opts...)
+	if err != nil {
+		r.logger.Error(err, "failed to list workloads")
+		return ctrl.Result{}, err
+	}
+
+	workloads = filterCachedWorkloads(r.cache, workloads)
+
+	runtimesByNamespace := map[string][]*api.Runtime{}
+	updates := make(map[string]func(s *cache.Snapshot), len(workloads))
+	for _, w := range workloads {
+		runtimes, ok := runtimesByNamespace[w.Namespace]
+		if !ok {
+			list, err := r.store.ListRuntimesByNamespace(ctx, w.Namespace)
+			if err != nil {
+				r.logger.Error(err, "failed to list runtimes")
+				return ctrl.Result{}, err
+			}
+
+			runtimes = list.Items
+			runtimesByNamespace[w.Namespace] = runtimes
+		}
+
+		filtered := store.FilterRuntimesByLabels(runtimes, w.Labels)
+		updates[w.Node] = func(s *cache.Snapshot) {
+			s.SetRuntimes(filtered)
+		}
+	}
+
+	r.exports.set(req, exportTo)
+
+	// NOTE: status is updated once the updates have been applied, rather than when they are enqueued.
+	var onFlushed func(nodes int)
+	if runtime != nil {
+		onFlushed = pushedStatusUpdater(r.store, r.elected, api.RuntimeKind, req, runtime.Spec.ConfigV3, r.logger)
+	}
+	r.queue.EnqueueAll(updates, onFlushed)
+
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/observer/trace"
+	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/workload"
+)
+
+var _ reconcile.Reconciler = (*SecretReconciler)(nil)
+
+func NewSecretReconciler(s store.Store, c cache.Cache, q *cache.Queue, wr *workload.Resolver, elected <-chan struct{}, l logr.Logger) reconcile.Reconciler {
+	return &SecretReconciler{
+		store:     s,
+		cache:     c,
+		queue:     q,
+		workloads: wr,
+		exports:   newExportedNamespaces(),
+		elected:   elected,
+		logger:    l,
+	}
+}
+
+type SecretReconciler struct {
+	store     store.Store
+	cache     cache.Cache
+	queue     *cache.Queue
+	workloads *workload.Resolver
+	exports   *exportedNamespaces
+	elected   <-chan struct{}
+	logger    logr.Logger
+}
+
+func (r *SecretReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := trace.NewSpan(context.Background(), "SecretReconciler.Reconcile")
+	defer span.End()
+
+	r.logger.Info(fmt.Sprintf("Reconciling %s", req.NamespacedName))
+
+	opts := []store.ListOption{}
+	var exportTo []string
+	secret, err := r.store.GetSecret(ctx, req.Name, req.Namespace)
+	if err != nil {
+		var invalid *store.InvalidResourceError
+		if errors.As(err, &invalid) {
+			r.logger.Error(err, "invalid secret")
+			if err := updateInvalidStatus(ctx, r.store, r.elected, api.SecretKind, req, invalid); err != nil {
+				r.logger.Error(err, "failed to update status")
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{}, nil
+		}
+
+		if !errors.Is(err, store.ErrNotFound) {
+			r.logger.Error(err, "failed to get secret")
+			return ctrl.Result{}, err
+		}
+
+		// NOTE: the deleted secret may never be listed again to be pruned.
+		r.store.Forget(api.SecretKind, req.Name, req.Namespace)
+	} else {
+		if secret.Spec.WorkloadSelector != nil {
+			opts = append(opts, store.WithWorkloadSelector(secret.Spec.WorkloadSelector))
+		}
+
+		exportTo = secret.Spec.ExportTo
+	}
+
+	workloads, err := listWorkloadsByNamespaces(ctx, r.workloads, r.exports.namespaces(req, exportTo), opts...)
+	if err != nil {
+		r.logger.Error(err, "failed to list workloads")
+		return ctrl.Result{}, err
+	}
+
+	workloads = filterCachedWorkloads(r.cache, workloads)
+
+	secretsByNamespace := map[string][]*api.Secret{}
+	updates := make(map[string]func(s *cache.Snapshot), len(workloads))
+	for _, w := range workloads {
+		secrets, ok := secretsByNamespace[w.Namespace]
+		if !ok {
+			list, err := r.store.ListSecretsByNamespace(ctx, w.Namespace)
+			if err != nil {
+				r.logger.Error(err, "failed to list secrets")
+				return ctrl.Result{}, err
+			}
+
+			secrets = list.Items
+			secretsByNamespace[w.Namespace] = secrets
+		}
+
+		filtered := store.FilterSecretsByLabels(secrets, w.Labels)
+		updates[w.Node] = func(s *cache.Snapshot) {
+			s.SetSecrets(filtered)
+		}
+	}
+
+	r.exports.set(req, exportTo)
+
+	// NOTE: status is updated once the updates have been applied, rather than when they are enqueued.
+	var onFlushed func(nodes int)
+	if secret != nil {
+		onFlushed = pushedStatusUpdater(r.store, r.elected, api.SecretKind, req, secret.Spec.ConfigV3, r.logger)
+	}
+	r.queue.EnqueueAll(updates, onFlushed)
+
+	return ctrl.Result{}, nil
+}
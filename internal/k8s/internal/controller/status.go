@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"context"
+	"errors"
+
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/k8s/internal/leader"
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/observer/trace"
+	"github.com/110y/bootes/internal/xds/cache"
+)
+
+// updateInvalidStatus records that spec.config of the requested resource could not be parsed.
+func updateInvalidStatus(ctx context.Context, s store.Store, elected <-chan struct{}, kind string, req ctrl.Request, parseErr error) error {
+	if !leader.IsElected(elected) {
+		return nil
+	}
+
+	err := s.UpdateStatus(ctx, kind, req.Name, req.Namespace, func(status *api.Status) {
+		status.Parsed = false
+		status.ParseError = parseErr.Error()
+	})
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// pushedStatusUpdater returns the callback of the queue which records the configuration of the requested resource has been pushed,
+// once the updates enqueued for the nodes have been applied to the cache served to them.
+// Errors are only logged since the reconciliation has already completed by then.
+func pushedStatusUpdater(s store.Store, elected <-chan struct{}, kind string, req ctrl.Request, config types.Resource, l logr.Logger) func(nodes int) {
+	return func(nodes int) {
+		ctx, span := trace.NewSpan(context.Background(), "Controller.UpdatePushedStatus")
+		defer span.End()
+
+		if err := updatePushedStatus(ctx, s, elected, kind, req, nodes, config); err != nil {
+			l.Error(err, "failed to update status", "name", req.Name, "namespace", req.Namespace)
+		}
+	}
+}
+
+// updatePushedStatus records that the configuration of the requested resource has been pushed to the nodes.
+// The number of the nodes is the one connected to the leader when multiple replicas are running, since only the leader updates status.
+func updatePushedStatus(ctx context.Context, s store.Store, elected <-chan struct{}, kind string, req ctrl.Request, nodes int, config types.Resource) error {
+	if !leader.IsElected(elected) {
+		return nil
+	}
+
+	version, err := cache.ConfigVersion(config)
+	if err != nil {
+		return err
+	}
+
+	err = s.UpdateStatus(ctx, kind, req.Name, req.Namespace, func(status *api.Status) {
+		status.Parsed = true
+		status.ParseError = ""
+		status.Nodes = int32(nodes)
+		status.LastPushedVersion = version
+	})
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
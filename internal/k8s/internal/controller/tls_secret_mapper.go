@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/observer/trace"
+)
+
+var _ handler.Mapper = (*TLSSecretMapper)(nil)
+
+// TLSSecretMapper maps a `kubernetes.io/tls` Secret to Secrets which refer to it by spec.tlsSecretRef,
+// so that rotated certificates are distributed without touching Secrets.
+type TLSSecretMapper struct {
+	store  store.Store
+	logger logr.Logger
+}
+
+func NewTLSSecretMapper(s store.Store, l logr.Logger) handler.Mapper {
+	return &TLSSecretMapper{
+		store:  s,
+		logger: l,
+	}
+}
+
+func (m *TLSSecretMapper) Map(obj handler.MapObject) []ctrl.Request {
+	ctx, span := trace.NewSpan(context.Background(), "TLSSecretMapper.Map")
+	defer span.End()
+
+	namespace := obj.Meta.GetNamespace()
+	name := obj.Meta.GetName()
+
+	secrets, err := m.store.ListSecretsByNamespace(ctx, namespace)
+	if err != nil {
+		m.logger.Error(err, "failed to list secrets", "namespace", namespace)
+		return nil
+	}
+
+	requests := []ctrl.Request{}
+	for _, s := range secrets.Items {
+		if s.Spec.TLSSecretRef == nil || s.Spec.TLSSecretRef.Name != name {
+			continue
+		}
+
+		requests = append(requests, ctrl.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      s.Name,
+				Namespace: s.Namespace,
+			},
+		})
+	}
+
+	return requests
+}
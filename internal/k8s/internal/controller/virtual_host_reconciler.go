@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/observer/trace"
+	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/workload"
+)
+
+var _ reconcile.Reconciler = (*VirtualHostReconciler)(nil)
+
+func NewVirtualHostReconciler(s store.Store, c cache.Cache, q *cache.Queue, wr *workload.Resolver, elected <-chan struct{}, l logr.Logger) reconcile.Reconciler {
+	return &VirtualHostReconciler{
+		store:     s,
+		cache:     c,
+		queue:     q,
+		workloads: wr,
+		exports:   newExportedNamespaces(),
+		elected:   elected,
+		logger:    l,
+	}
+}
+
+type VirtualHostReconciler struct {
+	store     store.Store
+	cache     cache.Cache
+	queue     *cache.Queue
+	workloads *workload.Resolver
+	exports   *exportedNamespaces
+	elected   <-chan struct{}
+	logger    logr.Logger
+}
+
+func (r *VirtualHostReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := trace.NewSpan(context.Background(), "VirtualHostReconciler.Reconcile")
+	defer span.End()
+
+	r.logger.Info(fmt.Sprintf("Reconciling %s", req.NamespacedName))
+
+	opts := []store.ListOption{}
+	var exportTo []string
+	virtualHost, err := r.store.GetVirtualHost(ctx, req.Name, req.Namespace)
+	if err != nil {
+		var invalid *store.InvalidResourceError
+		if errors.As(err, &invalid) {
+			r.logger.Error(err, "invalid virtual host")
+			if err := updateInvalidStatus(ctx, r.store, r.elected, api.VirtualHostKind, req, invalid); err != nil {
+				r.logger.Error(err, "failed to update status")
+				return ctrl.Result{}, err
+			}
+
+			return ctrl.Result{}, nil
+		}
+
+		if !errors.Is(err, store.ErrNotFound) {
+			r.logger.Error(err, "failed to get virtual host")
+			return ctrl.Result{}, err
+		}
+
+		// NOTE: the deleted virtual host may never be listed again to be pruned.
+		r.store.Forget(api.VirtualHostKind, req.Name, req.Namespace)
+	} else {
+		if virtualHost.Spec.WorkloadSelector != nil {
+			opts = append(opts, store.WithWorkloadSelector(virtualHost.Spec.WorkloadSelector))
+		}
+
+		exportTo = virtualHost.Spec.ExportTo
+	}
+
+	workloads, err := listWorkloadsByNamespaces(ctx, r.workloads, r.exports.namespaces(req, exportTo), opts...)
+	if err != nil {
+		r.logger.Error(err, "failed to list workloads")
+		return ctrl.Result{}, err
+	}
+
+	workloads = filterCachedWorkloads(r.cache, workloads)
+
+	virtualHostsByNamespace := map[string][]*api.VirtualHost{}
+	updates := make(map[string]func(s *cache.Snapshot), len(workloads))
+	for _, w := range workloads {
+		virtualHosts, ok := virtualHostsByNamespace[w.Namespace]
+		if !ok {
+			list, err := r.store.ListVirtualHostsByNamespace(ctx, w.Namespace)
+			if err != nil {
+				r.logger.Error(err, "failed to list virtual hosts")
+				return ctrl.Result{}, err
+			}
+
+			virtualHosts = list.Items
+			virtualHostsByNamespace[w.Namespace] = virtualHosts
+		}
+
+		filtered := store.FilterVirtualHostsByLabels(virtualHosts, w.Labels)
+		updates[w.Node] = func(s *cache.Snapshot) {
+			s.SetVirtualHosts(filtered)
+		}
+	}
+
+	r.exports.set(req, exportTo)
+
+	// NOTE: status is updated once the updates have been applied, rather than when they are enqueued.
+	var onFlushed func(nodes int)
+	if virtualHost != nil {
+		onFlushed = pushedStatusUpdater(r.store, r.elected, api.VirtualHostKind, req, virtualHost.Spec.ConfigV3, r.logger)
+	}
+	r.queue.EnqueueAll(updates, onFlushed)
+
+	return ctrl.Result{}, nil
+}
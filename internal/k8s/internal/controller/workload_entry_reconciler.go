@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/observer/trace"
+	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/snapshot"
+	"github.com/110y/bootes/internal/xds/workload"
+)
+
+var _ reconcile.Reconciler = (*WorkloadEntryReconciler)(nil)
+
+func NewWorkloadEntryReconciler(s store.Store, c cache.Cache, wr *workload.Resolver, e NodeEvictor, l logr.Logger) reconcile.Reconciler {
+	return &WorkloadEntryReconciler{
+		store:     s,
+		cache:     c,
+		builder:   snapshot.NewBuilder(s, c),
+		workloads: wr,
+		evictor:   e,
+		logger:    l,
+	}
+}
+
+// WorkloadEntryReconciler rebuilds the resources of the node when the labels of its workload entry have changed,
+// and evicts the node when the workload entry has been deleted.
+type WorkloadEntryReconciler struct {
+	store     store.Store
+	cache     cache.Cache
+	builder   *snapshot.Builder
+	workloads *workload.Resolver
+	evictor   NodeEvictor
+	logger    logr.Logger
+}
+
+func (r *WorkloadEntryReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := trace.NewSpan(context.Background(), "WorkloadEntryReconciler.Reconcile")
+	defer span.End()
+
+	r.logger.Info(fmt.Sprintf("Reconciling %s", req.NamespacedName))
+
+	node := r.workloads.Key(req.Name, req.Namespace)
+
+	entry, err := r.store.GetWorkloadEntry(ctx, req.Name, req.Namespace)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			r.evictor.EvictNode(node)
+			return ctrl.Result{}, nil
+		}
+
+		r.logger.Error(err, "failed to get workload entry")
+		return ctrl.Result{}, err
+	}
+
+	if r.cache.IsBuildingNode(node) {
+		return ctrl.Result{RequeueAfter: buildingNodeRequeueDelay}, nil
+	}
+
+	if !r.cache.IsCachedNode(node) {
+		// NOTE: resources of the node will be built when it connects.
+		return ctrl.Result{}, nil
+	}
+
+	// NOTE: the node is checked again when the resources are updated, since it may be evicted while they are being built.
+	if err := r.builder.Rebuild(ctx, node, entry.Namespace, entry.Spec.Labels); err != nil {
+		r.logger.Error(err, "failed to build resources")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/workload"
+)
+
+// buildingNodeRequeueDelay is the delay to reconcile the workload of the node being built again,
+// since the build may have resolved its labels before they changed.
+const buildingNodeRequeueDelay = time.Second
+
+// listWorkloadsByNamespaces lists the workloads selected by the options in the namespaces,
+// which are pods, WorkloadEntries and nodes declared by their metadata.
+func listWorkloadsByNamespaces(ctx context.Context, r *workload.Resolver, namespaces []string, opts ...store.ListOption) ([]*workload.Workload, error) {
+	workloads := []*workload.Workload{}
+	for _, ns := range namespaces {
+		list, err := r.List(ctx, ns, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		workloads = append(workloads, list...)
+	}
+
+	return workloads, nil
+}
+
+// filterCachedWorkloads returns the workloads whose nodes have been cached or are being built.
+// Resources of other nodes are built all at once when they connect, or have been evicted after they disconnected,
+// so updating only a part of their resources would make the cache serve incomplete snapshots.
+// Updates of nodes being built are enqueued too, since the build may have listed resources before they changed,
+// and they are applied once the build has completed as the queue only updates cached nodes.
+func filterCachedWorkloads(c cache.Cache, workloads []*workload.Workload) []*workload.Workload {
+	cached := make([]*workload.Workload, 0, len(workloads))
+	for _, w := range workloads {
+		if c.IsCachedNode(w.Node) || c.IsBuildingNode(w.Node) {
+			cached = append(cached, w)
+		}
+	}
+
+	return cached
+}
@@ -0,0 +1,13 @@
+package leader
+
+// IsElected reports whether the replica has been elected as the leader by the channel returned from manager.Manager.Elected,
+// which is always the case if leader election is disabled.
+// Only the leader writes to the API server, so that replicas do not overwrite what is written by each other.
+func IsElected(elected <-chan struct{}) bool {
+	select {
+	case <-elected:
+		return true
+	default:
+		return false
+	}
+}
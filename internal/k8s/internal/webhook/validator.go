@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/observer/trace"
+)
+
+const ValidatorPath = "/validate"
+
+var _ admission.Handler = (*Validator)(nil)
+
+func NewValidator(l logr.Logger) admission.Handler {
+	return &Validator{
+		logger: l,
+	}
+}
+
+// Validator rejects resources whose envoy configurations can not be served to proxies.
+type Validator struct {
+	logger logr.Logger
+}
+
+func (v *Validator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	_, span := trace.NewSpan(ctx, "Validator.Handle")
+	defer span.End()
+
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return admission.Allowed("")
+	}
+
+	logger := v.logger.WithValues("kind", req.Kind.Kind, "name", req.Name, "namespace", req.Namespace)
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(req.Object.Raw, &object); err != nil {
+		logger.Error(err, "failed to decode object")
+		return admission.Errored(http.StatusBadRequest, fmt.Errorf("failed to decode object: %w", err))
+	}
+
+	if err := store.Validate(object); err != nil {
+		var verr *store.ValidationError
+		if errors.As(err, &verr) {
+			logger.Info("rejected invalid envoy configuration", "field", verr.Field, "reason", verr.Reason)
+		} else {
+			logger.Info("rejected malformed resource", "reason", err.Error())
+		}
+
+		return admission.Denied(err.Error())
+	}
+
+	return admission.Allowed("")
+}
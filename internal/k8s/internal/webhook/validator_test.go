@@ -0,0 +1,203 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/110y/bootes/internal/k8s/internal/webhook"
+)
+
+func TestValidatorHandle(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		operation       admissionv1beta1.Operation
+		object          map[string]interface{}
+		expectedAllowed bool
+		expectedMessage string
+	}{
+		"should allow valid cluster": {
+			operation: admissionv1beta1.Create,
+			object: map[string]interface{}{
+				"kind": "Cluster",
+				"spec": map[string]interface{}{
+					"config": map[string]interface{}{
+						"name":            "cluster-1",
+						"connect_timeout": "1s",
+					},
+				},
+			},
+			expectedAllowed: true,
+		},
+		"should reject cluster violating constraints": {
+			operation: admissionv1beta1.Update,
+			object: map[string]interface{}{
+				"kind": "Cluster",
+				"spec": map[string]interface{}{
+					"config": map[string]interface{}{
+						"name": "",
+					},
+				},
+			},
+			expectedAllowed: false,
+			expectedMessage: "spec.config.name: value length must be at least 1 bytes",
+		},
+		"should reject nested field with its path": {
+			operation: admissionv1beta1.Create,
+			object: map[string]interface{}{
+				"kind": "Endpoint",
+				"spec": map[string]interface{}{
+					"xdsVersion": "v3",
+					"config": map[string]interface{}{
+						"cluster_name": "cluster-1",
+						"endpoints": []interface{}{
+							map[string]interface{}{
+								"lb_endpoints": []interface{}{
+									map[string]interface{}{
+										"load_balancing_weight": 0,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedAllowed: false,
+			expectedMessage: "spec.config.endpoints[0].lbEndpoints[0].loadBalancingWeight: value must be greater than or equal to 1",
+		},
+		"should reject resource without config": {
+			operation: admissionv1beta1.Create,
+			object: map[string]interface{}{
+				"kind": "Listener",
+				"spec": map[string]interface{}{},
+			},
+			expectedAllowed: false,
+			expectedMessage: "failed to unmarshal envoy configuration: spec.config not found",
+		},
+		"should reject unsupported xdsVersion": {
+			operation: admissionv1beta1.Create,
+			object: map[string]interface{}{
+				"kind": "Route",
+				"spec": map[string]interface{}{
+					"xdsVersion": "v1",
+					"config": map[string]interface{}{
+						"name": "route-1",
+					},
+				},
+			},
+			expectedAllowed: false,
+			expectedMessage: "unsupported spec.xdsVersion: v1",
+		},
+		"should reject secret violating constraints without resolving tlsSecretRef": {
+			operation: admissionv1beta1.Create,
+			object: map[string]interface{}{
+				"kind": "Secret",
+				"spec": map[string]interface{}{
+					"xdsVersion": "v3",
+					"tlsSecretRef": map[string]interface{}{
+						"name": "not-exist",
+					},
+					"config": map[string]interface{}{
+						"name": "secret-1",
+						"session_ticket_keys": map[string]interface{}{
+							"keys": []interface{}{},
+						},
+					},
+				},
+			},
+			expectedAllowed: false,
+			expectedMessage: "spec.config.sessionTicketKeys.keys: value must contain at least 1 item(s)",
+		},
+		"should reject v2 listener whose typed config has no v3 counterpart": {
+			operation: admissionv1beta1.Create,
+			object: map[string]interface{}{
+				"kind": "Listener",
+				"spec": map[string]interface{}{
+					"config": map[string]interface{}{
+						"name": "listener-1",
+						"listener_filters": []interface{}{
+							map[string]interface{}{
+								"name": "envoy.filters.udp_listener.udp_proxy",
+								"typed_config": map[string]interface{}{
+									"@type":       "type.googleapis.com/envoy.config.filter.udp.udp_proxy.v2alpha.UdpProxyConfig",
+									"stat_prefix": "udp",
+									"cluster":     "cluster-1",
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedAllowed: false,
+			expectedMessage: "failed to convert spec.config to v3: envoy.config.filter.udp.udp_proxy.v2alpha.UdpProxyConfig has no v3 counterpart",
+		},
+		"should reject invalid workloadSelector": {
+			operation: admissionv1beta1.Create,
+			object: map[string]interface{}{
+				"kind": "Cluster",
+				"spec": map[string]interface{}{
+					"workloadSelector": map[string]interface{}{
+						"matchExpressions": []interface{}{
+							map[string]interface{}{
+								"key":      "track",
+								"operator": "In",
+							},
+						},
+					},
+					"config": map[string]interface{}{
+						"name":            "cluster-1",
+						"connect_timeout": "1s",
+					},
+				},
+			},
+			expectedAllowed: false,
+			expectedMessage: "invalid spec.workloadSelector: for 'in', 'notin' operators, values set can't be empty",
+		},
+		"should ignore deletion": {
+			operation:       admissionv1beta1.Delete,
+			expectedAllowed: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var raw []byte
+			if test.object != nil {
+				var err error
+				raw, err = json.Marshal(test.object)
+				if err != nil {
+					t.Fatalf("failed to marshal object: %s", err)
+				}
+			}
+
+			v := webhook.NewValidator(logr.Logger(log.NullLogger{}))
+			res := v.Handle(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1beta1.AdmissionRequest{
+					Operation: test.operation,
+					Kind:      metav1.GroupVersionKind{Kind: "Cluster"},
+					Object:    runtime.RawExtension{Raw: raw},
+				},
+			})
+
+			if res.Allowed != test.expectedAllowed {
+				t.Fatalf("unexpected allowed: %t", res.Allowed)
+			}
+
+			if diff := cmp.Diff(test.expectedMessage, string(res.Result.Reason)); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/110y/bootes/internal/k8s/internal/leader"
+	"github.com/110y/bootes/internal/k8s/store"
+)
+
+const eventReasonInvalid = "Invalid"
+
+// InvalidResourceReporter reports resources skipped by the store since they can not be parsed.
+// Each version of the resources is reported only once since they are listed on every reconciliation and every new stream,
+// and the reported versions are forgotten when the resources have been deleted.
+// Only the leader reports them, since all replicas list the same resources.
+type InvalidResourceReporter struct {
+	recorder record.EventRecorder
+	elected  <-chan struct{}
+	logger   logr.Logger
+
+	mu       sync.Mutex
+	reported map[reportKey]string
+}
+
+type reportKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+func NewInvalidResourceReporter(mgr manager.Manager, l logr.Logger) *InvalidResourceReporter {
+	return &InvalidResourceReporter{
+		recorder: mgr.GetEventRecorderFor(eventSource),
+		elected:  mgr.Elected(),
+		logger:   l,
+		reported: map[reportKey]string{},
+	}
+}
+
+// Report records the error of the resource as a log and a Warning Event.
+func (r *InvalidResourceReporter) Report(_ context.Context, object *unstructured.Unstructured, err *store.InvalidResourceError) {
+	if !leader.IsElected(r.elected) || !r.markReported(object) {
+		return
+	}
+
+	r.logger.Error(err, "skipped invalid resource", "kind", object.GetKind(), "name", object.GetName(), "namespace", object.GetNamespace())
+	r.recorder.Eventf(object, corev1.EventTypeWarning, eventReasonInvalid, "skipped since it can not be parsed: %s", err)
+}
+
+// Forget discards the reported version of the resource, which is called when the resource has been deleted.
+func (r *InvalidResourceReporter) Forget(kind, name, namespace string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.reported, reportKey{kind: kind, namespace: namespace, name: name})
+}
+
+func (r *InvalidResourceReporter) markReported(object *unstructured.Unstructured) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := reportKey{kind: object.GetKind(), namespace: object.GetNamespace(), name: object.GetName()}
+
+	// NOTE: the UID distinguishes the resource recreated with the same name.
+	version := fmt.Sprintf("%s/%s", object.GetUID(), object.GetResourceVersion())
+	if r.reported[key] == version {
+		return false
+	}
+
+	r.reported[key] = version
+	return true
+}
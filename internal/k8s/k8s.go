@@ -2,40 +2,133 @@ package k8s
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	apiv1 "github.com/110y/bootes/internal/k8s/api/v1"
 	"github.com/110y/bootes/internal/k8s/internal/controller"
 	"github.com/110y/bootes/internal/k8s/store"
 	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/workload"
 )
 
+// specChanged filters out updates which do not change metadata.generation,
+// so that updating status of the resources by reconcilers does not trigger reconciliation again.
+var specChanged = builder.WithPredicates(predicate.GenerationChangedPredicate{})
+
+// podLabelsChanged filters out events of pods except for changes of their labels and deletions,
+// since resources of nodes are selected only by the labels of their pods.
+// Creations are also filtered out since resources of new nodes are built when they connect.
+var podLabelsChanged = builder.WithPredicates(predicate.Funcs{
+	CreateFunc: func(event.CreateEvent) bool {
+		return false
+	},
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return !labels.Equals(e.MetaOld.GetLabels(), e.MetaNew.GetLabels())
+	},
+	DeleteFunc: func(event.DeleteEvent) bool {
+		return true
+	},
+	GenericFunc: func(event.GenericEvent) bool {
+		return false
+	},
+})
+
+// newObject returns an unstructured object of the kind for controllers to watch,
+// so that they share the informer with the store which reads the resources as unstructured objects.
+func newObject(kind string) *unstructured.Unstructured {
+	o := &unstructured.Unstructured{}
+	o.SetGroupVersionKind(apiv1.GroupVersion.WithKind(kind))
+	return o
+}
+
 type Controller struct {
 	manager manager.Manager
 	logger  logr.Logger
 }
 
-func NewController(mgr manager.Manager, s store.Store, c cache.Cache, l logr.Logger) (*Controller, error) {
+func NewController(mgr manager.Manager, s store.Store, c cache.Cache, q *cache.Queue, wr *workload.Resolver, e controller.NodeEvictor, ti *TLSSecretInformer, reconcileTimeout time.Duration, l logr.Logger) (*Controller, error) {
 	ctrl.SetLogger(l)
 
-	if err := setupClusterReconciler(mgr, s, c, l.WithName("cluster_reconciler")); err != nil {
+	rm := &replicaManager{Manager: mgr}
+	m := newReconcileMonitor(reconcileTimeout)
+
+	if err := setupClusterReconciler(rm, m, s, c, q, wr, l.WithName("cluster_reconciler")); err != nil {
+		return nil, err
+	}
+
+	if err := setupListenerReconciler(rm, m, s, c, q, wr, l.WithName("listener_reconciler")); err != nil {
+		return nil, err
+	}
+
+	if err := setupRouteReconciler(rm, m, s, c, q, wr, l.WithName("route_reconciler")); err != nil {
+		return nil, err
+	}
+
+	if err := setupEndpointReconciler(rm, m, s, c, q, wr, l.WithName("endpoint_reconciler")); err != nil {
+		return nil, err
+	}
+
+	if err := setupSecretReconciler(rm, m, s, c, q, wr, ti, l.WithName("secret_reconciler")); err != nil {
+		return nil, err
+	}
+
+	if err := setupRuntimeReconciler(rm, m, s, c, q, wr, l.WithName("runtime_reconciler")); err != nil {
+		return nil, err
+	}
+
+	if err := setupScopedRouteReconciler(rm, m, s, c, q, wr, l.WithName("scoped_route_reconciler")); err != nil {
+		return nil, err
+	}
+
+	if err := setupVirtualHostReconciler(rm, m, s, c, q, wr, l.WithName("virtual_host_reconciler")); err != nil {
 		return nil, err
 	}
 
-	if err := setupListenerReconciler(mgr, s, c, l.WithName("listener_reconciler")); err != nil {
+	if err := setupPodReconciler(rm, m, s, c, wr, e, l.WithName("pod_reconciler")); err != nil {
 		return nil, err
 	}
 
-	if err := setupRouteReconciler(mgr, s, c, l.WithName("route_reconciler")); err != nil {
+	if err := setupWorkloadEntryReconciler(rm, m, s, c, wr, e, l.WithName("workload_entry_reconciler")); err != nil {
 		return nil, err
 	}
 
-	if err := setupEndpointReconciler(mgr, s, c, l.WithName("endpoint_reconciler")); err != nil {
+	informers, err := newSyncedInformers(mgr.GetCache(), map[string]runtime.Object{
+		"clusters":        newObject(apiv1.ClusterKind),
+		"listeners":       newObject(apiv1.ListenerKind),
+		"routes":          newObject(apiv1.RouteKind),
+		"endpoints":       newObject(apiv1.EndpointKind),
+		"secrets":         newObject(apiv1.SecretKind),
+		"runtimes":        newObject(apiv1.RuntimeKind),
+		"scopedroutes":    newObject(apiv1.ScopedRouteKind),
+		"virtualhosts":    newObject(apiv1.VirtualHostKind),
+		"workloadentries": &apiv1.WorkloadEntry{},
+		"pods":            &corev1.Pod{},
+	})
+	if err != nil {
 		return nil, err
 	}
+	informers.informers["tlssecrets"] = ti.informer
+
+	if err := mgr.AddReadyzCheck(informersCheckName, informers.check); err != nil {
+		return nil, fmt.Errorf("failed to register readyz checker: %w", err)
+	}
+
+	if err := mgr.AddHealthzCheck(reconcilesCheckName, m.check); err != nil {
+		return nil, fmt.Errorf("failed to register healthz checker: %w", err)
+	}
 
 	return &Controller{
 		manager: mgr,
@@ -43,46 +136,113 @@ func NewController(mgr manager.Manager, s store.Store, c cache.Cache, l logr.Log
 	}, nil
 }
 
-func setupClusterReconciler(mgr manager.Manager, s store.Store, c cache.Cache, l logr.Logger) error {
-	cr := controller.NewClusterReconciler(s, c, l)
+func setupClusterReconciler(mgr manager.Manager, m *reconcileMonitor, s store.Store, c cache.Cache, q *cache.Queue, wr *workload.Resolver, l logr.Logger) error {
+	cr := controller.NewClusterReconciler(s, c, q, wr, mgr.Elected(), l)
 
-	if err := ctrl.NewControllerManagedBy(mgr).For(&apiv1.Cluster{}).Complete(cr); err != nil {
+	if err := ctrl.NewControllerManagedBy(mgr).For(newObject(apiv1.ClusterKind), specChanged).Complete(m.wrap("cluster_reconciler", cr)); err != nil {
 		return fmt.Errorf("failed to setup cluster reconciler: %s", err)
 	}
 
 	return nil
 }
 
-func setupListenerReconciler(mgr manager.Manager, s store.Store, c cache.Cache, l logr.Logger) error {
-	lr := controller.NewListenerReconciler(s, c, l)
+func setupListenerReconciler(mgr manager.Manager, m *reconcileMonitor, s store.Store, c cache.Cache, q *cache.Queue, wr *workload.Resolver, l logr.Logger) error {
+	lr := controller.NewListenerReconciler(s, c, q, wr, mgr.Elected(), l)
 
-	if err := ctrl.NewControllerManagedBy(mgr).For(&apiv1.Listener{}).Complete(lr); err != nil {
+	if err := ctrl.NewControllerManagedBy(mgr).For(newObject(apiv1.ListenerKind), specChanged).Complete(m.wrap("listener_reconciler", lr)); err != nil {
 		return fmt.Errorf("failed to setup listener reconciler: %s", err)
 	}
 
 	return nil
 }
 
-func setupRouteReconciler(mgr manager.Manager, s store.Store, c cache.Cache, l logr.Logger) error {
-	rr := controller.NewRouteReconciler(s, c, l)
+func setupRouteReconciler(mgr manager.Manager, m *reconcileMonitor, s store.Store, c cache.Cache, q *cache.Queue, wr *workload.Resolver, l logr.Logger) error {
+	rr := controller.NewRouteReconciler(s, c, q, wr, mgr.Elected(), l)
 
-	if err := ctrl.NewControllerManagedBy(mgr).For(&apiv1.Route{}).Complete(rr); err != nil {
+	if err := ctrl.NewControllerManagedBy(mgr).For(newObject(apiv1.RouteKind), specChanged).Complete(m.wrap("route_reconciler", rr)); err != nil {
 		return fmt.Errorf("failed to setup route reconciler: %s", err)
 	}
 
 	return nil
 }
 
-func setupEndpointReconciler(mgr manager.Manager, s store.Store, c cache.Cache, l logr.Logger) error {
-	rr := controller.NewEndpointReconciler(s, c, l)
+func setupEndpointReconciler(mgr manager.Manager, m *reconcileMonitor, s store.Store, c cache.Cache, q *cache.Queue, wr *workload.Resolver, l logr.Logger) error {
+	rr := controller.NewEndpointReconciler(s, c, q, wr, mgr.Elected(), l)
 
-	if err := ctrl.NewControllerManagedBy(mgr).For(&apiv1.Endpoint{}).Complete(rr); err != nil {
+	if err := ctrl.NewControllerManagedBy(mgr).For(newObject(apiv1.EndpointKind), specChanged).Complete(m.wrap("endpoint_reconciler", rr)); err != nil {
 		return fmt.Errorf("failed to setup endpoint reconciler: %s", err)
 	}
 
 	return nil
 }
 
+func setupSecretReconciler(mgr manager.Manager, m *reconcileMonitor, s store.Store, c cache.Cache, q *cache.Queue, wr *workload.Resolver, ti *TLSSecretInformer, l logr.Logger) error {
+	sr := controller.NewSecretReconciler(s, c, q, wr, mgr.Elected(), l)
+
+	err := ctrl.NewControllerManagedBy(mgr).
+		For(newObject(apiv1.SecretKind), specChanged).
+		Watches(
+			&source.Informer{Informer: ti.informer},
+			&handler.EnqueueRequestsFromMapFunc{ToRequests: controller.NewTLSSecretMapper(s, l.WithName("tls_secret_mapper"))},
+		).
+		Complete(m.wrap("secret_reconciler", sr))
+	if err != nil {
+		return fmt.Errorf("failed to setup secret reconciler: %s", err)
+	}
+
+	return nil
+}
+
+func setupRuntimeReconciler(mgr manager.Manager, m *reconcileMonitor, s store.Store, c cache.Cache, q *cache.Queue, wr *workload.Resolver, l logr.Logger) error {
+	rr := controller.NewRuntimeReconciler(s, c, q, wr, mgr.Elected(), l)
+
+	if err := ctrl.NewControllerManagedBy(mgr).For(newObject(apiv1.RuntimeKind), specChanged).Complete(m.wrap("runtime_reconciler", rr)); err != nil {
+		return fmt.Errorf("failed to setup runtime reconciler: %s", err)
+	}
+
+	return nil
+}
+
+func setupScopedRouteReconciler(mgr manager.Manager, m *reconcileMonitor, s store.Store, c cache.Cache, q *cache.Queue, wr *workload.Resolver, l logr.Logger) error {
+	sr := controller.NewScopedRouteReconciler(s, c, q, wr, mgr.Elected(), l)
+
+	if err := ctrl.NewControllerManagedBy(mgr).For(newObject(apiv1.ScopedRouteKind), specChanged).Complete(m.wrap("scoped_route_reconciler", sr)); err != nil {
+		return fmt.Errorf("failed to setup scoped route reconciler: %s", err)
+	}
+
+	return nil
+}
+
+func setupVirtualHostReconciler(mgr manager.Manager, m *reconcileMonitor, s store.Store, c cache.Cache, q *cache.Queue, wr *workload.Resolver, l logr.Logger) error {
+	vr := controller.NewVirtualHostReconciler(s, c, q, wr, mgr.Elected(), l)
+
+	if err := ctrl.NewControllerManagedBy(mgr).For(newObject(apiv1.VirtualHostKind), specChanged).Complete(m.wrap("virtual_host_reconciler", vr)); err != nil {
+		return fmt.Errorf("failed to setup virtual host reconciler: %s", err)
+	}
+
+	return nil
+}
+
+func setupPodReconciler(mgr manager.Manager, m *reconcileMonitor, s store.Store, c cache.Cache, wr *workload.Resolver, e controller.NodeEvictor, l logr.Logger) error {
+	pr := controller.NewPodReconciler(s, c, wr, e, l)
+
+	if err := ctrl.NewControllerManagedBy(mgr).For(&corev1.Pod{}, podLabelsChanged).Complete(m.wrap("pod_reconciler", pr)); err != nil {
+		return fmt.Errorf("failed to setup pod reconciler: %s", err)
+	}
+
+	return nil
+}
+
+func setupWorkloadEntryReconciler(mgr manager.Manager, m *reconcileMonitor, s store.Store, c cache.Cache, wr *workload.Resolver, e controller.NodeEvictor, l logr.Logger) error {
+	er := controller.NewWorkloadEntryReconciler(s, c, wr, e, l)
+
+	if err := ctrl.NewControllerManagedBy(mgr).For(&apiv1.WorkloadEntry{}, specChanged).Complete(m.wrap("workload_entry_reconciler", er)); err != nil {
+		return fmt.Errorf("failed to setup workload entry reconciler: %s", err)
+	}
+
+	return nil
+}
+
 func (c *Controller) Start(stopCh chan struct{}) error {
 	c.logger.Info("starting k8s controller")
 	return c.manager.Start(stopCh)
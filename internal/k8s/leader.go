@@ -0,0 +1,26 @@
+package k8s
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// replicaManager adds controllers to the manager as runnables which run on all replicas regardless of leader election,
+// since every replica builds the resources of the nodes connected to it.
+// Writes to the API server made by the controllers are done only by the leader instead.
+type replicaManager struct {
+	manager.Manager
+}
+
+func (m *replicaManager) Add(r manager.Runnable) error {
+	return m.Manager.Add(&replicaRunnable{Runnable: r})
+}
+
+type replicaRunnable struct {
+	manager.Runnable
+}
+
+var _ manager.LeaderElectionRunnable = (*replicaRunnable)(nil)
+
+func (*replicaRunnable) NeedLeaderElection() bool {
+	return false
+}
@@ -1,22 +1,23 @@
 package k8s
 
 import (
+	"context"
 	"fmt"
-	"net/http"
 
 	apiv1 "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/k8s/store"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
 const (
 	healthzEndpoint = "/healthz"
 	readyzEndpoint  = "/readyz"
-	healthzName     = "healthz"
-	readyzName      = "readyz"
 )
 
 func NewManager(c *ManagerConfig) (manager.Manager, error) {
@@ -34,30 +35,40 @@ func NewManager(c *ManagerConfig) (manager.Manager, error) {
 	}
 
 	manager, err := ctrl.NewManager(cfg, ctrl.Options{
-		Scheme:                 s,
-		ReadinessEndpointName:  readyzEndpoint,
-		LivenessEndpointName:   healthzEndpoint,
-		HealthProbeBindAddress: fmt.Sprintf(":%d", c.HealthzServerPort),
-		MetricsBindAddress:     fmt.Sprintf(":%d", c.MetricsServerPort),
+		Scheme:                  s,
+		ReadinessEndpointName:   readyzEndpoint,
+		LivenessEndpointName:    healthzEndpoint,
+		HealthProbeBindAddress:  fmt.Sprintf(":%d", c.HealthzServerPort),
+		MetricsBindAddress:      fmt.Sprintf(":%d", c.MetricsServerPort),
+		Port:                    c.WebhookServerPort,
+		CertDir:                 c.WebhookCertDir,
+		LeaderElection:          c.LeaderElection,
+		LeaderElectionNamespace: c.LeaderElectionNamespace,
+		LeaderElectionID:        c.LeaderElectionID,
+		NewClient:               NewClient,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create manager: %w", err)
 	}
 
-	if err := manager.AddHealthzCheck(healthzName, newHealthChecker()); err != nil {
-		return nil, fmt.Errorf("failed to register healthz checker: %w", err)
-	}
-
-	if err := manager.AddReadyzCheck(readyzName, newHealthChecker()); err != nil {
-		return nil, fmt.Errorf("failed to register readyz checker: %w", err)
+	if err := store.IndexFields(context.Background(), manager.GetFieldIndexer()); err != nil {
+		return nil, fmt.Errorf("failed to index fields: %w", err)
 	}
 
 	return manager, nil
 }
 
-func newHealthChecker() healthz.Checker {
-	return func(req *http.Request) error {
-		// TODO: implement more meaningful healthz
-		return nil
+// NewClient creates the client of the manager which reads unstructured objects, i.e. Bootes resources read by the store, from the informer cache as well as typed ones,
+// while the default one reads them from the API server directly.
+func NewClient(c cache.Cache, config *rest.Config, options client.Options) (client.Client, error) {
+	cl, err := client.New(config, options)
+	if err != nil {
+		return nil, err
 	}
+
+	return &client.DelegatingClient{
+		Reader:       c,
+		Writer:       cl,
+		StatusClient: cl,
+	}, nil
 }
@@ -0,0 +1,133 @@
+package k8s_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/110y/bootes/internal/k8s"
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/k8s/store"
+)
+
+var _ cache.Cache = (*fakeCache)(nil)
+
+// fakeCache serves unstructured objects from memory in the same way as the informer cache.
+type fakeCache struct {
+	cache.Informers
+	objects []*unstructured.Unstructured
+}
+
+func (c *fakeCache) Get(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+	u := obj.(*unstructured.Unstructured)
+	for _, o := range c.objects {
+		if o.GetKind() == u.GetKind() && o.GetNamespace() == key.Namespace && o.GetName() == key.Name {
+			o.DeepCopyInto(u)
+			return nil
+		}
+	}
+
+	return apierrors.NewNotFound(schema.GroupResource{}, key.Name)
+}
+
+func (c *fakeCache) List(_ context.Context, list runtime.Object, opts ...client.ListOption) error {
+	l := list.(*unstructured.UnstructuredList)
+	if !strings.HasSuffix(l.GetKind(), "List") {
+		return fmt.Errorf("non-list type %s", l.GetKind())
+	}
+	kind := strings.TrimSuffix(l.GetKind(), "List")
+
+	lo := &client.ListOptions{}
+	lo.ApplyOptions(opts)
+
+	for _, o := range c.objects {
+		if o.GetKind() != kind || (lo.Namespace != "" && o.GetNamespace() != lo.Namespace) {
+			continue
+		}
+		l.Items = append(l.Items, *o.DeepCopy())
+	}
+
+	return nil
+}
+
+func TestNewClientReadsBootesResourcesFromCache(t *testing.T) {
+	t.Parallel()
+
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	s := runtime.NewScheme()
+	if err := api.AddToScheme(s); err != nil {
+		t.Fatalf("failed to add scheme: %s", err)
+	}
+
+	c := &fakeCache{
+		objects: []*unstructured.Unstructured{
+			{
+				Object: map[string]interface{}{
+					"kind":       api.ClusterKind,
+					"apiVersion": api.GroupVersion.String(),
+					"metadata": map[string]interface{}{
+						"name":      "cluster-1",
+						"namespace": "default",
+					},
+					"spec": map[string]interface{}{
+						"config": map[string]interface{}{
+							"name":            "cluster-1",
+							"connect_timeout": "1s",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	cl, err := k8s.NewClient(c, &rest.Config{Host: srv.URL}, client.Options{
+		Scheme: s,
+		Mapper: meta.NewDefaultRESTMapper(nil),
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+
+	st := store.New(cl, cl)
+	ctx := context.Background()
+
+	cluster, err := st.GetCluster(ctx, "cluster-1", "default")
+	if err != nil {
+		t.Fatalf("failed to get cluster: %s", err)
+	}
+	if diff := cmp.Diff("cluster-1", cluster.Spec.Config.Name); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+
+	clusters, err := st.ListClustersByNamespace(ctx, "default")
+	if err != nil {
+		t.Fatalf("failed to list clusters: %s", err)
+	}
+	if diff := cmp.Diff(1, len(clusters.Items)); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+
+	if diff := cmp.Diff(int32(0), atomic.LoadInt32(&requests)); diff != "" {
+		t.Errorf("unexpected requests to the API server\n(-expected, +actual)\n%s", diff)
+	}
+}
@@ -0,0 +1,256 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	apiv1 "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/observer/trace"
+	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/workload"
+)
+
+const (
+	eventSource = "bootes"
+
+	eventReasonRejected  = "Rejected"
+	eventReasonRecovered = "Accepted"
+)
+
+var kindsByTypeURL = map[string]string{
+	resource.ClusterType:    apiv1.ClusterKind,
+	resource.ListenerType:   apiv1.ListenerKind,
+	resource.RouteType:      apiv1.RouteKind,
+	resource.EndpointType:   apiv1.EndpointKind,
+	resource.SecretType:     apiv1.SecretKind,
+	resource.RuntimeType:    apiv1.RuntimeKind,
+	resourcev3.ClusterType:  apiv1.ClusterKind,
+	resourcev3.ListenerType: apiv1.ListenerKind,
+	resourcev3.RouteType:    apiv1.RouteKind,
+	resourcev3.EndpointType: apiv1.EndpointKind,
+	resourcev3.SecretType:   apiv1.SecretKind,
+	resourcev3.RuntimeType:  apiv1.RuntimeKind,
+	cache.ScopedRouteTypeV3: apiv1.ScopedRouteKind,
+	cache.VirtualHostTypeV3: apiv1.VirtualHostKind,
+}
+
+// NACKReporter reports rejections of resources by nodes as Events and status of the resources.
+// Unlike the other writes to the API server, it is not limited to the leader since only the replica which a node connects to knows its rejections.
+type NACKReporter struct {
+	store     store.Store
+	workloads *workload.Resolver
+	recorder  record.EventRecorder
+}
+
+// target is a resource pushed to a node, and configName is the name of its envoy configuration.
+type target struct {
+	name       string
+	namespace  string
+	configName string
+}
+
+func NewNACKReporter(mgr manager.Manager, s store.Store, wr *workload.Resolver) *NACKReporter {
+	return &NACKReporter{
+		store:     s,
+		workloads: wr,
+		recorder:  mgr.GetEventRecorderFor(eventSource),
+	}
+}
+
+// ReportRejected reports the rejection to the resources pushed to the node.
+// If the message mentions names of some of their envoy configurations, only these resources are reported.
+// Otherwise the rejection is recorded only as Events of the resources, since it can not be attributed to any of them.
+func (r *NACKReporter) ReportRejected(ctx context.Context, node, typeURL, message string) error {
+	ctx, span := trace.NewSpan(ctx, "NACKReporter.ReportRejected")
+	defer span.End()
+
+	kind, targets, err := r.listTargets(ctx, node, typeURL)
+	if err != nil {
+		return err
+	}
+
+	event := fmt.Sprintf("rejected by %s: %s", node, message)
+
+	narrowed := narrowTargets(targets, message)
+	if len(narrowed) == 0 {
+		for _, t := range targets {
+			if err := r.recordEvent(ctx, kind, t, corev1.EventTypeWarning, eventReasonRejected, event); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for _, t := range narrowed {
+		if err := r.recordEvent(ctx, kind, t, corev1.EventTypeWarning, eventReasonRejected, event); err != nil {
+			return err
+		}
+
+		err := r.store.UpdateStatus(ctx, kind, t.name, t.namespace, func(status *apiv1.Status) {
+			status.NACKError = fmt.Sprintf("%s: %s", node, message)
+		})
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("failed to update status: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ReportRecovered clears rejections of the resources pushed to the node.
+func (r *NACKReporter) ReportRecovered(ctx context.Context, node, typeURL string) error {
+	ctx, span := trace.NewSpan(ctx, "NACKReporter.ReportRecovered")
+	defer span.End()
+
+	kind, targets, err := r.listTargets(ctx, node, typeURL)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range targets {
+		recovered := false
+		err := r.store.UpdateStatus(ctx, kind, t.name, t.namespace, func(status *apiv1.Status) {
+			recovered = status.NACKError != ""
+			status.NACKError = ""
+		})
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("failed to update status: %w", err)
+		}
+
+		if recovered {
+			if err := r.recordEvent(ctx, kind, t, corev1.EventTypeNormal, eventReasonRecovered, fmt.Sprintf("accepted by %s", node)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *NACKReporter) recordEvent(ctx context.Context, kind string, t target, eventType, reason, message string) error {
+	obj, err := r.store.GetObject(ctx, kind, t.name, t.namespace)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	r.recorder.Event(obj, eventType, reason, message)
+
+	return nil
+}
+
+func (r *NACKReporter) listTargets(ctx context.Context, node, typeURL string) (string, []target, error) {
+	kind, ok := kindsByTypeURL[typeURL]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown type url: %s", typeURL)
+	}
+
+	w, err := r.workloads.Lookup(ctx, node)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to look up workload: %w", err)
+	}
+
+	targets, err := r.listTargetsByKind(ctx, kind, w.Namespace, w.Labels)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return kind, targets, nil
+}
+
+func (r *NACKReporter) listTargetsByKind(ctx context.Context, kind, namespace string, labels map[string]string) ([]target, error) {
+	targets := []target{}
+
+	switch kind {
+	case apiv1.ClusterKind:
+		clusters, err := r.store.ListClustersByNamespace(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range store.FilterClustersByLabels(clusters.Items, labels) {
+			targets = append(targets, target{name: c.Name, namespace: c.Namespace, configName: c.Spec.ConfigV3.GetName()})
+		}
+	case apiv1.ListenerKind:
+		listeners, err := r.store.ListListenersByNamespace(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range store.FilterListenersByLabels(listeners.Items, labels) {
+			targets = append(targets, target{name: l.Name, namespace: l.Namespace, configName: l.Spec.ConfigV3.GetName()})
+		}
+	case apiv1.RouteKind:
+		routes, err := r.store.ListRoutesByNamespace(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, rt := range store.FilterRoutesByLabels(routes.Items, labels) {
+			targets = append(targets, target{name: rt.Name, namespace: rt.Namespace, configName: rt.Spec.ConfigV3.GetName()})
+		}
+	case apiv1.EndpointKind:
+		endpoints, err := r.store.ListEndpointsByNamespace(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range store.FilterEndpointsByLabels(endpoints.Items, labels) {
+			targets = append(targets, target{name: e.Name, namespace: e.Namespace, configName: e.Spec.ConfigV3.GetClusterName()})
+		}
+	case apiv1.SecretKind:
+		secrets, err := r.store.ListSecretsByNamespace(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range store.FilterSecretsByLabels(secrets.Items, labels) {
+			targets = append(targets, target{name: s.Name, namespace: s.Namespace, configName: s.Spec.ConfigV3.GetName()})
+		}
+	case apiv1.RuntimeKind:
+		runtimes, err := r.store.ListRuntimesByNamespace(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, rt := range store.FilterRuntimesByLabels(runtimes.Items, labels) {
+			targets = append(targets, target{name: rt.Name, namespace: rt.Namespace, configName: rt.Spec.ConfigV3.GetName()})
+		}
+	case apiv1.ScopedRouteKind:
+		scopedRoutes, err := r.store.ListScopedRoutesByNamespace(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, sr := range store.FilterScopedRoutesByLabels(scopedRoutes.Items, labels) {
+			targets = append(targets, target{name: sr.Name, namespace: sr.Namespace, configName: sr.Spec.ConfigV3.GetName()})
+		}
+	case apiv1.VirtualHostKind:
+		virtualHosts, err := r.store.ListVirtualHostsByNamespace(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, vh := range store.FilterVirtualHostsByLabels(virtualHosts.Items, labels) {
+			targets = append(targets, target{name: vh.Name, namespace: vh.Namespace, configName: vh.Spec.ConfigV3.GetName()})
+		}
+	}
+
+	return targets, nil
+}
+
+// narrowTargets returns the targets whose envoy configurations are mentioned in the message.
+func narrowTargets(targets []target, message string) []target {
+	results := []target{}
+	for _, t := range targets {
+		if t.configName != "" && strings.Contains(message, t.configName) {
+			results = append(results, t)
+		}
+	}
+
+	return results
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	udpa "github.com/cncf/udpa/go/udpa/annotations"
+	"github.com/golang/protobuf/proto"
+	protov2 "google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+)
+
+const anyFullName protoreflect.FullName = "google.protobuf.Any"
+
+// Values of ApiVersion, which are the same in envoy.api.v2.core and envoy.config.core.v3.
+const (
+	apiVersionV2 protoreflect.EnumNumber = 1
+	apiVersionV3 protoreflect.EnumNumber = 2
+)
+
+var (
+	versionedTypesOnce sync.Once
+
+	// upgradedTypes maps the full names of v2 messages to the ones of their v3 counterparts, and downgradedTypes does vice versa.
+	upgradedTypes   map[protoreflect.FullName]protoreflect.FullName
+	downgradedTypes map[protoreflect.FullName]protoreflect.FullName
+)
+
+// convertConfig converts an Envoy configuration between API versions.
+// Since v3 messages keep the field numbers of their v2 counterparts (deprecated fields are only renamed),
+// the wire format of src can be decoded as dst directly.
+// Then the type URLs of nested typed configs and the API versions of config sources are rewritten to the version of dst,
+// since they are kept as they are in the wire format.
+func convertConfig(src, dst proto.Message, version api.XDSVersion) error {
+	b, err := proto.Marshal(src)
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+
+	if err := proto.Unmarshal(b, dst); err != nil {
+		return fmt.Errorf("failed to unmarshal: %w", err)
+	}
+
+	versionedTypesOnce.Do(loadVersionedTypes)
+
+	if err := convertMessage(proto.MessageV2(dst).ProtoReflect(), version); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func convertMessage(m protoreflect.Message, version api.XDSVersion) error {
+	if m.Descriptor().FullName() == anyFullName {
+		return convertAny(m, version)
+	}
+
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if !isAPIVersionField(fd) {
+			continue
+		}
+
+		// NOTE: AUTO is treated as v2 by Envoy, so that config sources are always rewritten to v3 explicitly.
+		current := m.Get(fd).Enum()
+		switch {
+		case version == api.XDSVersionV3 && current != apiVersionV3:
+			m.Set(fd, protoreflect.ValueOfEnum(apiVersionV3))
+		case version == api.XDSVersionV2 && current == apiVersionV3:
+			m.Set(fd, protoreflect.ValueOfEnum(apiVersionV2))
+		}
+	}
+
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			l := v.List()
+			for i := 0; i < l.Len() && err == nil; i++ {
+				err = convertMessage(l.Get(i).Message(), version)
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				err = convertMessage(mv.Message(), version)
+				return err == nil
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			err = convertMessage(v.Message(), version)
+		}
+
+		return err == nil
+	})
+
+	return err
+}
+
+// convertAny rewrites the type URL of the Any to the counterpart of the version, and converts its value recursively.
+func convertAny(m protoreflect.Message, version api.XDSVersion) error {
+	fields := m.Descriptor().Fields()
+	typeURLField := fields.ByName("type_url")
+	valueField := fields.ByName("value")
+
+	typeURL := m.Get(typeURLField).String()
+	i := strings.LastIndex(typeURL, "/")
+	name := protoreflect.FullName(typeURL[i+1:])
+
+	target, err := convertedTypeName(name, version)
+	if err != nil {
+		return err
+	}
+
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(target)
+	if err != nil {
+		return fmt.Errorf("failed to find %s: %w", target, err)
+	}
+
+	value := mt.New()
+	if err := protov2.Unmarshal(m.Get(valueField).Bytes(), value.Interface()); err != nil {
+		return fmt.Errorf("failed to unmarshal %s as %s: %w", name, target, err)
+	}
+
+	if err := convertMessage(value, version); err != nil {
+		return err
+	}
+
+	b, err := protov2.MarshalOptions{Deterministic: true}.Marshal(value.Interface())
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", target, err)
+	}
+
+	m.Set(typeURLField, protoreflect.ValueOfString(typeURL[:i+1]+string(target)))
+	m.Set(valueField, protoreflect.ValueOfBytes(b))
+
+	return nil
+}
+
+// convertedTypeName returns the full name of the counterpart of the message in the version.
+// v2 messages which have no v3 counterparts are rejected since v3 Envoys can not load them.
+// Conversely, v3 messages which have no v2 counterparts are kept as they are, so that configurations only available in v3 can still be served to v3 Envoys.
+func convertedTypeName(name protoreflect.FullName, version api.XDSVersion) (protoreflect.FullName, error) {
+	switch version {
+	case api.XDSVersionV3:
+		if t, ok := upgradedTypes[name]; ok {
+			return t, nil
+		}
+
+		if _, ok := downgradedTypes[name]; !ok && isV2Message(name) {
+			return "", fmt.Errorf("%s has no v3 counterpart", name)
+		}
+	case api.XDSVersionV2:
+		if t, ok := downgradedTypes[name]; ok {
+			return t, nil
+		}
+	}
+
+	return name, nil
+}
+
+// isV2Message reports whether the message belongs to the Envoy API before v3, since v3 and later ones are all versioned like v3 or v3alpha in their package names.
+func isV2Message(name protoreflect.FullName) bool {
+	if !strings.HasPrefix(string(name), "envoy.") {
+		return false
+	}
+
+	for _, p := range strings.Split(string(name.Parent()), ".") {
+		if strings.HasPrefix(p, "v3") || strings.HasPrefix(p, "v4") {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isAPIVersionField(fd protoreflect.FieldDescriptor) bool {
+	if fd.Kind() != protoreflect.EnumKind || fd.IsList() || fd.ContainingOneof() != nil {
+		return false
+	}
+
+	switch fd.Enum().FullName() {
+	case "envoy.api.v2.core.ApiVersion", "envoy.config.core.v3.ApiVersion":
+		return true
+	default:
+		return false
+	}
+}
+
+// loadVersionedTypes maps v2 and v3 messages each other by their `udpa.annotations.versioning` options.
+func loadVersionedTypes() {
+	previous := map[protoreflect.FullName]protoreflect.FullName{}
+	protoregistry.GlobalTypes.RangeMessages(func(mt protoreflect.MessageType) bool {
+		opts, ok := mt.Descriptor().Options().(*descriptorpb.MessageOptions)
+		if !ok || opts == nil {
+			return true
+		}
+
+		ext, err := proto.GetExtension(opts, udpa.E_Versioning)
+		if err != nil {
+			return true
+		}
+
+		if p := ext.(*udpa.VersioningAnnotation).GetPreviousMessageType(); p != "" {
+			previous[mt.Descriptor().FullName()] = protoreflect.FullName(p)
+		}
+
+		return true
+	})
+
+	upgradedTypes = map[protoreflect.FullName]protoreflect.FullName{}
+	downgradedTypes = map[protoreflect.FullName]protoreflect.FullName{}
+	for name, p := range previous {
+		// NOTE: skip v4alpha messages, whose previous messages are v3 ones.
+		if _, ok := previous[p]; ok {
+			continue
+		}
+
+		upgradedTypes[p] = name
+		downgradedTypes[name] = p
+	}
+}
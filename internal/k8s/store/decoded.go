@@ -0,0 +1,216 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+)
+
+// decodedResources holds the resources decoded from the objects of the informer cache,
+// so that each object is decoded once when it changes rather than every time it is got or listed.
+// The decoded resources are shared by all callers, thus they must not be modified.
+type decodedResources struct {
+	mu    sync.Mutex
+	items map[resourceKey]*decodedResource
+}
+
+type decodedResource struct {
+	version string
+	item    interface{}
+}
+
+func newDecodedResources() *decodedResources {
+	return &decodedResources{
+		items: map[resourceKey]*decodedResource{},
+	}
+}
+
+// decode returns the resource decoded from the object of the version, and calls fn to decode it only if the version has changed.
+// Objects which can not be decoded are not remembered, so that they are decoded again when got or listed next time.
+func (r *decodedResources) decode(kind string, object *unstructured.Unstructured, version string, fn func() (interface{}, error)) (interface{}, error) {
+	if version == "" {
+		return fn()
+	}
+
+	key := resourceKey{kind: kind, namespace: object.GetNamespace(), name: object.GetName()}
+
+	r.mu.Lock()
+	d, ok := r.items[key]
+	r.mu.Unlock()
+
+	if ok && d.version == version {
+		return d.item, nil
+	}
+
+	item, err := fn()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.items[key] = &decodedResource{version: version, item: item}
+	r.mu.Unlock()
+
+	return item, nil
+}
+
+// forget removes the resource, e.g. when it has been deleted.
+func (r *decodedResources) forget(kind, name, namespace string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.items, resourceKey{kind: kind, namespace: namespace, name: name})
+}
+
+// prune removes the resources of the kind in the namespace which no longer exist.
+// Resources in other namespaces are pruned when their own namespaces are listed, since objects only contain the ones exported to the namespace,
+// and resources deleted from namespaces which are not listed again are removed by Forget.
+func (r *decodedResources) prune(kind, namespace string, objects []unstructured.Unstructured) {
+	exists := make(map[resourceKey]struct{}, len(objects))
+	for _, o := range objects {
+		exists[resourceKey{kind: kind, namespace: o.GetNamespace(), name: o.GetName()}] = struct{}{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key := range r.items {
+		if key.kind != kind || key.namespace != namespace {
+			continue
+		}
+
+		if _, ok := exists[key]; !ok {
+			delete(r.items, key)
+		}
+	}
+}
+
+// objectVersion returns the version which changes whenever the spec of the object changes.
+// The generation is used rather than the resource version since updates of the status, which are made on every push, do not change it.
+func objectVersion(object *unstructured.Unstructured) string {
+	if g := object.GetGeneration(); g > 0 {
+		return fmt.Sprintf("%s/%d", object.GetUID(), g)
+	}
+
+	return object.GetResourceVersion()
+}
+
+func (s *store) decodeCluster(object *unstructured.Unstructured) (*api.Cluster, error) {
+	item, err := s.decoded.decode(api.ClusterKind, object, objectVersion(object), func() (interface{}, error) {
+		return s.unmarshalCluster(object.Object)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item.(*api.Cluster), nil
+}
+
+func (s *store) decodeListener(object *unstructured.Unstructured) (*api.Listener, error) {
+	item, err := s.decoded.decode(api.ListenerKind, object, objectVersion(object), func() (interface{}, error) {
+		return s.unmarshalListener(object.Object)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item.(*api.Listener), nil
+}
+
+func (s *store) decodeRoute(object *unstructured.Unstructured) (*api.Route, error) {
+	item, err := s.decoded.decode(api.RouteKind, object, objectVersion(object), func() (interface{}, error) {
+		return s.unmarshalRoute(object.Object)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item.(*api.Route), nil
+}
+
+func (s *store) decodeEndpoint(object *unstructured.Unstructured) (*api.Endpoint, error) {
+	item, err := s.decoded.decode(api.EndpointKind, object, objectVersion(object), func() (interface{}, error) {
+		return s.unmarshalEndpoint(object.Object)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item.(*api.Endpoint), nil
+}
+
+func (s *store) decodeRuntime(object *unstructured.Unstructured) (*api.Runtime, error) {
+	item, err := s.decoded.decode(api.RuntimeKind, object, objectVersion(object), func() (interface{}, error) {
+		return s.unmarshalRuntime(object.Object)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item.(*api.Runtime), nil
+}
+
+func (s *store) decodeScopedRoute(object *unstructured.Unstructured) (*api.ScopedRoute, error) {
+	item, err := s.decoded.decode(api.ScopedRouteKind, object, objectVersion(object), func() (interface{}, error) {
+		return s.unmarshalScopedRoute(object.Object)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item.(*api.ScopedRoute), nil
+}
+
+func (s *store) decodeVirtualHost(object *unstructured.Unstructured) (*api.VirtualHost, error) {
+	item, err := s.decoded.decode(api.VirtualHostKind, object, objectVersion(object), func() (interface{}, error) {
+		return s.unmarshalVirtualHost(object.Object)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item.(*api.VirtualHost), nil
+}
+
+// decodeSecret also decodes the secret again when the secret referred by its spec.tlsSecretRef has changed,
+// since the certificate of the referred secret is injected into the decoded one.
+func (s *store) decodeSecret(ctx context.Context, object *unstructured.Unstructured, namespace string) (*api.Secret, error) {
+	spec, err := extractSpecFromObject(object.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := unmarshalTLSSecretRef(spec)
+	if err != nil && !errors.Is(err, errTLSSecretRefNotFound) {
+		return nil, err
+	}
+
+	version := objectVersion(object)
+
+	var tlsSecret *corev1.Secret
+	if ref != nil {
+		tlsSecret, err = s.getTLSSecret(ctx, ref, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		if version != "" {
+			version = fmt.Sprintf("%s/%s", version, tlsSecret.GetResourceVersion())
+		}
+	}
+
+	item, err := s.decoded.decode(api.SecretKind, object, version, func() (interface{}, error) {
+		return s.unmarshalSecret(object.Object, tlsSecret)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return item.(*api.Secret), nil
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+)
+
+// ExportToField is the field indexed to find the resources exported to a namespace, or to all namespaces by api.ExportToAllNamespaces.
+const ExportToField = "spec.exportTo"
+
+// exportableKinds are the kinds of the resources which can be exported to other namespaces.
+var exportableKinds = []string{
+	api.ClusterKind,
+	api.ListenerKind,
+	api.RouteKind,
+	api.EndpointKind,
+	api.RuntimeKind,
+	api.ScopedRouteKind,
+	api.VirtualHostKind,
+	api.SecretKind,
+}
+
+// IndexFields registers the indexes of the fields by which the store lists resources,
+// which must be done before the cache starts.
+func IndexFields(ctx context.Context, indexer client.FieldIndexer) error {
+	for _, kind := range exportableKinds {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(api.GroupVersion.WithKind(kind))
+
+		if err := indexer.IndexField(ctx, obj, ExportToField, ExportTo); err != nil {
+			return fmt.Errorf("failed to index %s of %s: %w", ExportToField, kind, err)
+		}
+	}
+
+	return nil
+}
+
+// ExportTo returns spec.exportTo of the resource, which is read without parsing the whole resource.
+func ExportTo(obj runtime.Object) []string {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil
+	}
+
+	exportTo, _, err := unstructured.NestedStringSlice(u.Object, "spec", "exportTo")
+	if err != nil {
+		return nil
+	}
+
+	return exportTo
+}
+
+// listVisible lists the resources of the kind in the namespace and the ones exported to it by the index of spec.exportTo.
+// Exported resources are followed by local ones, so that the local ones take precedence over the exported ones with the same name.
+func (s *store) listVisible(ctx context.Context, kind, namespace string) ([]unstructured.Unstructured, error) {
+	local := newUnstructuredList(kind)
+	if err := s.client.List(ctx, local, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	var exported []unstructured.Unstructured
+	seen := map[client.ObjectKey]struct{}{}
+	for _, value := range []string{namespace, api.ExportToAllNamespaces} {
+		list := newUnstructuredList(kind)
+		if err := s.client.List(ctx, list, client.MatchingFields{ExportToField: value}); err != nil {
+			return nil, err
+		}
+
+		for _, item := range list.Items {
+			key := client.ObjectKey{Namespace: item.GetNamespace(), Name: item.GetName()}
+			if _, ok := seen[key]; ok || key.Namespace == namespace {
+				continue
+			}
+			seen[key] = struct{}{}
+
+			exported = append(exported, item)
+		}
+	}
+
+	return append(exported, local.Items...), nil
+}
+
+// IsExportedTo returns true if spec.exportTo contains the namespace or exports the resource to all namespaces.
+func IsExportedTo(exportTo []string, namespace string) bool {
+	for _, ns := range exportTo {
+		if ns == namespace || ns == api.ExportToAllNamespaces {
+			return true
+		}
+	}
+
+	return false
+}
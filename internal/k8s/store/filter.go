@@ -1,6 +1,8 @@
 package store
 
 import (
+	"k8s.io/apimachinery/pkg/labels"
+
 	api "github.com/110y/bootes/internal/k8s/api/v1"
 )
 
@@ -48,20 +50,60 @@ func FilterEndpointsByLabels(endpoints []*api.Endpoint, labels map[string]string
 	return results
 }
 
-func matchSelector(resource api.EnvoyResource, labels map[string]string) bool {
+func FilterRuntimesByLabels(runtimes []*api.Runtime, labels map[string]string) []*api.Runtime {
+	results := []*api.Runtime{}
+	for _, r := range runtimes {
+		if matchSelector(r, labels) {
+			results = append(results, r)
+		}
+	}
+
+	return results
+}
+
+func FilterScopedRoutesByLabels(scopedRoutes []*api.ScopedRoute, labels map[string]string) []*api.ScopedRoute {
+	results := []*api.ScopedRoute{}
+	for _, r := range scopedRoutes {
+		if matchSelector(r, labels) {
+			results = append(results, r)
+		}
+	}
+
+	return results
+}
+
+func FilterVirtualHostsByLabels(virtualHosts []*api.VirtualHost, labels map[string]string) []*api.VirtualHost {
+	results := []*api.VirtualHost{}
+	for _, v := range virtualHosts {
+		if matchSelector(v, labels) {
+			results = append(results, v)
+		}
+	}
+
+	return results
+}
+
+func FilterSecretsByLabels(secrets []*api.Secret, labels map[string]string) []*api.Secret {
+	results := []*api.Secret{}
+	for _, s := range secrets {
+		if matchSelector(s, labels) {
+			results = append(results, s)
+		}
+	}
+
+	return results
+}
+
+func matchSelector(resource api.EnvoyResource, podLabels map[string]string) bool {
 	ws := resource.GetWorkloadSelector()
 	if ws == nil {
 		return true
 	}
 
-	match := true
-	for key, val := range ws.Labels {
-		v, ok := labels[key]
-		if !ok || v != val {
-			match = false
-			break
-		}
+	selector, err := ws.AsSelector()
+	if err != nil {
+		return false
 	}
 
-	return match
+	return selector.Matches(labels.Set(podLabels))
 }
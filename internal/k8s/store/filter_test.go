@@ -163,3 +163,133 @@ func TestFilterClusters(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterClustersWithMatchExpressions(t *testing.T) {
+	canaryOrStable := &api.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "canary-or-stable"},
+		Spec: api.ClusterSpec{
+			WorkloadSelector: &api.WorkloadSelector{
+				Labels: map[string]string{
+					"app": "envoy",
+				},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "track",
+						Operator: metav1.LabelSelectorOpIn,
+						Values:   []string{"canary", "stable"},
+					},
+				},
+			},
+		},
+	}
+	exceptBackend := &api.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "except-backend"},
+		Spec: api.ClusterSpec{
+			WorkloadSelector: &api.WorkloadSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "tier",
+						Operator: metav1.LabelSelectorOpNotIn,
+						Values:   []string{"backend"},
+					},
+				},
+			},
+		},
+	}
+	tracked := &api.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "tracked"},
+		Spec: api.ClusterSpec{
+			WorkloadSelector: &api.WorkloadSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "track",
+						Operator: metav1.LabelSelectorOpExists,
+					},
+				},
+			},
+		},
+	}
+	untracked := &api.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "untracked"},
+		Spec: api.ClusterSpec{
+			WorkloadSelector: &api.WorkloadSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "track",
+						Operator: metav1.LabelSelectorOpDoesNotExist,
+					},
+				},
+			},
+		},
+	}
+	invalid := &api.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "invalid"},
+		Spec: api.ClusterSpec{
+			WorkloadSelector: &api.WorkloadSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "track",
+						Operator: metav1.LabelSelectorOpIn,
+					},
+				},
+			},
+		},
+	}
+
+	clusters := []*api.Cluster{
+		canaryOrStable,
+		exceptBackend,
+		tracked,
+		untracked,
+		invalid,
+	}
+
+	tests := map[string]struct {
+		podLabels map[string]string
+		expected  []*api.Cluster
+	}{
+		"canary frontend": {
+			podLabels: map[string]string{
+				"app":   "envoy",
+				"track": "canary",
+				"tier":  "frontend",
+			},
+			expected: []*api.Cluster{
+				canaryOrStable,
+				exceptBackend,
+				tracked,
+			},
+		},
+		"experimental backend": {
+			podLabels: map[string]string{
+				"app":   "envoy",
+				"track": "experimental",
+				"tier":  "backend",
+			},
+			expected: []*api.Cluster{
+				tracked,
+			},
+		},
+		"untracked pod without tier": {
+			podLabels: map[string]string{
+				"app": "envoy",
+			},
+			expected: []*api.Cluster{
+				exceptBackend,
+				untracked,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := store.FilterClustersByLabels(clusters, test.podLabels)
+			if diff := cmp.Diff(test.expected, actual); diff != "" {
+				t.Errorf("diff: %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+)
+
+// InvalidResourceHandler is called when a resource is skipped by list methods since it can not be parsed.
+type InvalidResourceHandler func(ctx context.Context, object *unstructured.Unstructured, err *InvalidResourceError)
+
+type Option func(*store)
+
+// WithInvalidResourceHandler sets the handler called for each resource skipped by list methods.
+func WithInvalidResourceHandler(h InvalidResourceHandler) Option {
+	return func(s *store) {
+		s.invalidResourceHandler = h
+	}
+}
+
+// ForgetHandler is called when a resource has been forgotten by Forget.
+type ForgetHandler func(kind, name, namespace string)
+
+// WithForgetHandler sets the handler called for each resource forgotten by Forget, e.g. to forget the reports of the resource.
+func WithForgetHandler(h ForgetHandler) Option {
+	return func(s *store) {
+		s.forgetHandler = h
+	}
+}
+
+type resourceKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// lastValidResources holds the last successfully parsed resources,
+// which are listed instead of the resources broken by later updates so that proxies keep the configurations they already have.
+type lastValidResources struct {
+	mu    sync.Mutex
+	items map[resourceKey]interface{}
+}
+
+func newLastValidResources() *lastValidResources {
+	return &lastValidResources{
+		items: map[resourceKey]interface{}{},
+	}
+}
+
+func (r *lastValidResources) set(kind string, object *unstructured.Unstructured, item interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.items[resourceKey{kind: kind, namespace: object.GetNamespace(), name: object.GetName()}] = item
+}
+
+func (r *lastValidResources) get(kind string, object *unstructured.Unstructured) (interface{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item, ok := r.items[resourceKey{kind: kind, namespace: object.GetNamespace(), name: object.GetName()}]
+	return item, ok
+}
+
+// forget removes the resource, e.g. when it has been deleted.
+func (r *lastValidResources) forget(kind, name, namespace string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.items, resourceKey{kind: kind, namespace: namespace, name: name})
+}
+
+// prune removes the resources of the kind in the namespace which no longer exist.
+// Resources in other namespaces are pruned when their own namespaces are listed, since objects only contain the ones exported to the namespace,
+// and resources deleted from namespaces which are not listed again are removed by Forget.
+func (r *lastValidResources) prune(kind, namespace string, objects []unstructured.Unstructured) {
+	exists := make(map[resourceKey]struct{}, len(objects))
+	for _, o := range objects {
+		exists[resourceKey{kind: kind, namespace: o.GetNamespace(), name: o.GetName()}] = struct{}{}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key := range r.items {
+		if key.kind != kind || key.namespace != namespace {
+			continue
+		}
+
+		if _, ok := exists[key]; !ok {
+			delete(r.items, key)
+		}
+	}
+}
+
+// skipInvalidResource reports the resource which can not be parsed and returns its last valid one if exists.
+// Errors not caused by the resource itself (e.g. failures of the API server) are returned as they are.
+func (s *store) skipInvalidResource(ctx context.Context, kind string, object *unstructured.Unstructured, err error) (interface{}, error) {
+	err = newInvalidResourceError(err)
+
+	var invalid *InvalidResourceError
+	if !errors.As(err, &invalid) {
+		return nil, err
+	}
+
+	if s.invalidResourceHandler != nil {
+		object.SetGroupVersionKind(api.GroupVersion.WithKind(kind))
+		s.invalidResourceHandler(ctx, object, invalid)
+	}
+
+	last, ok := s.lastValid.get(kind, object)
+	if !ok {
+		return nil, nil
+	}
+
+	return last, nil
+}
+
+// Forget removes what is kept for the resource which has been deleted, since it may never be pruned if its namespace is not listed again.
+func (s *store) Forget(kind, name, namespace string) {
+	s.lastValid.forget(kind, name, namespace)
+	s.decoded.forget(kind, name, namespace)
+
+	if s.forgetHandler != nil {
+		s.forgetHandler(kind, name, namespace)
+	}
+}
@@ -7,13 +7,25 @@ import (
 	"fmt"
 
 	envoyapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	envoycore "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
+	runtimev3 "github.com/envoyproxy/go-control-plane/envoy/service/runtime/v3"
 	"github.com/golang/protobuf/proto"
 	"google.golang.org/protobuf/encoding/protojson"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	api "github.com/110y/bootes/internal/k8s/api/v1"
@@ -24,12 +36,37 @@ var (
 	ErrNotFound = errors.New("resource not found")
 
 	errWorkloadSelectorNotFound = errors.New("workloadSelector not found")
+	errTLSSecretRefNotFound     = errors.New("tlsSecretRef not found")
 )
 
+// InvalidResourceError is returned when a resource can not be parsed into its envoy configuration.
+type InvalidResourceError struct {
+	err error
+}
+
+func (e *InvalidResourceError) Error() string {
+	return e.err.Error()
+}
+
+func (e *InvalidResourceError) Unwrap() error {
+	return e.err
+}
+
+func newInvalidResourceError(err error) error {
+	// NOTE: errors returned from the API server are not caused by the resource itself.
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		return err
+	}
+
+	return &InvalidResourceError{err: err}
+}
+
 type ListOption func(*listOption)
 
 type listOption struct {
-	filterLabels map[string]string
+	filterLabels      map[string]string
+	filterExpressions []metav1.LabelSelectorRequirement
 }
 
 func WithLabelFilter(labels map[string]string) ListOption {
@@ -38,6 +75,42 @@ func WithLabelFilter(labels map[string]string) ListOption {
 	}
 }
 
+// WithWorkloadSelector filters resources by both the labels and the expressions of the selector.
+func WithWorkloadSelector(ws *api.WorkloadSelector) ListOption {
+	return func(opt *listOption) {
+		opt.filterLabels = ws.Labels
+		opt.filterExpressions = ws.MatchExpressions
+	}
+}
+
+// Selector returns the label selector which the options filter resources by.
+func Selector(options ...ListOption) (labels.Selector, error) {
+	return newListOption(options).selector()
+}
+
+func newListOption(options []ListOption) *listOption {
+	opt := &listOption{}
+	for _, o := range options {
+		o(opt)
+	}
+
+	return opt
+}
+
+func (o *listOption) selector() (labels.Selector, error) {
+	ws := &api.WorkloadSelector{
+		Labels:           o.filterLabels,
+		MatchExpressions: o.filterExpressions,
+	}
+
+	selector, err := ws.AsSelector()
+	if err != nil {
+		return nil, fmt.Errorf("failed to use labels.Selector: %w", err)
+	}
+
+	return selector, nil
+}
+
 var _ Store = (*store)(nil)
 
 type Store interface {
@@ -49,24 +122,56 @@ type Store interface {
 	ListRoutesByNamespace(ctx context.Context, namespace string) (*api.RouteList, error)
 	GetEndpoint(ctx context.Context, name, namespace string) (*api.Endpoint, error)
 	ListEndpointsByNamespace(ctx context.Context, namespace string) (*api.EndpointList, error)
+	GetRuntime(ctx context.Context, name, namespace string) (*api.Runtime, error)
+	ListRuntimesByNamespace(ctx context.Context, namespace string) (*api.RuntimeList, error)
+	GetScopedRoute(ctx context.Context, name, namespace string) (*api.ScopedRoute, error)
+	ListScopedRoutesByNamespace(ctx context.Context, namespace string) (*api.ScopedRouteList, error)
+	GetVirtualHost(ctx context.Context, name, namespace string) (*api.VirtualHost, error)
+	ListVirtualHostsByNamespace(ctx context.Context, namespace string) (*api.VirtualHostList, error)
+	GetSecret(ctx context.Context, name, namespace string) (*api.Secret, error)
+	ListSecretsByNamespace(ctx context.Context, namespace string) (*api.SecretList, error)
+	GetObject(ctx context.Context, kind, name, namespace string) (*unstructured.Unstructured, error)
+	UpdateStatus(ctx context.Context, kind, name, namespace string, update func(*api.Status)) error
+	Forget(kind, name, namespace string)
 	GetPod(ctx context.Context, name, namespace string) (*corev1.Pod, error)
 	ListPodsByNamespace(ctx context.Context, namespace string, options ...ListOption) (*corev1.PodList, error)
+	GetWorkloadEntry(ctx context.Context, name, namespace string) (*api.WorkloadEntry, error)
+	ListWorkloadEntriesByNamespace(ctx context.Context, namespace string, options ...ListOption) (*api.WorkloadEntryList, error)
 }
 
 type store struct {
-	client      client.Client
-	reader      client.Reader
-	unmarshaler *protojson.UnmarshalOptions
-}
-
-func New(c client.Client, reader client.Reader) Store {
-	return &store{
-		client: c,
-		reader: reader,
-		unmarshaler: &protojson.UnmarshalOptions{
-			AllowPartial:   false,
-			DiscardUnknown: true,
-		},
+	client                 client.Client
+	reader                 client.Reader
+	tlsSecretReader        client.Reader
+	unmarshaler            *protojson.UnmarshalOptions
+	lastValid              *lastValidResources
+	decoded                *decodedResources
+	invalidResourceHandler InvalidResourceHandler
+	forgetHandler          ForgetHandler
+}
+
+func New(c client.Client, reader client.Reader, opts ...Option) Store {
+	s := &store{
+		client:          c,
+		reader:          reader,
+		tlsSecretReader: reader,
+		unmarshaler:     newUnmarshaler(),
+		lastValid:       newLastValidResources(),
+		decoded:         newDecodedResources(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// WithTLSSecretReader sets the reader of Secrets referred by spec.tlsSecretRef, e.g. the cache of the labeled ones,
+// instead of the reader passed to New.
+func WithTLSSecretReader(r client.Reader) Option {
+	return func(s *store) {
+		s.tlsSecretReader = r
 	}
 }
 
@@ -94,9 +199,9 @@ func (s *store) GetCluster(ctx context.Context, name, namespace string) (*api.Cl
 		return nil, fmt.Errorf("failed to get cluster: %w", err)
 	}
 
-	c, err := s.unmarshalCluster(cluster.Object)
+	c, err := s.decodeCluster(cluster)
 	if err != nil {
-		return nil, err
+		return nil, newInvalidResourceError(err)
 	}
 
 	return c, nil
@@ -106,29 +211,36 @@ func (s *store) ListClustersByNamespace(ctx context.Context, namespace string) (
 	ctx, span := trace.NewSpan(ctx, "Store.ListClustersByNamespace")
 	defer span.End()
 
-	clusters := &unstructured.UnstructuredList{
-		Object: map[string]interface{}{
-			"kind":       api.ClusterKind,
-			"apiVersion": api.GroupVersion.String(),
-		},
-	}
-	err := s.client.List(ctx, clusters, &client.ListOptions{
-		Namespace: namespace,
-	})
+	clusters, err := s.listVisible(ctx, api.ClusterKind, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list clusters: %w", err)
 	}
 
-	items := make([]*api.Cluster, len(clusters.Items))
-	for i, c := range clusters.Items {
-		cluster, err := s.unmarshalCluster(c.Object)
+	items := []*api.Cluster{}
+	for i := range clusters {
+		c := &clusters[i]
+
+		cluster, err := s.decodeCluster(c)
 		if err != nil {
-			return nil, err
+			last, err := s.skipInvalidResource(ctx, api.ClusterKind, c, err)
+			if err != nil {
+				return nil, err
+			}
+
+			cluster, _ = last.(*api.Cluster)
+			if cluster == nil {
+				continue
+			}
+		} else {
+			s.lastValid.set(api.ClusterKind, c, cluster)
 		}
 
-		items[i] = cluster
+		items = append(items, cluster)
 	}
 
+	s.lastValid.prune(api.ClusterKind, namespace, clusters)
+	s.decoded.prune(api.ClusterKind, namespace, clusters)
+
 	return &api.ClusterList{
 		Items: items,
 	}, nil
@@ -158,9 +270,9 @@ func (s *store) GetListener(ctx context.Context, name, namespace string) (*api.L
 		return nil, fmt.Errorf("failed to get route: %w", err)
 	}
 
-	l, err := s.unmarshalListener(listener.Object)
+	l, err := s.decodeListener(listener)
 	if err != nil {
-		return nil, err
+		return nil, newInvalidResourceError(err)
 	}
 
 	return l, nil
@@ -170,29 +282,36 @@ func (s *store) ListListenersByNamespace(ctx context.Context, namespace string)
 	ctx, span := trace.NewSpan(ctx, "Store.ListListenersByNamespace")
 	defer span.End()
 
-	listeners := &unstructured.UnstructuredList{
-		Object: map[string]interface{}{
-			"kind":       api.ListenerKind,
-			"apiVersion": api.GroupVersion.String(),
-		},
-	}
-	err := s.client.List(ctx, listeners, &client.ListOptions{
-		Namespace: namespace,
-	})
+	listeners, err := s.listVisible(ctx, api.ListenerKind, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list listeners: %w", err)
 	}
 
-	items := make([]*api.Listener, len(listeners.Items))
-	for i, c := range listeners.Items {
-		listener, err := s.unmarshalListener(c.Object)
+	items := []*api.Listener{}
+	for i := range listeners {
+		c := &listeners[i]
+
+		listener, err := s.decodeListener(c)
 		if err != nil {
-			return nil, err
+			last, err := s.skipInvalidResource(ctx, api.ListenerKind, c, err)
+			if err != nil {
+				return nil, err
+			}
+
+			listener, _ = last.(*api.Listener)
+			if listener == nil {
+				continue
+			}
+		} else {
+			s.lastValid.set(api.ListenerKind, c, listener)
 		}
 
-		items[i] = listener
+		items = append(items, listener)
 	}
 
+	s.lastValid.prune(api.ListenerKind, namespace, listeners)
+	s.decoded.prune(api.ListenerKind, namespace, listeners)
+
 	return &api.ListenerList{
 		Items: items,
 	}, nil
@@ -222,9 +341,9 @@ func (s *store) GetRoute(ctx context.Context, name, namespace string) (*api.Rout
 		return nil, fmt.Errorf("failed to get route: %w", err)
 	}
 
-	r, err := s.unmarshalRoute(route.Object)
+	r, err := s.decodeRoute(route)
 	if err != nil {
-		return nil, err
+		return nil, newInvalidResourceError(err)
 	}
 
 	return r, nil
@@ -234,29 +353,36 @@ func (s *store) ListRoutesByNamespace(ctx context.Context, namespace string) (*a
 	ctx, span := trace.NewSpan(ctx, "Store.ListRoutesByNamespace")
 	defer span.End()
 
-	routes := &unstructured.UnstructuredList{
-		Object: map[string]interface{}{
-			"kind":       api.RouteKind,
-			"apiVersion": api.GroupVersion.String(),
-		},
-	}
-	err := s.client.List(ctx, routes, &client.ListOptions{
-		Namespace: namespace,
-	})
+	routes, err := s.listVisible(ctx, api.RouteKind, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list routes: %w", err)
 	}
 
-	items := make([]*api.Route, len(routes.Items))
-	for i, c := range routes.Items {
-		route, err := s.unmarshalRoute(c.Object)
+	items := []*api.Route{}
+	for i := range routes {
+		c := &routes[i]
+
+		route, err := s.decodeRoute(c)
 		if err != nil {
-			return nil, err
+			last, err := s.skipInvalidResource(ctx, api.RouteKind, c, err)
+			if err != nil {
+				return nil, err
+			}
+
+			route, _ = last.(*api.Route)
+			if route == nil {
+				continue
+			}
+		} else {
+			s.lastValid.set(api.RouteKind, c, route)
 		}
 
-		items[i] = route
+		items = append(items, route)
 	}
 
+	s.lastValid.prune(api.RouteKind, namespace, routes)
+	s.decoded.prune(api.RouteKind, namespace, routes)
+
 	return &api.RouteList{
 		Items: items,
 	}, nil
@@ -286,9 +412,9 @@ func (s *store) GetEndpoint(ctx context.Context, name, namespace string) (*api.E
 		return nil, fmt.Errorf("failed to get endpoint: %w", err)
 	}
 
-	e, err := s.unmarshalEndpoint(endpoint.Object)
+	e, err := s.decodeEndpoint(endpoint)
 	if err != nil {
-		return nil, err
+		return nil, newInvalidResourceError(err)
 	}
 
 	return e, nil
@@ -298,36 +424,43 @@ func (s *store) ListEndpointsByNamespace(ctx context.Context, namespace string)
 	ctx, span := trace.NewSpan(ctx, "Store.ListEndpointsByNamespace")
 	defer span.End()
 
-	routes := &unstructured.UnstructuredList{
-		Object: map[string]interface{}{
-			"kind":       api.EndpointKind,
-			"apiVersion": api.GroupVersion.String(),
-		},
-	}
-	err := s.client.List(ctx, routes, &client.ListOptions{
-		Namespace: namespace,
-	})
+	routes, err := s.listVisible(ctx, api.EndpointKind, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list endpoints: %w", err)
 	}
 
-	items := make([]*api.Endpoint, len(routes.Items))
-	for i, c := range routes.Items {
-		endpoint, err := s.unmarshalEndpoint(c.Object)
+	items := []*api.Endpoint{}
+	for i := range routes {
+		c := &routes[i]
+
+		endpoint, err := s.decodeEndpoint(c)
 		if err != nil {
-			return nil, err
+			last, err := s.skipInvalidResource(ctx, api.EndpointKind, c, err)
+			if err != nil {
+				return nil, err
+			}
+
+			endpoint, _ = last.(*api.Endpoint)
+			if endpoint == nil {
+				continue
+			}
+		} else {
+			s.lastValid.set(api.EndpointKind, c, endpoint)
 		}
 
-		items[i] = endpoint
+		items = append(items, endpoint)
 	}
 
+	s.lastValid.prune(api.EndpointKind, namespace, routes)
+	s.decoded.prune(api.EndpointKind, namespace, routes)
+
 	return &api.EndpointList{
 		Items: items,
 	}, nil
 }
 
-func (s *store) GetPod(ctx context.Context, name, namespace string) (*corev1.Pod, error) {
-	ctx, span := trace.NewSpan(ctx, "Store.GetPod")
+func (s *store) GetRuntime(ctx context.Context, name, namespace string) (*api.Runtime, error) {
+	ctx, span := trace.NewSpan(ctx, "Store.GetRuntime")
 	defer span.End()
 
 	key := client.ObjectKey{
@@ -335,203 +468,664 @@ func (s *store) GetPod(ctx context.Context, name, namespace string) (*corev1.Pod
 		Namespace: namespace,
 	}
 
-	var pod corev1.Pod
-	if err := s.reader.Get(ctx, key, &pod); err != nil {
+	runtime := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       api.RuntimeKind,
+			"apiVersion": api.GroupVersion.String(),
+		},
+	}
+
+	if err := s.client.Get(ctx, key, runtime); err != nil {
 		if apierrors.IsNotFound(err) {
 			return nil, ErrNotFound
 		}
 
-		return nil, fmt.Errorf("failed to get pod: %w", err)
+		return nil, fmt.Errorf("failed to get runtime: %w", err)
 	}
 
-	return &pod, nil
+	r, err := s.decodeRuntime(runtime)
+	if err != nil {
+		return nil, newInvalidResourceError(err)
+	}
+
+	return r, nil
 }
 
-func (s *store) ListPodsByNamespace(ctx context.Context, namespace string, options ...ListOption) (*corev1.PodList, error) {
-	ctx, span := trace.NewSpan(ctx, "Store.ListPodsByNamespace")
+func (s *store) ListRuntimesByNamespace(ctx context.Context, namespace string) (*api.RuntimeList, error) {
+	ctx, span := trace.NewSpan(ctx, "Store.ListRuntimesByNamespace")
 	defer span.End()
 
-	opt := &listOption{}
-	for _, o := range options {
-		o(opt)
-	}
-
-	lo := &client.ListOptions{
-		Namespace: namespace,
+	runtimes, err := s.listVisible(ctx, api.RuntimeKind, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runtimes: %w", err)
 	}
 
-	lenLabels := len(opt.filterLabels)
-	if lenLabels != 0 {
-		requirements := make([]labels.Requirement, lenLabels)
+	items := []*api.Runtime{}
+	for i := range runtimes {
+		c := &runtimes[i]
 
-		i := 0
-		for key, val := range opt.filterLabels {
-			r, err := labels.NewRequirement(key, selection.Equals, []string{val})
+		runtime, err := s.decodeRuntime(c)
+		if err != nil {
+			last, err := s.skipInvalidResource(ctx, api.RuntimeKind, c, err)
 			if err != nil {
-				return nil, fmt.Errorf("failed to use labels.Requirement")
+				return nil, err
 			}
 
-			requirements[i] = *r
-			i++
+			runtime, _ = last.(*api.Runtime)
+			if runtime == nil {
+				continue
+			}
+		} else {
+			s.lastValid.set(api.RuntimeKind, c, runtime)
 		}
 
-		lo.LabelSelector = labels.Everything().Add(requirements...)
+		items = append(items, runtime)
 	}
 
-	var pods corev1.PodList
-	err := s.client.List(ctx, &pods, lo)
-	if err != nil {
-		return nil, err
-	}
+	s.lastValid.prune(api.RuntimeKind, namespace, runtimes)
+	s.decoded.prune(api.RuntimeKind, namespace, runtimes)
 
-	return &pods, nil
+	return &api.RuntimeList{
+		Items: items,
+	}, nil
 }
 
-func extractSpecFromObject(object map[string]interface{}) (map[string]interface{}, error) {
-	spec, ok := object["spec"]
-	if !ok {
-		return nil, fmt.Errorf("spec not found")
+func (s *store) GetScopedRoute(ctx context.Context, name, namespace string) (*api.ScopedRoute, error) {
+	ctx, span := trace.NewSpan(ctx, "Store.GetScopedRoute")
+	defer span.End()
+
+	key := client.ObjectKey{
+		Name:      name,
+		Namespace: namespace,
 	}
 
-	s, ok := spec.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("invalid spec form")
+	scopedRoute := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       api.ScopedRouteKind,
+			"apiVersion": api.GroupVersion.String(),
+		},
 	}
 
-	return s, nil
-}
+	if err := s.client.Get(ctx, key, scopedRoute); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrNotFound
+		}
 
-func unmarshalWorkloadSelector(spec map[string]interface{}) (*api.WorkloadSelector, error) {
-	selector, ok := spec["workloadSelector"]
-	if !ok {
-		return nil, errWorkloadSelectorNotFound
+		return nil, fmt.Errorf("failed to get scoped route: %w", err)
 	}
 
-	j, err := json.Marshal(selector)
+	r, err := s.decodeScopedRoute(scopedRoute)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse spec.workloadSelector: %w", err)
-	}
-
-	var ws api.WorkloadSelector
-	if err := json.Unmarshal(j, &ws); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal spec.workloadSelector: %w", err)
+		return nil, newInvalidResourceError(err)
 	}
 
-	return &ws, nil
+	return r, nil
 }
 
-func (s *store) unmarshalCluster(object map[string]interface{}) (*api.Cluster, error) {
-	spec, err := extractSpecFromObject(object)
-	if err != nil {
-		return nil, err
-	}
+func (s *store) ListScopedRoutesByNamespace(ctx context.Context, namespace string) (*api.ScopedRouteList, error) {
+	ctx, span := trace.NewSpan(ctx, "Store.ListScopedRoutesByNamespace")
+	defer span.End()
 
-	config, err := s.unmarshalClusterConfig(spec)
+	scopedRoutes, err := s.listVisible(ctx, api.ScopedRouteKind, namespace)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to list scoped routes: %w", err)
 	}
 
-	selector, err := unmarshalWorkloadSelector(spec)
-	if err != nil && !errors.Is(err, errWorkloadSelectorNotFound) {
-		return nil, err
+	items := []*api.ScopedRoute{}
+	for i := range scopedRoutes {
+		c := &scopedRoutes[i]
+
+		scopedRoute, err := s.decodeScopedRoute(c)
+		if err != nil {
+			last, err := s.skipInvalidResource(ctx, api.ScopedRouteKind, c, err)
+			if err != nil {
+				return nil, err
+			}
+
+			scopedRoute, _ = last.(*api.ScopedRoute)
+			if scopedRoute == nil {
+				continue
+			}
+		} else {
+			s.lastValid.set(api.ScopedRouteKind, c, scopedRoute)
+		}
+
+		items = append(items, scopedRoute)
 	}
 
-	return &api.Cluster{
-		Spec: api.ClusterSpec{
-			WorkloadSelector: selector,
-			Config:           config,
-		},
+	s.lastValid.prune(api.ScopedRouteKind, namespace, scopedRoutes)
+	s.decoded.prune(api.ScopedRouteKind, namespace, scopedRoutes)
+
+	return &api.ScopedRouteList{
+		Items: items,
 	}, nil
 }
 
-func (s *store) unmarshalClusterConfig(spec map[string]interface{}) (*envoyapi.Cluster, error) {
-	config, err := unmarshalEnvoyConfig(spec)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal envoy configuration: %w", err)
+func (s *store) GetVirtualHost(ctx context.Context, name, namespace string) (*api.VirtualHost, error) {
+	ctx, span := trace.NewSpan(ctx, "Store.GetVirtualHost")
+	defer span.End()
+
+	key := client.ObjectKey{
+		Name:      name,
+		Namespace: namespace,
 	}
 
-	cluster := &envoyapi.Cluster{}
-	if err := s.unmarshaler.Unmarshal(config, proto.MessageV2(cluster)); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal spec.config: %w", err)
+	virtualHost := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       api.VirtualHostKind,
+			"apiVersion": api.GroupVersion.String(),
+		},
 	}
 
-	return cluster, nil
-}
+	if err := s.client.Get(ctx, key, virtualHost); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrNotFound
+		}
 
-func (s *store) unmarshalListener(object map[string]interface{}) (*api.Listener, error) {
-	spec, err := extractSpecFromObject(object)
+		return nil, fmt.Errorf("failed to get virtual host: %w", err)
+	}
+
+	r, err := s.decodeVirtualHost(virtualHost)
 	if err != nil {
-		return nil, err
+		return nil, newInvalidResourceError(err)
 	}
 
-	config, err := s.unmarshalListenerConfig(spec)
+	return r, nil
+}
+
+func (s *store) ListVirtualHostsByNamespace(ctx context.Context, namespace string) (*api.VirtualHostList, error) {
+	ctx, span := trace.NewSpan(ctx, "Store.ListVirtualHostsByNamespace")
+	defer span.End()
+
+	virtualHosts, err := s.listVisible(ctx, api.VirtualHostKind, namespace)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to list virtual hosts: %w", err)
 	}
 
-	selector, err := unmarshalWorkloadSelector(spec)
-	if err != nil && !errors.Is(err, errWorkloadSelectorNotFound) {
-		return nil, err
+	items := []*api.VirtualHost{}
+	for i := range virtualHosts {
+		c := &virtualHosts[i]
+
+		virtualHost, err := s.decodeVirtualHost(c)
+		if err != nil {
+			last, err := s.skipInvalidResource(ctx, api.VirtualHostKind, c, err)
+			if err != nil {
+				return nil, err
+			}
+
+			virtualHost, _ = last.(*api.VirtualHost)
+			if virtualHost == nil {
+				continue
+			}
+		} else {
+			s.lastValid.set(api.VirtualHostKind, c, virtualHost)
+		}
+
+		items = append(items, virtualHost)
 	}
 
-	return &api.Listener{
-		Spec: api.ListenerSpec{
-			WorkloadSelector: selector,
-			Config:           config,
-		},
+	s.lastValid.prune(api.VirtualHostKind, namespace, virtualHosts)
+	s.decoded.prune(api.VirtualHostKind, namespace, virtualHosts)
+
+	return &api.VirtualHostList{
+		Items: items,
 	}, nil
 }
 
-func (s *store) unmarshalListenerConfig(spec map[string]interface{}) (*envoyapi.Listener, error) {
-	config, err := unmarshalEnvoyConfig(spec)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal envoy configuration: %w", err)
+func (s *store) GetSecret(ctx context.Context, name, namespace string) (*api.Secret, error) {
+	ctx, span := trace.NewSpan(ctx, "Store.GetSecret")
+	defer span.End()
+
+	key := client.ObjectKey{
+		Name:      name,
+		Namespace: namespace,
 	}
 
-	listener := &envoyapi.Listener{}
-	if err := s.unmarshaler.Unmarshal(config, proto.MessageV2(listener)); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal spec.config: %w", err)
+	secret := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       api.SecretKind,
+			"apiVersion": api.GroupVersion.String(),
+		},
 	}
 
-	return listener, nil
-}
+	if err := s.client.Get(ctx, key, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrNotFound
+		}
 
-func (s *store) unmarshalRoute(object map[string]interface{}) (*api.Route, error) {
-	spec, err := extractSpecFromObject(object)
-	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to get secret: %w", err)
 	}
 
-	config, err := s.unmarshalRouteConfig(spec)
+	sec, err := s.decodeSecret(ctx, secret, namespace)
 	if err != nil {
-		return nil, err
+		return nil, newInvalidResourceError(err)
 	}
 
-	selector, err := unmarshalWorkloadSelector(spec)
+	return sec, nil
+}
+
+func (s *store) ListSecretsByNamespace(ctx context.Context, namespace string) (*api.SecretList, error) {
+	ctx, span := trace.NewSpan(ctx, "Store.ListSecretsByNamespace")
+	defer span.End()
+
+	secrets, err := s.listVisible(ctx, api.SecretKind, namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	items := []*api.Secret{}
+	for i := range secrets {
+		c := &secrets[i]
+
+		secret, err := s.decodeSecret(ctx, c, c.GetNamespace())
+		if err != nil {
+			last, err := s.skipInvalidResource(ctx, api.SecretKind, c, err)
+			if err != nil {
+				return nil, err
+			}
+
+			secret, _ = last.(*api.Secret)
+			if secret == nil {
+				continue
+			}
+		} else {
+			s.lastValid.set(api.SecretKind, c, secret)
+		}
+
+		items = append(items, secret)
+	}
+
+	s.lastValid.prune(api.SecretKind, namespace, secrets)
+	s.decoded.prune(api.SecretKind, namespace, secrets)
+
+	return &api.SecretList{
+		Items: items,
+	}, nil
+}
+
+// GetObject returns the resource as it is, which is used to refer to the resource, e.g. from Events.
+func (s *store) GetObject(ctx context.Context, kind, name, namespace string) (*unstructured.Unstructured, error) {
+	ctx, span := trace.NewSpan(ctx, "Store.GetObject")
+	defer span.End()
+
+	key := client.ObjectKey{
+		Name:      name,
+		Namespace: namespace,
+	}
+
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       kind,
+			"apiVersion": api.GroupVersion.String(),
+		},
+	}
+
+	if err := s.client.Get(ctx, key, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("failed to get %s: %w", kind, err)
+	}
+
+	return obj, nil
+}
+
+// UpdateStatus updates the status subresource of the resource by the update function which receives its current status.
+func (s *store) UpdateStatus(ctx context.Context, kind, name, namespace string, update func(*api.Status)) error {
+	ctx, span := trace.NewSpan(ctx, "Store.UpdateStatus")
+	defer span.End()
+
+	key := client.ObjectKey{
+		Name:      name,
+		Namespace: namespace,
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		obj := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       kind,
+				"apiVersion": api.GroupVersion.String(),
+			},
+		}
+
+		// NOTE: reads the latest object since updates of the stale one in the cache keep conflicting until the cache catches up.
+		if err := s.reader.Get(ctx, key, obj); err != nil {
+			if apierrors.IsNotFound(err) {
+				return ErrNotFound
+			}
+
+			return fmt.Errorf("failed to get %s: %w", kind, err)
+		}
+
+		status, err := unmarshalStatus(obj.Object)
+		if err != nil {
+			return err
+		}
+
+		update(status)
+
+		st, err := marshalStatus(status)
+		if err != nil {
+			return err
+		}
+
+		if err := unstructured.SetNestedField(obj.Object, st, "status"); err != nil {
+			return fmt.Errorf("failed to set status: %w", err)
+		}
+
+		return s.client.Status().Update(ctx, obj)
+	})
+}
+
+func (s *store) GetPod(ctx context.Context, name, namespace string) (*corev1.Pod, error) {
+	ctx, span := trace.NewSpan(ctx, "Store.GetPod")
+	defer span.End()
+
+	key := client.ObjectKey{
+		Name:      name,
+		Namespace: namespace,
+	}
+
+	var pod corev1.Pod
+	if err := s.reader.Get(ctx, key, &pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("failed to get pod: %w", err)
+	}
+
+	return &pod, nil
+}
+
+func (s *store) ListPodsByNamespace(ctx context.Context, namespace string, options ...ListOption) (*corev1.PodList, error) {
+	ctx, span := trace.NewSpan(ctx, "Store.ListPodsByNamespace")
+	defer span.End()
+
+	lo := &client.ListOptions{
+		Namespace: namespace,
+	}
+
+	selector, err := newListOption(options).selector()
+	if err != nil {
+		return nil, err
+	}
+
+	if !selector.Empty() {
+		lo.LabelSelector = selector
+	}
+
+	var pods corev1.PodList
+	if err := s.client.List(ctx, &pods, lo); err != nil {
+		return nil, err
+	}
+
+	return &pods, nil
+}
+
+func (s *store) GetWorkloadEntry(ctx context.Context, name, namespace string) (*api.WorkloadEntry, error) {
+	ctx, span := trace.NewSpan(ctx, "Store.GetWorkloadEntry")
+	defer span.End()
+
+	key := client.ObjectKey{
+		Name:      name,
+		Namespace: namespace,
+	}
+
+	var entry api.WorkloadEntry
+	if err := s.reader.Get(ctx, key, &entry); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("failed to get workload entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// ListWorkloadEntriesByNamespace lists the workload entries whose spec.labels match the options.
+func (s *store) ListWorkloadEntriesByNamespace(ctx context.Context, namespace string, options ...ListOption) (*api.WorkloadEntryList, error) {
+	ctx, span := trace.NewSpan(ctx, "Store.ListWorkloadEntriesByNamespace")
+	defer span.End()
+
+	selector, err := newListOption(options).selector()
+	if err != nil {
+		return nil, err
+	}
+
+	var list api.WorkloadEntryList
+	if err := s.client.List(ctx, &list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	// NOTE: filter entries here since the API server only selects objects by their metadata.labels.
+	entries := make([]api.WorkloadEntry, 0, len(list.Items))
+	for _, e := range list.Items {
+		if selector.Matches(labels.Set(e.Spec.Labels)) {
+			entries = append(entries, e)
+		}
+	}
+	list.Items = entries
+
+	return &list, nil
+}
+
+// newUnstructuredList returns the list of the kind, whose kind must end with `List` for the informer cache to find the informer of the items.
+func newUnstructuredList(kind string) *unstructured.UnstructuredList {
+	return &unstructured.UnstructuredList{
+		Object: map[string]interface{}{
+			"kind":       kind + "List",
+			"apiVersion": api.GroupVersion.String(),
+		},
+	}
+}
+
+// objectMetaFromObject returns metadata identifying the resource, which are used to refer to it from its envoy configuration.
+func objectMetaFromObject(object map[string]interface{}) metav1.ObjectMeta {
+	u := &unstructured.Unstructured{Object: object}
+
+	return metav1.ObjectMeta{
+		Name:      u.GetName(),
+		Namespace: u.GetNamespace(),
+	}
+}
+
+func extractSpecFromObject(object map[string]interface{}) (map[string]interface{}, error) {
+	spec, ok := object["spec"]
+	if !ok {
+		return nil, fmt.Errorf("spec not found")
+	}
+
+	s, ok := spec.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid spec form")
+	}
+
+	return s, nil
+}
+
+func unmarshalWorkloadSelector(spec map[string]interface{}) (*api.WorkloadSelector, error) {
+	selector, ok := spec["workloadSelector"]
+	if !ok {
+		return nil, errWorkloadSelectorNotFound
+	}
+
+	j, err := json.Marshal(selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse spec.workloadSelector: %w", err)
+	}
+
+	var ws api.WorkloadSelector
+	if err := json.Unmarshal(j, &ws); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal spec.workloadSelector: %w", err)
+	}
+
+	if _, err := ws.AsSelector(); err != nil {
+		return nil, fmt.Errorf("invalid spec.workloadSelector: %w", err)
+	}
+
+	return &ws, nil
+}
+
+func (s *store) unmarshalCluster(object map[string]interface{}) (*api.Cluster, error) {
+	spec, err := extractSpecFromObject(object)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := unmarshalXDSVersion(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	config, configV3, err := s.unmarshalClusterConfig(spec, version)
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := unmarshalWorkloadSelector(spec)
 	if err != nil && !errors.Is(err, errWorkloadSelectorNotFound) {
 		return nil, err
 	}
 
+	exportTo, err := unmarshalExportTo(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.Cluster{
+		ObjectMeta: objectMetaFromObject(object),
+		Spec: api.ClusterSpec{
+			WorkloadSelector: selector,
+			XDSVersion:       version,
+			ExportTo:         exportTo,
+			Config:           config,
+			ConfigV3:         configV3,
+		},
+	}, nil
+}
+
+func (s *store) unmarshalClusterConfig(spec map[string]interface{}, version api.XDSVersion) (*envoyapi.Cluster, *clusterv3.Cluster, error) {
+	config, err := unmarshalEnvoyConfig(spec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal envoy configuration: %w", err)
+	}
+
+	cluster := &envoyapi.Cluster{}
+	clusterV3 := &clusterv3.Cluster{}
+	if err := s.unmarshalVersionedConfig(config, version, cluster, clusterV3); err != nil {
+		return nil, nil, err
+	}
+
+	return cluster, clusterV3, nil
+}
+
+func (s *store) unmarshalListener(object map[string]interface{}) (*api.Listener, error) {
+	spec, err := extractSpecFromObject(object)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := unmarshalXDSVersion(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	config, configV3, err := s.unmarshalListenerConfig(spec, version)
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := unmarshalWorkloadSelector(spec)
+	if err != nil && !errors.Is(err, errWorkloadSelectorNotFound) {
+		return nil, err
+	}
+
+	exportTo, err := unmarshalExportTo(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.Listener{
+		ObjectMeta: objectMetaFromObject(object),
+		Spec: api.ListenerSpec{
+			WorkloadSelector: selector,
+			XDSVersion:       version,
+			ExportTo:         exportTo,
+			Config:           config,
+			ConfigV3:         configV3,
+		},
+	}, nil
+}
+
+func (s *store) unmarshalListenerConfig(spec map[string]interface{}, version api.XDSVersion) (*envoyapi.Listener, *listenerv3.Listener, error) {
+	config, err := unmarshalEnvoyConfig(spec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal envoy configuration: %w", err)
+	}
+
+	listener := &envoyapi.Listener{}
+	listenerV3 := &listenerv3.Listener{}
+	if err := s.unmarshalVersionedConfig(config, version, listener, listenerV3); err != nil {
+		return nil, nil, err
+	}
+
+	return listener, listenerV3, nil
+}
+
+func (s *store) unmarshalRoute(object map[string]interface{}) (*api.Route, error) {
+	spec, err := extractSpecFromObject(object)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := unmarshalXDSVersion(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	config, configV3, err := s.unmarshalRouteConfig(spec, version)
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := unmarshalWorkloadSelector(spec)
+	if err != nil && !errors.Is(err, errWorkloadSelectorNotFound) {
+		return nil, err
+	}
+
+	exportTo, err := unmarshalExportTo(spec)
+	if err != nil {
+		return nil, err
+	}
+
 	return &api.Route{
+		ObjectMeta: objectMetaFromObject(object),
 		Spec: api.RouteSpec{
 			WorkloadSelector: selector,
+			XDSVersion:       version,
+			ExportTo:         exportTo,
 			Config:           config,
+			ConfigV3:         configV3,
 		},
 	}, nil
 }
 
-func (s *store) unmarshalRouteConfig(spec map[string]interface{}) (*envoyapi.RouteConfiguration, error) {
+func (s *store) unmarshalRouteConfig(spec map[string]interface{}, version api.XDSVersion) (*envoyapi.RouteConfiguration, *routev3.RouteConfiguration, error) {
 	config, err := unmarshalEnvoyConfig(spec)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal envoy configuration: %w", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal envoy configuration: %w", err)
 	}
 
 	route := &envoyapi.RouteConfiguration{}
-	if err := s.unmarshaler.Unmarshal(config, proto.MessageV2(route)); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal spec.config: %w", err)
+	routeV3 := &routev3.RouteConfiguration{}
+	if err := s.unmarshalVersionedConfig(config, version, route, routeV3); err != nil {
+		return nil, nil, err
 	}
 
-	return route, nil
+	return route, routeV3, nil
 }
 
 func (s *store) unmarshalEndpoint(object map[string]interface{}) (*api.Endpoint, error) {
@@ -540,7 +1134,12 @@ func (s *store) unmarshalEndpoint(object map[string]interface{}) (*api.Endpoint,
 		return nil, err
 	}
 
-	config, err := s.unmarshalEndpointConfig(spec)
+	version, err := unmarshalXDSVersion(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	config, configV3, err := s.unmarshalEndpointConfig(spec, version)
 	if err != nil {
 		return nil, err
 	}
@@ -550,26 +1149,457 @@ func (s *store) unmarshalEndpoint(object map[string]interface{}) (*api.Endpoint,
 		return nil, err
 	}
 
+	exportTo, err := unmarshalExportTo(spec)
+	if err != nil {
+		return nil, err
+	}
+
 	return &api.Endpoint{
+		ObjectMeta: objectMetaFromObject(object),
 		Spec: api.EndpointSpec{
 			WorkloadSelector: selector,
+			XDSVersion:       version,
+			ExportTo:         exportTo,
 			Config:           config,
+			ConfigV3:         configV3,
 		},
 	}, nil
 }
 
-func (s *store) unmarshalEndpointConfig(spec map[string]interface{}) (*envoyapi.ClusterLoadAssignment, error) {
+func (s *store) unmarshalEndpointConfig(spec map[string]interface{}, version api.XDSVersion) (*envoyapi.ClusterLoadAssignment, *endpointv3.ClusterLoadAssignment, error) {
 	config, err := unmarshalEnvoyConfig(spec)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal envoy configuration: %w", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal envoy configuration: %w", err)
 	}
 
 	endpoint := &envoyapi.ClusterLoadAssignment{}
-	if err := s.unmarshaler.Unmarshal(config, proto.MessageV2(endpoint)); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal spec.config: %w", err)
+	endpointV3 := &endpointv3.ClusterLoadAssignment{}
+	if err := s.unmarshalVersionedConfig(config, version, endpoint, endpointV3); err != nil {
+		return nil, nil, err
+	}
+
+	return endpoint, endpointV3, nil
+}
+
+func (s *store) unmarshalRuntime(object map[string]interface{}) (*api.Runtime, error) {
+	spec, err := extractSpecFromObject(object)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := unmarshalXDSVersion(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	config, configV3, err := s.unmarshalRuntimeConfig(spec, version)
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := unmarshalWorkloadSelector(spec)
+	if err != nil && !errors.Is(err, errWorkloadSelectorNotFound) {
+		return nil, err
+	}
+
+	exportTo, err := unmarshalExportTo(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.Runtime{
+		ObjectMeta: objectMetaFromObject(object),
+		Spec: api.RuntimeSpec{
+			WorkloadSelector: selector,
+			XDSVersion:       version,
+			ExportTo:         exportTo,
+			Config:           config,
+			ConfigV3:         configV3,
+		},
+	}, nil
+}
+
+func (s *store) unmarshalRuntimeConfig(spec map[string]interface{}, version api.XDSVersion) (*discovery.Runtime, *runtimev3.Runtime, error) {
+	config, err := unmarshalEnvoyConfig(spec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal envoy configuration: %w", err)
+	}
+
+	runtime := &discovery.Runtime{}
+	runtimeV3 := &runtimev3.Runtime{}
+	if err := s.unmarshalVersionedConfig(config, version, runtime, runtimeV3); err != nil {
+		return nil, nil, err
+	}
+
+	return runtime, runtimeV3, nil
+}
+
+func (s *store) unmarshalScopedRoute(object map[string]interface{}) (*api.ScopedRoute, error) {
+	spec, err := extractSpecFromObject(object)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := unmarshalXDSVersion(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	config, configV3, err := s.unmarshalScopedRouteConfig(spec, version)
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := unmarshalWorkloadSelector(spec)
+	if err != nil && !errors.Is(err, errWorkloadSelectorNotFound) {
+		return nil, err
+	}
+
+	exportTo, err := unmarshalExportTo(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.ScopedRoute{
+		ObjectMeta: objectMetaFromObject(object),
+		Spec: api.ScopedRouteSpec{
+			WorkloadSelector: selector,
+			XDSVersion:       version,
+			ExportTo:         exportTo,
+			Config:           config,
+			ConfigV3:         configV3,
+		},
+	}, nil
+}
+
+func (s *store) unmarshalScopedRouteConfig(spec map[string]interface{}, version api.XDSVersion) (*envoyapi.ScopedRouteConfiguration, *routev3.ScopedRouteConfiguration, error) {
+	config, err := unmarshalEnvoyConfig(spec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal envoy configuration: %w", err)
+	}
+
+	scopedRoute := &envoyapi.ScopedRouteConfiguration{}
+	scopedRouteV3 := &routev3.ScopedRouteConfiguration{}
+	if err := s.unmarshalVersionedConfig(config, version, scopedRoute, scopedRouteV3); err != nil {
+		return nil, nil, err
+	}
+
+	return scopedRoute, scopedRouteV3, nil
+}
+
+func (s *store) unmarshalVirtualHost(object map[string]interface{}) (*api.VirtualHost, error) {
+	spec, err := extractSpecFromObject(object)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := unmarshalXDSVersion(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	config, configV3, err := s.unmarshalVirtualHostConfig(spec, version)
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := unmarshalWorkloadSelector(spec)
+	if err != nil && !errors.Is(err, errWorkloadSelectorNotFound) {
+		return nil, err
+	}
+
+	exportTo, err := unmarshalExportTo(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	routeConfigurationName, err := unmarshalRouteConfigurationName(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.VirtualHost{
+		ObjectMeta: objectMetaFromObject(object),
+		Spec: api.VirtualHostSpec{
+			WorkloadSelector:       selector,
+			XDSVersion:             version,
+			ExportTo:               exportTo,
+			RouteConfigurationName: routeConfigurationName,
+			Config:                 config,
+			ConfigV3:               configV3,
+		},
+	}, nil
+}
+
+func (s *store) unmarshalVirtualHostConfig(spec map[string]interface{}, version api.XDSVersion) (*route.VirtualHost, *routev3.VirtualHost, error) {
+	config, err := unmarshalEnvoyConfig(spec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal envoy configuration: %w", err)
+	}
+
+	virtualHost := &route.VirtualHost{}
+	virtualHostV3 := &routev3.VirtualHost{}
+	if err := s.unmarshalVersionedConfig(config, version, virtualHost, virtualHostV3); err != nil {
+		return nil, nil, err
+	}
+
+	return virtualHost, virtualHostV3, nil
+}
+
+func unmarshalRouteConfigurationName(spec map[string]interface{}) (string, error) {
+	name, ok := spec["routeConfigurationName"]
+	if !ok {
+		return "", fmt.Errorf("spec.routeConfigurationName not found")
+	}
+
+	n, ok := name.(string)
+	if !ok || n == "" {
+		return "", fmt.Errorf("invalid spec.routeConfigurationName form")
+	}
+
+	return n, nil
+}
+
+// unmarshalSecret injects the certificate of tlsSecret into the configurations if spec.tlsSecretRef is set.
+func (s *store) unmarshalSecret(object map[string]interface{}, tlsSecret *corev1.Secret) (*api.Secret, error) {
+	spec, err := extractSpecFromObject(object)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := unmarshalXDSVersion(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	config, configV3, err := s.unmarshalSecretConfig(spec, version)
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := unmarshalWorkloadSelector(spec)
+	if err != nil && !errors.Is(err, errWorkloadSelectorNotFound) {
+		return nil, err
+	}
+
+	exportTo, err := unmarshalExportTo(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := unmarshalTLSSecretRef(spec)
+	if err != nil && !errors.Is(err, errTLSSecretRefNotFound) {
+		return nil, err
+	}
+
+	if ref != nil {
+		if err := injectTLSCertificate(tlsSecret, config, configV3); err != nil {
+			return nil, err
+		}
+	}
+
+	return &api.Secret{
+		ObjectMeta: objectMetaFromObject(object),
+		Spec: api.SecretSpec{
+			WorkloadSelector: selector,
+			XDSVersion:       version,
+			ExportTo:         exportTo,
+			TLSSecretRef:     ref,
+			Config:           config,
+			ConfigV3:         configV3,
+		},
+	}, nil
+}
+
+func (s *store) unmarshalSecretConfig(spec map[string]interface{}, version api.XDSVersion) (*auth.Secret, *tlsv3.Secret, error) {
+	config, err := unmarshalEnvoyConfig(spec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal envoy configuration: %w", err)
+	}
+
+	secret := &auth.Secret{}
+	secretV3 := &tlsv3.Secret{}
+	if err := s.unmarshalVersionedConfig(config, version, secret, secretV3); err != nil {
+		return nil, nil, err
+	}
+
+	return secret, secretV3, nil
+}
+
+func unmarshalTLSSecretRef(spec map[string]interface{}) (*api.TLSSecretReference, error) {
+	ref, ok := spec["tlsSecretRef"]
+	if !ok {
+		return nil, errTLSSecretRefNotFound
+	}
+
+	j, err := json.Marshal(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse spec.tlsSecretRef: %w", err)
+	}
+
+	var r api.TLSSecretReference
+	if err := json.Unmarshal(j, &r); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal spec.tlsSecretRef: %w", err)
+	}
+
+	return &r, nil
+}
+
+func (s *store) getTLSSecret(ctx context.Context, ref *api.TLSSecretReference, namespace string) (*corev1.Secret, error) {
+	key := client.ObjectKey{
+		Name:      ref.Name,
+		Namespace: namespace,
+	}
+
+	var secret corev1.Secret
+	if err := s.tlsSecretReader.Get(ctx, key, &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("secret referenced by spec.tlsSecretRef not found: %s (it must be labeled with %s)", ref.Name, api.TLSSecretLabel)
+		}
+
+		return nil, fmt.Errorf("failed to get secret referenced by spec.tlsSecretRef: %w", err)
+	}
+
+	// NOTE: rejects unlabeled Secrets even if the reader can read them, so that the Secret is also watched to be re-distributed on updates.
+	if _, ok := secret.GetLabels()[api.TLSSecretLabel]; !ok {
+		return nil, fmt.Errorf("secret referenced by spec.tlsSecretRef is not labeled with %s: %s", api.TLSSecretLabel, ref.Name)
+	}
+
+	return &secret, nil
+}
+
+// injectTLSCertificate overwrites tls_certificate of the given configurations by the certificate and the private key
+// of the referenced `kubernetes.io/tls` Secret.
+func injectTLSCertificate(secret *corev1.Secret, config *auth.Secret, configV3 *tlsv3.Secret) error {
+	if secret.Type != corev1.SecretTypeTLS {
+		return fmt.Errorf("secret referenced by spec.tlsSecretRef must be %s, but %s", corev1.SecretTypeTLS, secret.Type)
+	}
+
+	cert, ok := secret.Data[corev1.TLSCertKey]
+	if !ok {
+		return fmt.Errorf("%s not found in secret referenced by spec.tlsSecretRef", corev1.TLSCertKey)
+	}
+
+	privateKey, ok := secret.Data[corev1.TLSPrivateKeyKey]
+	if !ok {
+		return fmt.Errorf("%s not found in secret referenced by spec.tlsSecretRef", corev1.TLSPrivateKeyKey)
+	}
+
+	config.Type = &auth.Secret_TlsCertificate{
+		TlsCertificate: &auth.TlsCertificate{
+			CertificateChain: &envoycore.DataSource{
+				Specifier: &envoycore.DataSource_InlineBytes{InlineBytes: cert},
+			},
+			PrivateKey: &envoycore.DataSource{
+				Specifier: &envoycore.DataSource_InlineBytes{InlineBytes: privateKey},
+			},
+		},
+	}
+
+	configV3.Type = &tlsv3.Secret_TlsCertificate{
+		TlsCertificate: &tlsv3.TlsCertificate{
+			CertificateChain: &corev3.DataSource{
+				Specifier: &corev3.DataSource_InlineBytes{InlineBytes: cert},
+			},
+			PrivateKey: &corev3.DataSource{
+				Specifier: &corev3.DataSource_InlineBytes{InlineBytes: privateKey},
+			},
+		},
+	}
+
+	return nil
+}
+
+func (s *store) unmarshalVersionedConfig(config []byte, version api.XDSVersion, v2, v3 proto.Message) error {
+	switch version {
+	case api.XDSVersionV2:
+		if err := s.unmarshaler.Unmarshal(config, proto.MessageV2(v2)); err != nil {
+			return fmt.Errorf("failed to unmarshal spec.config: %w", err)
+		}
+
+		if err := convertConfig(v2, v3, api.XDSVersionV3); err != nil {
+			return fmt.Errorf("failed to convert spec.config to v3: %w", err)
+		}
+	case api.XDSVersionV3:
+		if err := s.unmarshaler.Unmarshal(config, proto.MessageV2(v3)); err != nil {
+			return fmt.Errorf("failed to unmarshal spec.config: %w", err)
+		}
+
+		if err := convertConfig(v3, v2, api.XDSVersionV2); err != nil {
+			return fmt.Errorf("failed to convert spec.config to v2: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported spec.xdsVersion: %s", version)
+	}
+
+	return nil
+}
+
+func unmarshalStatus(object map[string]interface{}) (*api.Status, error) {
+	status := &api.Status{}
+
+	st, ok := object["status"]
+	if !ok {
+		return status, nil
+	}
+
+	j, err := json.Marshal(st)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse status: %w", err)
+	}
+
+	if err := json.Unmarshal(j, status); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal status: %w", err)
+	}
+
+	return status, nil
+}
+
+func marshalStatus(status *api.Status) (map[string]interface{}, error) {
+	j, err := json.Marshal(status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal status: %w", err)
+	}
+
+	var st map[string]interface{}
+	if err := json.Unmarshal(j, &st); err != nil {
+		return nil, fmt.Errorf("failed to convert status: %w", err)
+	}
+
+	return st, nil
+}
+
+func unmarshalExportTo(spec map[string]interface{}) ([]string, error) {
+	exportTo, ok := spec["exportTo"]
+	if !ok {
+		return nil, nil
+	}
+
+	j, err := json.Marshal(exportTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse spec.exportTo: %w", err)
+	}
+
+	var namespaces []string
+	if err := json.Unmarshal(j, &namespaces); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal spec.exportTo: %w", err)
+	}
+
+	return namespaces, nil
+}
+
+func unmarshalXDSVersion(spec map[string]interface{}) (api.XDSVersion, error) {
+	version, ok := spec["xdsVersion"]
+	if !ok {
+		return api.XDSVersionV2, nil
+	}
+
+	v, ok := version.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid spec.xdsVersion form")
 	}
 
-	return endpoint, nil
+	return api.XDSVersion(v), nil
 }
 
 func unmarshalEnvoyConfig(spec map[string]interface{}) ([]byte, error) {
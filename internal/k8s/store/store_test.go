@@ -2,19 +2,32 @@ package store_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
 
 	envoyapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
 	core "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/api/v2/endpoint"
 	envoylistener "github.com/envoyproxy/go-control-plane/envoy/api/v2/listener"
 	route "github.com/envoyproxy/go-control-plane/envoy/api/v2/route"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	routerv2 "github.com/envoyproxy/go-control-plane/envoy/config/filter/http/router/v2"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/config/filter/network/http_connection_manager/v2"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	routerv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/router/v3"
+	hcmv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
 	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
 	any "github.com/golang/protobuf/ptypes/any"
 	"github.com/golang/protobuf/ptypes/duration"
+	structpb "github.com/golang/protobuf/ptypes/struct"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/testing/protocmp"
 	corev1 "k8s.io/api/core/v1"
@@ -40,6 +53,11 @@ func TestMain(m *testing.M) {
 
 		k8sClient = cli
 
+		if err := store.IndexFields(context.Background(), cli.(client.FieldIndexer)); err != nil {
+			fmt.Fprintf(os.Stdout, fmt.Sprintf("failed to index fields: %s", err))
+			os.Exit(1)
+		}
+
 		return m.Run()
 	}())
 }
@@ -129,6 +147,43 @@ func TestGetCluster(t *testing.T) {
 				},
 			},
 		},
+		&unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       api.ClusterKind,
+				"apiVersion": api.GroupVersion.String(),
+				"metadata": map[string]interface{}{
+					"name":      "test-cluster-3",
+					"namespace": namespace,
+				},
+				"spec": map[string]interface{}{
+					"xdsVersion": "v3",
+					"config": map[string]interface{}{
+						"name":            "cluster-3",
+						"connect_timeout": "1s",
+						"type":            "LOGICAL_DNS",
+						"load_assignment": map[string]interface{}{
+							"cluster_name": "cluster-3",
+							"endpoints": []map[string]interface{}{
+								{
+									"lb_endpoints": []map[string]interface{}{
+										{
+											"endpoint": map[string]interface{}{
+												"address": map[string]interface{}{
+													"socket_address": map[string]interface{}{
+														"address":    "test-3.test.svc.cluster.local",
+														"port_value": "10000",
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, f := range fixtures {
@@ -146,6 +201,10 @@ func TestGetCluster(t *testing.T) {
 		"should get cluster": {
 			name: "test-cluster-1",
 			expected: &api.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-cluster-1",
+					Namespace: namespace,
+				},
 				Spec: api.ClusterSpec{
 					WorkloadSelector: &api.WorkloadSelector{
 						Labels: map[string]string{
@@ -191,6 +250,10 @@ func TestGetCluster(t *testing.T) {
 		"should get cluster even though workloadSelector is empty": {
 			name: "test-cluster-2",
 			expected: &api.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-cluster-2",
+					Namespace: namespace,
+				},
 				Spec: api.ClusterSpec{
 					Config: &envoyapi.Cluster{
 						Name:           "cluster-2",
@@ -227,6 +290,81 @@ func TestGetCluster(t *testing.T) {
 				},
 			},
 		},
+		"should get v3 cluster as both v2 and v3 configurations": {
+			name: "test-cluster-3",
+			expected: &api.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-cluster-3",
+					Namespace: namespace,
+				},
+				Spec: api.ClusterSpec{
+					Config: &envoyapi.Cluster{
+						Name:           "cluster-3",
+						ConnectTimeout: &duration.Duration{Seconds: 1},
+						ClusterDiscoveryType: &envoyapi.Cluster_Type{
+							Type: envoyapi.Cluster_LOGICAL_DNS,
+						},
+						LoadAssignment: &envoyapi.ClusterLoadAssignment{
+							ClusterName: "cluster-3",
+							Endpoints: []*endpoint.LocalityLbEndpoints{
+								{
+									LbEndpoints: []*endpoint.LbEndpoint{
+										{
+											HostIdentifier: &endpoint.LbEndpoint_Endpoint{
+												Endpoint: &endpoint.Endpoint{
+													Address: &core.Address{
+														Address: &core.Address_SocketAddress{
+															SocketAddress: &core.SocketAddress{
+																Address: "test-3.test.svc.cluster.local",
+																PortSpecifier: &core.SocketAddress_PortValue{
+																	PortValue: 10000,
+																},
+															},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					ConfigV3: &clusterv3.Cluster{
+						Name:           "cluster-3",
+						ConnectTimeout: &duration.Duration{Seconds: 1},
+						ClusterDiscoveryType: &clusterv3.Cluster_Type{
+							Type: clusterv3.Cluster_LOGICAL_DNS,
+						},
+						LoadAssignment: &endpointv3.ClusterLoadAssignment{
+							ClusterName: "cluster-3",
+							Endpoints: []*endpointv3.LocalityLbEndpoints{
+								{
+									LbEndpoints: []*endpointv3.LbEndpoint{
+										{
+											HostIdentifier: &endpointv3.LbEndpoint_Endpoint{
+												Endpoint: &endpointv3.Endpoint{
+													Address: &corev3.Address{
+														Address: &corev3.Address_SocketAddress{
+															SocketAddress: &corev3.SocketAddress{
+																Address: "test-3.test.svc.cluster.local",
+																PortSpecifier: &corev3.SocketAddress_PortValue{
+																	PortValue: 10000,
+																},
+															},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for name, test := range tests {
@@ -346,6 +484,10 @@ func TestListClustersByNamespace(t *testing.T) {
 			expected: &api.ClusterList{
 				Items: []*api.Cluster{
 					&api.Cluster{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "test-cluster-1",
+							Namespace: namespace,
+						},
 						Spec: api.ClusterSpec{
 							WorkloadSelector: &api.WorkloadSelector{
 								Labels: map[string]string{
@@ -388,6 +530,10 @@ func TestListClustersByNamespace(t *testing.T) {
 						},
 					},
 					&api.Cluster{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "test-cluster-2",
+							Namespace: namespace,
+						},
 						Spec: api.ClusterSpec{
 							Config: &envoyapi.Cluster{
 								Name:           "cluster-2",
@@ -450,163 +596,501 @@ func TestListClustersByNamespace(t *testing.T) {
 	}
 }
 
-func TestGetListener(t *testing.T) {
+func TestListClustersByNamespaceIncludesExportedResources(t *testing.T) {
 	t.Parallel()
 
 	ctx := context.Background()
 	namespace := testutils.NewNamespace(t, ctx, k8sClient)
+	exporter := testutils.NewNamespace(t, ctx, k8sClient)
+	other := testutils.NewNamespace(t, ctx, k8sClient)
 
-	fixtures := []*unstructured.Unstructured{
-		&unstructured.Unstructured{
+	newCluster := func(name, namespace, configName string, exportTo []interface{}) *unstructured.Unstructured {
+		spec := map[string]interface{}{
+			"config": map[string]interface{}{
+				"name": configName,
+			},
+		}
+		if exportTo != nil {
+			spec["exportTo"] = exportTo
+		}
+
+		return &unstructured.Unstructured{
 			Object: map[string]interface{}{
-				"kind":       api.ListenerKind,
+				"kind":       api.ClusterKind,
 				"apiVersion": api.GroupVersion.String(),
 				"metadata": map[string]interface{}{
-					"name":      "test-listener-1",
+					"name":      name,
 					"namespace": namespace,
 				},
-				"spec": map[string]interface{}{
-					"workloadSelector": map[string]interface{}{
-						"labels": map[string]interface{}{
-							"label-1": "1",
-							"label-2": "2",
-						},
-					},
-					"config": map[string]interface{}{
-						"address": map[string]interface{}{
-							"socket_address": map[string]interface{}{
-								"protocol":   "TCP",
-								"address":    "0.0.0.0",
-								"port_value": "10000",
-							},
-						},
-						"filter_chains": []map[string]interface{}{
-							{
-								"filters": []map[string]interface{}{
-									{
-										"name": "envoy.http_connection_manager",
-										"typed_config": map[string]interface{}{
-											"@type":       "type.googleapis.com/envoy.config.filter.network.http_connection_manager.v2.HttpConnectionManager",
-											"stat_prefix": "ingress_http",
-											"route_config": map[string]interface{}{
-												"name": "route",
-												"virtual_hosts": []map[string]interface{}{
-													{
-														"name":    "service",
-														"domains": []string{"*"},
-														"routes": []map[string]interface{}{
-															{
-																"match": map[string]interface{}{
-																	"prefix": "/",
-																},
-																"route": map[string]interface{}{
-																	"cluster": "upstream",
-																},
-															},
-														},
-													},
-												},
-											},
-										},
-									},
-								},
-							},
-						},
-					},
-				},
+				"spec": spec,
 			},
-		},
-		&unstructured.Unstructured{
+		}
+	}
+
+	fixtures := []*unstructured.Unstructured{
+		newCluster("test-cluster-1", namespace, "cluster-1", nil),
+		newCluster("test-cluster-2", exporter, "cluster-2", []interface{}{namespace}),
+		newCluster("test-cluster-3", exporter, "cluster-3", []interface{}{other}),
+		newCluster("test-cluster-4", exporter, "cluster-1", []interface{}{other, namespace}),
+		newCluster("test-cluster-5", other, "cluster-5", nil),
+	}
+	for _, f := range fixtures {
+		if err := k8sClient.Create(ctx, f); err != nil {
+			t.Fatalf("failed to create fixture: %s", err)
+		}
+	}
+
+	s := store.New(k8sClient, k8sClient)
+
+	clusters, err := s.ListClustersByNamespace(ctx, namespace)
+	if err != nil {
+		t.Fatalf("failed to list clusters: %s", err)
+	}
+
+	actual := []string{}
+	for _, c := range clusters.Items {
+		actual = append(actual, fmt.Sprintf("%s/%s:%s", c.Namespace, c.Name, c.Spec.Config.Name))
+	}
+
+	// NOTE: the local cluster follows the exported ones so that it takes precedence over test-cluster-4.
+	expected := []string{
+		fmt.Sprintf("%s/test-cluster-2:cluster-2", exporter),
+		fmt.Sprintf("%s/test-cluster-4:cluster-1", exporter),
+		fmt.Sprintf("%s/test-cluster-1:cluster-1", namespace),
+	}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+}
+
+func TestListClustersByNamespaceSkipsInvalidResources(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	namespace := testutils.NewNamespace(t, ctx, k8sClient)
+
+	newCluster := func(name string, config map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
 			Object: map[string]interface{}{
-				"kind":       api.ListenerKind,
+				"kind":       api.ClusterKind,
 				"apiVersion": api.GroupVersion.String(),
 				"metadata": map[string]interface{}{
-					"name":      "test-listener-2",
+					"name":      name,
 					"namespace": namespace,
 				},
 				"spec": map[string]interface{}{
-					"config": map[string]interface{}{
-						"address": map[string]interface{}{
-							"socket_address": map[string]interface{}{
-								"protocol":   "TCP",
-								"address":    "0.0.0.0",
-								"port_value": "10000",
-							},
-						},
-						"filter_chains": []map[string]interface{}{
-							{
-								"filters": []map[string]interface{}{
-									{
-										"name": "envoy.http_connection_manager",
-										"typed_config": map[string]interface{}{
-											"@type":       "type.googleapis.com/envoy.config.filter.network.http_connection_manager.v2.HttpConnectionManager",
-											"stat_prefix": "ingress_http",
-											"route_config": map[string]interface{}{
-												"name": "route",
-												"virtual_hosts": []map[string]interface{}{
-													{
-														"name":    "service",
-														"domains": []string{"*"},
-														"routes": []map[string]interface{}{
-															{
-																"match": map[string]interface{}{
-																	"prefix": "/",
-																},
-																"route": map[string]interface{}{
-																	"cluster": "upstream",
-																},
-															},
-														},
-													},
-												},
-											},
-										},
-									},
-								},
-							},
-						},
-					},
+					"config": config,
 				},
 			},
-		},
+		}
 	}
 
-	for _, f := range fixtures {
+	for _, f := range []*unstructured.Unstructured{
+		newCluster("test-cluster-1", map[string]interface{}{"name": "cluster-1"}),
+		newCluster("test-cluster-2", map[string]interface{}{"name": "cluster-2"}),
+	} {
 		if err := k8sClient.Create(ctx, f); err != nil {
 			t.Fatalf("failed to create fixture: %s", err)
 		}
 	}
 
-	s := store.New(k8sClient, k8sClient)
+	reported := []string{}
+	s := store.New(k8sClient, k8sClient, store.WithInvalidResourceHandler(func(_ context.Context, object *unstructured.Unstructured, _ *store.InvalidResourceError) {
+		reported = append(reported, object.GetName())
+	}))
 
-	cm, err := proto.Marshal(&hcm.HttpConnectionManager{
-		StatPrefix: "ingress_http",
-		RouteSpecifier: &hcm.HttpConnectionManager_RouteConfig{
-			RouteConfig: &envoyapi.RouteConfiguration{
-				Name: "route",
-				VirtualHosts: []*route.VirtualHost{
-					{
-						Name:    "service",
-						Domains: []string{"*"},
-						Routes: []*route.Route{
-							{
-								Match: &route.RouteMatch{
-									PathSpecifier: &route.RouteMatch_Prefix{
-										Prefix: "/",
-									},
-								},
-								Action: &route.Route_Route{
-									Route: &route.RouteAction{
-										ClusterSpecifier: &route.RouteAction_Cluster{
-											Cluster: "upstream",
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-			},
+	// NOTE: lists valid resources at first so that the store remembers them.
+	if _, err := s.ListClustersByNamespace(ctx, namespace); err != nil {
+		t.Fatalf("failed to list clusters: %s", err)
+	}
+
+	broken := &unstructured.Unstructured{}
+	broken.SetGroupVersionKind(api.GroupVersion.WithKind(api.ClusterKind))
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-cluster-2", Namespace: namespace}, broken); err != nil {
+		t.Fatalf("failed to get fixture: %s", err)
+	}
+	if err := unstructured.SetNestedField(broken.Object, "invalid", "spec", "config", "connect_timeout"); err != nil {
+		t.Fatalf("failed to set field: %s", err)
+	}
+	if err := k8sClient.Update(ctx, broken); err != nil {
+		t.Fatalf("failed to update fixture: %s", err)
+	}
+
+	if err := k8sClient.Create(ctx, newCluster("test-cluster-3", map[string]interface{}{"name": "cluster-3", "connect_timeout": "invalid"})); err != nil {
+		t.Fatalf("failed to create fixture: %s", err)
+	}
+
+	clusters, err := s.ListClustersByNamespace(ctx, namespace)
+	if err != nil {
+		t.Fatalf("failed to list clusters: %s", err)
+	}
+
+	actual := []string{}
+	for _, c := range clusters.Items {
+		actual = append(actual, c.Spec.Config.Name)
+	}
+
+	expected := []string{"cluster-1", "cluster-2"}
+	if diff := cmp.Diff(expected, actual); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+
+	expectedReported := []string{"test-cluster-2", "test-cluster-3"}
+	if diff := cmp.Diff(expectedReported, reported); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+}
+
+func TestForget(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	namespace := testutils.NewNamespace(t, ctx, k8sClient)
+
+	fixture := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       api.ClusterKind,
+			"apiVersion": api.GroupVersion.String(),
+			"metadata": map[string]interface{}{
+				"name":      "test-cluster-1",
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"config": map[string]interface{}{
+					"name": "cluster-1",
+				},
+			},
+		},
+	}
+	if err := k8sClient.Create(ctx, fixture); err != nil {
+		t.Fatalf("failed to create fixture: %s", err)
+	}
+
+	forgotten := []string{}
+	s := store.New(k8sClient, k8sClient, store.WithForgetHandler(func(kind, name, namespace string) {
+		forgotten = append(forgotten, fmt.Sprintf("%s %s/%s", kind, namespace, name))
+	}))
+
+	// NOTE: lists the valid resource at first so that the store remembers it.
+	if _, err := s.ListClustersByNamespace(ctx, namespace); err != nil {
+		t.Fatalf("failed to list clusters: %s", err)
+	}
+
+	broken := &unstructured.Unstructured{}
+	broken.SetGroupVersionKind(api.GroupVersion.WithKind(api.ClusterKind))
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-cluster-1", Namespace: namespace}, broken); err != nil {
+		t.Fatalf("failed to get fixture: %s", err)
+	}
+	if err := unstructured.SetNestedField(broken.Object, "invalid", "spec", "config", "connect_timeout"); err != nil {
+		t.Fatalf("failed to set field: %s", err)
+	}
+	if err := k8sClient.Update(ctx, broken); err != nil {
+		t.Fatalf("failed to update fixture: %s", err)
+	}
+
+	s.Forget(api.ClusterKind, "test-cluster-1", namespace)
+
+	clusters, err := s.ListClustersByNamespace(ctx, namespace)
+	if err != nil {
+		t.Fatalf("failed to list clusters: %s", err)
+	}
+
+	// NOTE: the last valid resource has been forgotten, thus the broken one is skipped without being replaced.
+	if diff := cmp.Diff(0, len(clusters.Items)); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+
+	expected := []string{fmt.Sprintf("%s %s/test-cluster-1", api.ClusterKind, namespace)}
+	if diff := cmp.Diff(expected, forgotten); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+}
+
+func TestForgetDecodedResources(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	namespace := testutils.NewNamespace(t, ctx, k8sClient)
+
+	fixture := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       api.ClusterKind,
+			"apiVersion": api.GroupVersion.String(),
+			"metadata": map[string]interface{}{
+				"name":      "test-cluster-1",
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"config": map[string]interface{}{
+					"name": "cluster-1",
+				},
+			},
+		},
+	}
+	if err := k8sClient.Create(ctx, fixture); err != nil {
+		t.Fatalf("failed to create fixture: %s", err)
+	}
+
+	s := store.New(k8sClient, k8sClient)
+
+	first, err := s.GetCluster(ctx, "test-cluster-1", namespace)
+	if err != nil {
+		t.Fatalf("failed to get cluster: %s", err)
+	}
+
+	s.Forget(api.ClusterKind, "test-cluster-1", namespace)
+
+	second, err := s.GetCluster(ctx, "test-cluster-1", namespace)
+	if err != nil {
+		t.Fatalf("failed to get cluster: %s", err)
+	}
+
+	if second == first {
+		t.Error("forgotten cluster has not been decoded again")
+	}
+}
+
+func TestListClustersByNamespaceDecodesChangedResourcesOnly(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	namespace := testutils.NewNamespace(t, ctx, k8sClient)
+
+	fixture := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       api.ClusterKind,
+			"apiVersion": api.GroupVersion.String(),
+			"metadata": map[string]interface{}{
+				"name":      "test-cluster-1",
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"config": map[string]interface{}{
+					"name": "cluster-1",
+				},
+			},
+		},
+	}
+	if err := k8sClient.Create(ctx, fixture); err != nil {
+		t.Fatalf("failed to create fixture: %s", err)
+	}
+
+	s := store.New(k8sClient, k8sClient)
+
+	list := func() *api.Cluster {
+		t.Helper()
+
+		clusters, err := s.ListClustersByNamespace(ctx, namespace)
+		if err != nil {
+			t.Fatalf("failed to list clusters: %s", err)
+		}
+
+		for _, c := range clusters.Items {
+			if c.Namespace == namespace && c.Name == "test-cluster-1" {
+				return c
+			}
+		}
+
+		t.Fatal("cluster not found")
+		return nil
+	}
+
+	first := list()
+
+	if second := list(); second != first {
+		t.Error("unchanged cluster has been decoded again")
+	}
+
+	err := s.UpdateStatus(ctx, api.ClusterKind, "test-cluster-1", namespace, func(status *api.Status) {
+		status.Parsed = true
+	})
+	if err != nil {
+		t.Fatalf("failed to update status: %s", err)
+	}
+
+	if third := list(); third != first {
+		t.Error("cluster has been decoded again by the update of its status")
+	}
+
+	updated := &unstructured.Unstructured{}
+	updated.SetGroupVersionKind(api.GroupVersion.WithKind(api.ClusterKind))
+	if err := k8sClient.Get(ctx, client.ObjectKey{Name: "test-cluster-1", Namespace: namespace}, updated); err != nil {
+		t.Fatalf("failed to get fixture: %s", err)
+	}
+	if err := unstructured.SetNestedField(updated.Object, "cluster-2", "spec", "config", "name"); err != nil {
+		t.Fatalf("failed to set field: %s", err)
+	}
+	if err := k8sClient.Update(ctx, updated); err != nil {
+		t.Fatalf("failed to update fixture: %s", err)
+	}
+
+	fourth := list()
+	if fourth == first {
+		t.Error("updated cluster has not been decoded again")
+	}
+
+	if diff := cmp.Diff("cluster-2", fourth.Spec.Config.Name); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+}
+
+func TestGetListener(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	namespace := testutils.NewNamespace(t, ctx, k8sClient)
+
+	fixtures := []*unstructured.Unstructured{
+		&unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       api.ListenerKind,
+				"apiVersion": api.GroupVersion.String(),
+				"metadata": map[string]interface{}{
+					"name":      "test-listener-1",
+					"namespace": namespace,
+				},
+				"spec": map[string]interface{}{
+					"workloadSelector": map[string]interface{}{
+						"labels": map[string]interface{}{
+							"label-1": "1",
+							"label-2": "2",
+						},
+					},
+					"config": map[string]interface{}{
+						"address": map[string]interface{}{
+							"socket_address": map[string]interface{}{
+								"protocol":   "TCP",
+								"address":    "0.0.0.0",
+								"port_value": "10000",
+							},
+						},
+						"filter_chains": []map[string]interface{}{
+							{
+								"filters": []map[string]interface{}{
+									{
+										"name": "envoy.http_connection_manager",
+										"typed_config": map[string]interface{}{
+											"@type":       "type.googleapis.com/envoy.config.filter.network.http_connection_manager.v2.HttpConnectionManager",
+											"stat_prefix": "ingress_http",
+											"route_config": map[string]interface{}{
+												"name": "route",
+												"virtual_hosts": []map[string]interface{}{
+													{
+														"name":    "service",
+														"domains": []string{"*"},
+														"routes": []map[string]interface{}{
+															{
+																"match": map[string]interface{}{
+																	"prefix": "/",
+																},
+																"route": map[string]interface{}{
+																	"cluster": "upstream",
+																},
+															},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		&unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       api.ListenerKind,
+				"apiVersion": api.GroupVersion.String(),
+				"metadata": map[string]interface{}{
+					"name":      "test-listener-2",
+					"namespace": namespace,
+				},
+				"spec": map[string]interface{}{
+					"config": map[string]interface{}{
+						"address": map[string]interface{}{
+							"socket_address": map[string]interface{}{
+								"protocol":   "TCP",
+								"address":    "0.0.0.0",
+								"port_value": "10000",
+							},
+						},
+						"filter_chains": []map[string]interface{}{
+							{
+								"filters": []map[string]interface{}{
+									{
+										"name": "envoy.http_connection_manager",
+										"typed_config": map[string]interface{}{
+											"@type":       "type.googleapis.com/envoy.config.filter.network.http_connection_manager.v2.HttpConnectionManager",
+											"stat_prefix": "ingress_http",
+											"route_config": map[string]interface{}{
+												"name": "route",
+												"virtual_hosts": []map[string]interface{}{
+													{
+														"name":    "service",
+														"domains": []string{"*"},
+														"routes": []map[string]interface{}{
+															{
+																"match": map[string]interface{}{
+																	"prefix": "/",
+																},
+																"route": map[string]interface{}{
+																	"cluster": "upstream",
+																},
+															},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, f := range fixtures {
+		if err := k8sClient.Create(ctx, f); err != nil {
+			t.Fatalf("failed to create fixture: %s", err)
+		}
+	}
+
+	s := store.New(k8sClient, k8sClient)
+
+	cm, err := proto.Marshal(&hcm.HttpConnectionManager{
+		StatPrefix: "ingress_http",
+		RouteSpecifier: &hcm.HttpConnectionManager_RouteConfig{
+			RouteConfig: &envoyapi.RouteConfiguration{
+				Name: "route",
+				VirtualHosts: []*route.VirtualHost{
+					{
+						Name:    "service",
+						Domains: []string{"*"},
+						Routes: []*route.Route{
+							{
+								Match: &route.RouteMatch{
+									PathSpecifier: &route.RouteMatch_Prefix{
+										Prefix: "/",
+									},
+								},
+								Action: &route.Route_Route{
+									Route: &route.RouteAction{
+										ClusterSpecifier: &route.RouteAction_Cluster{
+											Cluster: "upstream",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
 		},
 	})
 	if err != nil {
@@ -620,6 +1104,10 @@ func TestGetListener(t *testing.T) {
 		"should get listener": {
 			name: "test-listener-1",
 			expected: &api.Listener{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-listener-1",
+					Namespace: namespace,
+				},
 				Spec: api.ListenerSpec{
 					WorkloadSelector: &api.WorkloadSelector{
 						Labels: map[string]string{
@@ -661,6 +1149,10 @@ func TestGetListener(t *testing.T) {
 		"should get cluster even though workloadSelector is empty": {
 			name: "test-listener-2",
 			expected: &api.Listener{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-listener-2",
+					Namespace: namespace,
+				},
 				Spec: api.ListenerSpec{
 					Config: &envoyapi.Listener{
 						Address: &core.Address{
@@ -705,9 +1197,216 @@ func TestGetListener(t *testing.T) {
 				t.Fatalf("error: %s", err)
 			}
 
-			diffListener(t, test.expected, actual)
+			diffListener(t, test.expected, actual)
+		})
+	}
+}
+
+func TestGetListenerConvertsTypedConfigs(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	namespace := testutils.NewNamespace(t, ctx, k8sClient)
+
+	newListener := func(name, version, hcmType, routerType string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       api.ListenerKind,
+				"apiVersion": api.GroupVersion.String(),
+				"metadata": map[string]interface{}{
+					"name":      name,
+					"namespace": namespace,
+				},
+				"spec": map[string]interface{}{
+					"xdsVersion": version,
+					"config": map[string]interface{}{
+						"name": "listener-1",
+						"filter_chains": []map[string]interface{}{
+							{
+								"filters": []map[string]interface{}{
+									{
+										"name": "envoy.http_connection_manager",
+										"typed_config": map[string]interface{}{
+											"@type":       hcmType,
+											"stat_prefix": "ingress_http",
+											"rds": map[string]interface{}{
+												"route_config_name": "route-1",
+												"config_source": map[string]interface{}{
+													"ads": map[string]interface{}{},
+												},
+											},
+											"http_filters": []map[string]interface{}{
+												{
+													"name": "envoy.router",
+													"typed_config": map[string]interface{}{
+														"@type": routerType,
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	fixtures := []*unstructured.Unstructured{
+		newListener(
+			"test-listener-1",
+			"v2",
+			"type.googleapis.com/envoy.config.filter.network.http_connection_manager.v2.HttpConnectionManager",
+			"type.googleapis.com/envoy.config.filter.http.router.v2.Router",
+		),
+		newListener(
+			"test-listener-2",
+			"v3",
+			"type.googleapis.com/envoy.extensions.filters.network.http_connection_manager.v3.HttpConnectionManager",
+			"type.googleapis.com/envoy.extensions.filters.http.router.v3.Router",
+		),
+		newListener(
+			"test-listener-3",
+			"v2",
+			"type.googleapis.com/envoy.config.filter.network.http_connection_manager.v2.HttpConnectionManager",
+			"type.googleapis.com/envoy.config.filter.thrift.router.v2alpha1.Router",
+		),
+	}
+	for _, f := range fixtures {
+		if err := k8sClient.Create(ctx, f); err != nil {
+			t.Fatalf("failed to create fixture: %s", err)
+		}
+	}
+
+	s := store.New(k8sClient, k8sClient)
+
+	marshalAny := func(m proto.Message) *any.Any {
+		t.Helper()
+
+		a, err := ptypes.MarshalAny(m)
+		if err != nil {
+			t.Fatalf("failed to marshal fixture proto: %s", err)
+		}
+
+		return a
+	}
+
+	expectedV2 := &envoyapi.Listener{
+		Name: "listener-1",
+		FilterChains: []*envoylistener.FilterChain{
+			{
+				Filters: []*envoylistener.Filter{
+					{
+						Name: "envoy.http_connection_manager",
+						ConfigType: &envoylistener.Filter_TypedConfig{
+							TypedConfig: marshalAny(&hcm.HttpConnectionManager{
+								StatPrefix: "ingress_http",
+								RouteSpecifier: &hcm.HttpConnectionManager_Rds{
+									Rds: &hcm.Rds{
+										RouteConfigName: "route-1",
+										ConfigSource: &core.ConfigSource{
+											ConfigSourceSpecifier: &core.ConfigSource_Ads{
+												Ads: &core.AggregatedConfigSource{},
+											},
+										},
+									},
+								},
+								HttpFilters: []*hcm.HttpFilter{
+									{
+										Name: "envoy.router",
+										ConfigType: &hcm.HttpFilter_TypedConfig{
+											TypedConfig: marshalAny(&routerv2.Router{}),
+										},
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	expectedV3 := &listenerv3.Listener{
+		Name: "listener-1",
+		FilterChains: []*listenerv3.FilterChain{
+			{
+				Filters: []*listenerv3.Filter{
+					{
+						Name: "envoy.http_connection_manager",
+						ConfigType: &listenerv3.Filter_TypedConfig{
+							TypedConfig: marshalAny(&hcmv3.HttpConnectionManager{
+								StatPrefix: "ingress_http",
+								RouteSpecifier: &hcmv3.HttpConnectionManager_Rds{
+									Rds: &hcmv3.Rds{
+										RouteConfigName: "route-1",
+										ConfigSource: &corev3.ConfigSource{
+											ConfigSourceSpecifier: &corev3.ConfigSource_Ads{
+												Ads: &corev3.AggregatedConfigSource{},
+											},
+											ResourceApiVersion: corev3.ApiVersion_V3,
+										},
+									},
+								},
+								HttpFilters: []*hcmv3.HttpFilter{
+									{
+										Name: "envoy.router",
+										ConfigType: &hcmv3.HttpFilter_TypedConfig{
+											TypedConfig: marshalAny(&routerv3.Router{}),
+										},
+									},
+								},
+							}),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		name string
+	}{
+		"should convert typed configs and config sources of v2 listener to v3": {
+			name: "test-listener-1",
+		},
+		"should convert typed configs of v3 listener to v2": {
+			name: "test-listener-2",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := s.GetListener(ctx, test.name, namespace)
+			if err != nil {
+				t.Fatalf("error: %s", err)
+			}
+
+			if diff := cmp.Diff(expectedV2, actual.Spec.Config, protocmp.Transform()); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+
+			if diff := cmp.Diff(expectedV3, actual.Spec.ConfigV3, protocmp.Transform()); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
 		})
 	}
+
+	t.Run("should reject v2 listener whose typed config has no v3 counterpart", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := s.GetListener(ctx, "test-listener-3", namespace)
+
+		var ierr *store.InvalidResourceError
+		if !errors.As(err, &ierr) {
+			t.Errorf("expected InvalidResourceError, but got %v", err)
+		}
+	})
 }
 
 func TestListListenersByNamespace(t *testing.T) {
@@ -880,6 +1579,10 @@ func TestListListenersByNamespace(t *testing.T) {
 			&api.ListenerList{
 				Items: []*api.Listener{
 					&api.Listener{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "test-listener-1",
+							Namespace: namespace,
+						},
 						Spec: api.ListenerSpec{
 							WorkloadSelector: &api.WorkloadSelector{
 								Labels: map[string]string{
@@ -918,6 +1621,10 @@ func TestListListenersByNamespace(t *testing.T) {
 						},
 					},
 					&api.Listener{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "test-listener-2",
+							Namespace: namespace,
+						},
 						Spec: api.ListenerSpec{
 							Config: &envoyapi.Listener{
 								Address: &core.Address{
@@ -1039,6 +1746,10 @@ func TestGetRoute(t *testing.T) {
 		"should get route": {
 			name: "test-route-1",
 			expected: &api.Route{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-route-1",
+					Namespace: namespace,
+				},
 				Spec: api.RouteSpec{
 					WorkloadSelector: &api.WorkloadSelector{
 						Labels: map[string]string{
@@ -1080,62 +1791,388 @@ func TestGetRoute(t *testing.T) {
 	for name, test := range tests {
 		test := test
 		t.Run(name, func(t *testing.T) {
-			actual, err := s.GetRoute(ctx, test.name, namespace)
+			actual, err := s.GetRoute(ctx, test.name, namespace)
+			if err != nil {
+				t.Fatalf("error: %s", err)
+			}
+
+			diffRoute(t, test.expected, actual)
+		})
+	}
+}
+
+func TestListRoutesByNamespace(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	namespace := testutils.NewNamespace(t, ctx, k8sClient)
+
+	fixtures := []*unstructured.Unstructured{
+		&unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       api.RouteKind,
+				"apiVersion": api.GroupVersion.String(),
+				"metadata": map[string]interface{}{
+					"name":      "test-route-1",
+					"namespace": namespace,
+				},
+				"spec": map[string]interface{}{
+					"workloadSelector": map[string]interface{}{
+						"labels": map[string]interface{}{
+							"label-1": "1",
+							"label-2": "2",
+						},
+					},
+					"config": map[string]interface{}{
+						"name": "route",
+						"virtual_hosts": []map[string]interface{}{
+							{
+								"name":    "service",
+								"domains": []string{"*"},
+								"routes": []map[string]interface{}{
+									{
+
+										"name": "cluster-1",
+										"route": map[string]interface{}{
+											"cluster": "cluster-1",
+										},
+										"match": map[string]interface{}{
+											"prefix": "/",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, f := range fixtures {
+		if err := k8sClient.Create(ctx, f); err != nil {
+			t.Fatalf("failed to create fixture: %s", err)
+		}
+	}
+
+	s := store.New(k8sClient, k8sClient)
+
+	tests := map[string]struct {
+		expected *api.RouteList
+	}{
+		"should list clusters": {
+			expected: &api.RouteList{
+				Items: []*api.Route{
+					&api.Route{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "test-route-1",
+							Namespace: namespace,
+						},
+						Spec: api.RouteSpec{
+							WorkloadSelector: &api.WorkloadSelector{
+								Labels: map[string]string{
+									"label-1": "1",
+									"label-2": "2",
+								},
+							},
+							Config: &envoyapi.RouteConfiguration{
+								Name: "route",
+								VirtualHosts: []*route.VirtualHost{
+									&route.VirtualHost{
+										Name:    "service",
+										Domains: []string{"*"},
+										Routes: []*route.Route{
+											{
+												Name: "cluster-1",
+												Match: &route.RouteMatch{
+													PathSpecifier: &route.RouteMatch_Prefix{
+														Prefix: "/",
+													},
+												},
+												Action: &route.Route_Route{
+													Route: &route.RouteAction{
+														ClusterSpecifier: &route.RouteAction_Cluster{
+															Cluster: "cluster-1",
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := s.ListRoutesByNamespace(ctx, namespace)
+			if err != nil {
+				t.Fatalf("failed %s", err)
+			}
+
+			if len(test.expected.Items) != len(actual.Items) {
+				t.Fatal("Different number of Items found")
+			}
+
+			for i, a := range actual.Items {
+				diffRoute(t, test.expected.Items[i], a)
+			}
+		})
+	}
+}
+
+func TestGetRuntime(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	namespace := testutils.NewNamespace(t, ctx, k8sClient)
+
+	fixtures := []*unstructured.Unstructured{
+		&unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       api.RuntimeKind,
+				"apiVersion": api.GroupVersion.String(),
+				"metadata": map[string]interface{}{
+					"name":      "test-runtime-1",
+					"namespace": namespace,
+				},
+				"spec": map[string]interface{}{
+					"workloadSelector": map[string]interface{}{
+						"labels": map[string]interface{}{
+							"label-1": "1",
+						},
+					},
+					"config": map[string]interface{}{
+						"name": "runtime-1",
+						"layer": map[string]interface{}{
+							"features.enabled":    true,
+							"features.percentage": 10,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, f := range fixtures {
+		if err := k8sClient.Create(ctx, f); err != nil {
+			t.Fatalf("failed to create fixture: %s", err)
+		}
+	}
+
+	s := store.New(k8sClient, k8sClient)
+
+	tests := map[string]struct {
+		expected *api.Runtime
+		name     string
+	}{
+		"should get runtime": {
+			name: "test-runtime-1",
+			expected: &api.Runtime{
+				Spec: api.RuntimeSpec{
+					WorkloadSelector: &api.WorkloadSelector{
+						Labels: map[string]string{
+							"label-1": "1",
+						},
+					},
+					Config: &discovery.Runtime{
+						Name: "runtime-1",
+						Layer: &structpb.Struct{
+							Fields: map[string]*structpb.Value{
+								"features.enabled": &structpb.Value{
+									Kind: &structpb.Value_BoolValue{BoolValue: true},
+								},
+								"features.percentage": &structpb.Value{
+									Kind: &structpb.Value_NumberValue{NumberValue: 10},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := s.GetRuntime(ctx, test.name, namespace)
+			if err != nil {
+				t.Fatalf("error: %s", err)
+			}
+
+			if diff := cmp.Diff(test.expected.Spec.WorkloadSelector, actual.Spec.WorkloadSelector); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+
+			if diff := cmp.Diff(test.expected.Spec.Config, actual.Spec.Config, protocmp.Transform()); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetVirtualHost(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	namespace := testutils.NewNamespace(t, ctx, k8sClient)
+
+	fixtures := []*unstructured.Unstructured{
+		&unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       api.VirtualHostKind,
+				"apiVersion": api.GroupVersion.String(),
+				"metadata": map[string]interface{}{
+					"name":      "test-virtual-host-1",
+					"namespace": namespace,
+				},
+				"spec": map[string]interface{}{
+					"xdsVersion":             "v3",
+					"routeConfigurationName": "route-1",
+					"config": map[string]interface{}{
+						"name":    "virtual-host-1",
+						"domains": []interface{}{"*.example.com"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, f := range fixtures {
+		if err := k8sClient.Create(ctx, f); err != nil {
+			t.Fatalf("failed to create fixture: %s", err)
+		}
+	}
+
+	s := store.New(k8sClient, k8sClient)
+
+	tests := map[string]struct {
+		expected *api.VirtualHost
+		name     string
+	}{
+		"should get virtual host": {
+			name: "test-virtual-host-1",
+			expected: &api.VirtualHost{
+				Spec: api.VirtualHostSpec{
+					XDSVersion:             api.XDSVersionV3,
+					RouteConfigurationName: "route-1",
+					Config: &route.VirtualHost{
+						Name:    "virtual-host-1",
+						Domains: []string{"*.example.com"},
+					},
+					ConfigV3: &routev3.VirtualHost{
+						Name:    "virtual-host-1",
+						Domains: []string{"*.example.com"},
+					},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := s.GetVirtualHost(ctx, test.name, namespace)
 			if err != nil {
 				t.Fatalf("error: %s", err)
 			}
 
-			diffRoute(t, test.expected, actual)
+			if diff := cmp.Diff(test.expected.Spec.RouteConfigurationName, actual.Spec.RouteConfigurationName); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+
+			if diff := cmp.Diff(test.expected.Spec.Config, actual.Spec.Config, protocmp.Transform()); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+
+			if diff := cmp.Diff(test.expected.Spec.ConfigV3, actual.Spec.ConfigV3, protocmp.Transform()); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
 		})
 	}
 }
 
-func TestListRoutesByNamespace(t *testing.T) {
+func TestGetSecret(t *testing.T) {
 	t.Parallel()
 
 	ctx := context.Background()
 	namespace := testutils.NewNamespace(t, ctx, k8sClient)
 
+	tlsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "tls-secret",
+			Namespace: namespace,
+			Labels: map[string]string{
+				api.TLSSecretLabel: "",
+			},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       []byte("certificate"),
+			corev1.TLSPrivateKeyKey: []byte("private key"),
+		},
+	}
+	if err := k8sClient.Create(ctx, tlsSecret); err != nil {
+		t.Fatalf("failed to create fixture: %s", err)
+	}
+
 	fixtures := []*unstructured.Unstructured{
 		&unstructured.Unstructured{
 			Object: map[string]interface{}{
-				"kind":       api.RouteKind,
+				"kind":       api.SecretKind,
 				"apiVersion": api.GroupVersion.String(),
 				"metadata": map[string]interface{}{
-					"name":      "test-route-1",
+					"name":      "test-secret-1",
 					"namespace": namespace,
 				},
 				"spec": map[string]interface{}{
 					"workloadSelector": map[string]interface{}{
 						"labels": map[string]interface{}{
 							"label-1": "1",
-							"label-2": "2",
 						},
 					},
 					"config": map[string]interface{}{
-						"name": "route",
-						"virtual_hosts": []map[string]interface{}{
-							{
-								"name":    "service",
-								"domains": []string{"*"},
-								"routes": []map[string]interface{}{
-									{
-
-										"name": "cluster-1",
-										"route": map[string]interface{}{
-											"cluster": "cluster-1",
-										},
-										"match": map[string]interface{}{
-											"prefix": "/",
-										},
-									},
-								},
+						"name": "secret-1",
+						"validation_context": map[string]interface{}{
+							"trusted_ca": map[string]interface{}{
+								"filename": "/etc/ssl/certs/ca-certificates.crt",
 							},
 						},
 					},
 				},
 			},
 		},
+		&unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"kind":       api.SecretKind,
+				"apiVersion": api.GroupVersion.String(),
+				"metadata": map[string]interface{}{
+					"name":      "test-secret-2",
+					"namespace": namespace,
+				},
+				"spec": map[string]interface{}{
+					"tlsSecretRef": map[string]interface{}{
+						"name": "tls-secret",
+					},
+					"config": map[string]interface{}{
+						"name": "secret-2",
+					},
+				},
+			},
+		},
 	}
 
 	for _, f := range fixtures {
@@ -1147,42 +2184,60 @@ func TestListRoutesByNamespace(t *testing.T) {
 	s := store.New(k8sClient, k8sClient)
 
 	tests := map[string]struct {
-		expected *api.RouteList
+		expected *api.Secret
+		name     string
 	}{
-		"should list clusters": {
-			expected: &api.RouteList{
-				Items: []*api.Route{
-					&api.Route{
-						Spec: api.RouteSpec{
-							WorkloadSelector: &api.WorkloadSelector{
-								Labels: map[string]string{
-									"label-1": "1",
-									"label-2": "2",
+		"should get secret": {
+			name: "test-secret-1",
+			expected: &api.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-secret-1",
+					Namespace: namespace,
+				},
+				Spec: api.SecretSpec{
+					WorkloadSelector: &api.WorkloadSelector{
+						Labels: map[string]string{
+							"label-1": "1",
+						},
+					},
+					Config: &auth.Secret{
+						Name: "secret-1",
+						Type: &auth.Secret_ValidationContext{
+							ValidationContext: &auth.CertificateValidationContext{
+								TrustedCa: &core.DataSource{
+									Specifier: &core.DataSource_Filename{
+										Filename: "/etc/ssl/certs/ca-certificates.crt",
+									},
 								},
 							},
-							Config: &envoyapi.RouteConfiguration{
-								Name: "route",
-								VirtualHosts: []*route.VirtualHost{
-									&route.VirtualHost{
-										Name:    "service",
-										Domains: []string{"*"},
-										Routes: []*route.Route{
-											{
-												Name: "cluster-1",
-												Match: &route.RouteMatch{
-													PathSpecifier: &route.RouteMatch_Prefix{
-														Prefix: "/",
-													},
-												},
-												Action: &route.Route_Route{
-													Route: &route.RouteAction{
-														ClusterSpecifier: &route.RouteAction_Cluster{
-															Cluster: "cluster-1",
-														},
-													},
-												},
-											},
-										},
+						},
+					},
+				},
+			},
+		},
+		"should get secret with the certificate of the referenced tls secret": {
+			name: "test-secret-2",
+			expected: &api.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-secret-2",
+					Namespace: namespace,
+				},
+				Spec: api.SecretSpec{
+					TLSSecretRef: &api.TLSSecretReference{
+						Name: "tls-secret",
+					},
+					Config: &auth.Secret{
+						Name: "secret-2",
+						Type: &auth.Secret_TlsCertificate{
+							TlsCertificate: &auth.TlsCertificate{
+								CertificateChain: &core.DataSource{
+									Specifier: &core.DataSource_InlineBytes{
+										InlineBytes: []byte("certificate"),
+									},
+								},
+								PrivateKey: &core.DataSource{
+									Specifier: &core.DataSource_InlineBytes{
+										InlineBytes: []byte("private key"),
 									},
 								},
 							},
@@ -1198,17 +2253,103 @@ func TestListRoutesByNamespace(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			actual, err := s.ListRoutesByNamespace(ctx, namespace)
+			actual, err := s.GetSecret(ctx, test.name, namespace)
 			if err != nil {
-				t.Fatalf("failed %s", err)
+				t.Fatalf("error: %s", err)
 			}
 
-			if len(test.expected.Items) != len(actual.Items) {
-				t.Fatal("Different number of Items found")
+			diffSecret(t, test.expected, actual)
+		})
+	}
+}
+
+func TestUpdateStatus(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	namespace := testutils.NewNamespace(t, ctx, k8sClient)
+
+	fixture := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"kind":       api.ClusterKind,
+			"apiVersion": api.GroupVersion.String(),
+			"metadata": map[string]interface{}{
+				"name":      "test-cluster-1",
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"config": map[string]interface{}{
+					"name": "cluster-1",
+				},
+			},
+		},
+	}
+	if err := k8sClient.Create(ctx, fixture); err != nil {
+		t.Fatalf("failed to create fixture: %s", err)
+	}
+
+	s := store.New(k8sClient, k8sClient)
+
+	tests := map[string]struct {
+		name     string
+		updates  []func(*api.Status)
+		expected *api.Status
+		err      error
+	}{
+		"should keep fields which are not updated": {
+			name: "test-cluster-1",
+			updates: []func(*api.Status){
+				func(status *api.Status) {
+					status.Parsed = true
+					status.Nodes = 2
+					status.LastPushedVersion = "version-1"
+				},
+				func(status *api.Status) {
+					status.NACKError = "rejected"
+				},
+			},
+			expected: &api.Status{
+				Parsed:            true,
+				Nodes:             2,
+				LastPushedVersion: "version-1",
+				NACKError:         "rejected",
+			},
+		},
+		"should return ErrNotFound": {
+			name: "not-found",
+			updates: []func(*api.Status){
+				func(*api.Status) {},
+			},
+			err: store.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, update := range test.updates {
+				err := s.UpdateStatus(ctx, api.ClusterKind, test.name, namespace, update)
+				if !errors.Is(err, test.err) {
+					t.Fatalf("want %v, but got %v", test.err, err)
+				}
 			}
 
-			for i, a := range actual.Items {
-				diffRoute(t, test.expected.Items[i], a)
+			if test.expected == nil {
+				return
+			}
+
+			var actual api.Status
+			err := s.UpdateStatus(ctx, api.ClusterKind, test.name, namespace, func(status *api.Status) {
+				actual = *status
+			})
+			if err != nil {
+				t.Fatalf("error: %s", err)
+			}
+
+			if diff := cmp.Diff(*test.expected, actual); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
 			}
 		})
 	}
@@ -1299,6 +2440,25 @@ func TestListPodsByNamespace(t *testing.T) {
 				}),
 			},
 		},
+		"should list pod2 with match expressions": {
+			expected: &corev1.PodList{
+				Items: []corev1.Pod{pod2},
+			},
+			options: []store.ListOption{
+				store.WithWorkloadSelector(&api.WorkloadSelector{
+					Labels: map[string]string{
+						"app": "envoy",
+					},
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{
+							Key:      "test",
+							Operator: metav1.LabelSelectorOpNotIn,
+							Values:   []string{"1"},
+						},
+					},
+				}),
+			},
+		},
 	}
 
 	s := store.New(k8sClient, k8sClient)
@@ -1320,6 +2480,92 @@ func TestListPodsByNamespace(t *testing.T) {
 	}
 }
 
+func TestListWorkloadEntriesByNamespace(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	namespace := testutils.NewNamespace(t, ctx, k8sClient)
+
+	entry1 := api.WorkloadEntry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vm-1",
+			Namespace: namespace,
+		},
+		Spec: api.WorkloadEntrySpec{
+			Labels: map[string]string{
+				"app":  "envoy",
+				"test": "1",
+			},
+		},
+	}
+
+	entry2 := api.WorkloadEntry{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "vm-2",
+			Namespace: namespace,
+		},
+		Spec: api.WorkloadEntrySpec{
+			Labels: map[string]string{
+				"app":  "envoy",
+				"test": "2",
+			},
+		},
+	}
+
+	fixtures := []api.WorkloadEntry{
+		entry1,
+		entry2,
+	}
+
+	for _, f := range fixtures {
+		if err := k8sClient.Create(ctx, &f); err != nil {
+			t.Fatalf("failed to create fixture: %s", err)
+		}
+	}
+
+	tests := map[string]struct {
+		expected []string
+		options  []store.ListOption
+	}{
+		"should list all workload entries": {
+			expected: []string{"vm-1", "vm-2"},
+		},
+		"should list workload entries by spec.labels": {
+			expected: []string{"vm-2"},
+			options: []store.ListOption{
+				store.WithWorkloadSelector(&api.WorkloadSelector{
+					Labels: map[string]string{
+						"test": "2",
+					},
+				}),
+			},
+		},
+	}
+
+	s := store.New(k8sClient, k8sClient)
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := s.ListWorkloadEntriesByNamespace(ctx, namespace, test.options...)
+			if err != nil {
+				t.Fatalf("failed %s", err)
+			}
+
+			names := make([]string, len(actual.Items))
+			for i, e := range actual.Items {
+				names[i] = e.Name
+			}
+
+			if diff := cmp.Diff(test.expected, names); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+		})
+	}
+}
+
 func podComparer(x, y corev1.Pod) bool {
 	if len(x.Spec.Containers) != len(y.Spec.Containers) {
 		return false
@@ -1355,6 +2601,12 @@ func diffCluster(t *testing.T, expected, actual *api.Cluster) {
 	if diff := cmp.Diff(expected.Spec.Config, actual.Spec.Config, protocmp.Transform()); diff != "" {
 		t.Errorf("\n(-expected, +actual)\n%s", diff)
 	}
+
+	if expected.Spec.ConfigV3 != nil {
+		if diff := cmp.Diff(expected.Spec.ConfigV3, actual.Spec.ConfigV3, protocmp.Transform()); diff != "" {
+			t.Errorf("\n(-expected, +actual)\n%s", diff)
+		}
+	}
 }
 
 func diffListener(t *testing.T, expected, actual *api.Listener) {
@@ -1396,3 +2648,23 @@ func diffRoute(t *testing.T, expected, actual *api.Route) {
 		t.Errorf("\n(-expected, +actual)\n%s", diff)
 	}
 }
+
+func diffSecret(t *testing.T, expected, actual *api.Secret) {
+	t.Helper()
+
+	if diff := cmp.Diff(expected.ObjectMeta, actual.ObjectMeta); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+
+	if diff := cmp.Diff(expected.Spec.WorkloadSelector, actual.Spec.WorkloadSelector); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+
+	if diff := cmp.Diff(expected.Spec.TLSSecretRef, actual.Spec.TLSSecretRef); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+
+	if diff := cmp.Diff(expected.Spec.Config, actual.Spec.Config, protocmp.Transform()); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+}
@@ -0,0 +1,184 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+)
+
+// ValidationError is returned when an envoy configuration violates its constraints.
+type ValidationError struct {
+	// Field is the path to the invalid field, e.g. `spec.config.loadAssignment.endpoints[0]`.
+	Field  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Reason)
+}
+
+// fieldError is implemented by the errors generated by protoc-gen-validate.
+type fieldError interface {
+	Field() string
+	Reason() string
+	Cause() error
+}
+
+// Validate parses the given object in the same way as the store does,
+// and then validates the envoy configuration of the declared xDS version.
+// Note that Validate does not resolve spec.tlsSecretRef of Secrets since the referenced Secret may not exist yet.
+func Validate(object map[string]interface{}) error {
+	s := &store{unmarshaler: newUnmarshaler()}
+
+	var (
+		version api.XDSVersion
+		config  proto.Message
+	)
+
+	switch kind := (&unstructured.Unstructured{Object: object}).GetKind(); kind {
+	case api.ClusterKind:
+		c, err := s.unmarshalCluster(object)
+		if err != nil {
+			return err
+		}
+		version, config = c.Spec.XDSVersion, selectConfig(c.Spec.XDSVersion, c.Spec.Config, c.Spec.ConfigV3)
+	case api.ListenerKind:
+		l, err := s.unmarshalListener(object)
+		if err != nil {
+			return err
+		}
+		version, config = l.Spec.XDSVersion, selectConfig(l.Spec.XDSVersion, l.Spec.Config, l.Spec.ConfigV3)
+	case api.RouteKind:
+		r, err := s.unmarshalRoute(object)
+		if err != nil {
+			return err
+		}
+		version, config = r.Spec.XDSVersion, selectConfig(r.Spec.XDSVersion, r.Spec.Config, r.Spec.ConfigV3)
+	case api.EndpointKind:
+		e, err := s.unmarshalEndpoint(object)
+		if err != nil {
+			return err
+		}
+		version, config = e.Spec.XDSVersion, selectConfig(e.Spec.XDSVersion, e.Spec.Config, e.Spec.ConfigV3)
+	case api.RuntimeKind:
+		r, err := s.unmarshalRuntime(object)
+		if err != nil {
+			return err
+		}
+		version, config = r.Spec.XDSVersion, selectConfig(r.Spec.XDSVersion, r.Spec.Config, r.Spec.ConfigV3)
+	case api.ScopedRouteKind:
+		sr, err := s.unmarshalScopedRoute(object)
+		if err != nil {
+			return err
+		}
+		version, config = sr.Spec.XDSVersion, selectConfig(sr.Spec.XDSVersion, sr.Spec.Config, sr.Spec.ConfigV3)
+	case api.VirtualHostKind:
+		vh, err := s.unmarshalVirtualHost(object)
+		if err != nil {
+			return err
+		}
+		version, config = vh.Spec.XDSVersion, selectConfig(vh.Spec.XDSVersion, vh.Spec.Config, vh.Spec.ConfigV3)
+	case api.SecretKind:
+		spec, err := extractSpecFromObject(object)
+		if err != nil {
+			return err
+		}
+
+		version, err = unmarshalXDSVersion(spec)
+		if err != nil {
+			return err
+		}
+
+		sc, scV3, err := s.unmarshalSecretConfig(spec, version)
+		if err != nil {
+			return err
+		}
+
+		if _, err := unmarshalWorkloadSelector(spec); err != nil && !errors.Is(err, errWorkloadSelectorNotFound) {
+			return err
+		}
+
+		if _, err := unmarshalExportTo(spec); err != nil {
+			return err
+		}
+
+		if _, err := unmarshalTLSSecretRef(spec); err != nil && !errors.Is(err, errTLSSecretRefNotFound) {
+			return err
+		}
+
+		config = selectConfig(version, sc, scV3)
+	default:
+		return fmt.Errorf("unsupported kind: %s", kind)
+	}
+
+	v, ok := config.(interface{ Validate() error })
+	if !ok {
+		return fmt.Errorf("spec.config of xdsVersion %s can not be validated", version)
+	}
+
+	if err := v.Validate(); err != nil {
+		return newValidationError("spec.config", err)
+	}
+
+	return nil
+}
+
+func newUnmarshaler() *protojson.UnmarshalOptions {
+	return &protojson.UnmarshalOptions{
+		AllowPartial:   false,
+		DiscardUnknown: true,
+	}
+}
+
+func selectConfig(version api.XDSVersion, v2, v3 proto.Message) proto.Message {
+	if version == api.XDSVersionV3 {
+		return v3
+	}
+
+	return v2
+}
+
+// newValidationError converts the chain of errors generated by protoc-gen-validate into the path to the innermost invalid field.
+func newValidationError(prefix string, err error) *ValidationError {
+	path := []string{prefix}
+	reason := err.Error()
+
+	for err != nil {
+		fe, ok := err.(fieldError)
+		if !ok {
+			break
+		}
+
+		path = append(path, lowerFirst(fe.Field()))
+		reason = fe.Reason()
+		err = fe.Cause()
+	}
+
+	if err != nil && len(path) > 1 {
+		// NOTE: the innermost cause which is not generated by protoc-gen-validate (e.g. failures of resolving Any) has the actual reason.
+		reason = fmt.Sprintf("%s: %s", reason, err)
+	}
+
+	return &ValidationError{
+		Field:  strings.Join(path, "."),
+		Reason: reason,
+	}
+}
+
+// lowerFirst converts the Go field name used by protoc-gen-validate into the JSON name of the field.
+func lowerFirst(s string) string {
+	r, n := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+
+	return string(unicode.ToLower(r)) + s[n:]
+}
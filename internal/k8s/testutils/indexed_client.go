@@ -0,0 +1,96 @@
+// +build test
+
+package testutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+var _ client.FieldIndexer = (*IndexedClient)(nil)
+
+// IndexedClient emulates field indexes of the informer cache on the client which reads objects from the API server,
+// since the API server can not select custom resources by their fields.
+type IndexedClient struct {
+	client.Client
+	indexes map[indexKey]client.IndexerFunc
+}
+
+type indexKey struct {
+	gvk   schema.GroupVersionKind
+	field string
+}
+
+func NewIndexedClient(c client.Client) *IndexedClient {
+	return &IndexedClient{
+		Client:  c,
+		indexes: map[indexKey]client.IndexerFunc{},
+	}
+}
+
+func (c *IndexedClient) IndexField(_ context.Context, obj runtime.Object, field string, extractValue client.IndexerFunc) error {
+	gvk, err := apiutil.GVKForObject(obj, s)
+	if err != nil {
+		return err
+	}
+
+	c.indexes[indexKey{gvk: gvk, field: field}] = extractValue
+
+	return nil
+}
+
+func (c *IndexedClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	lo := &client.ListOptions{}
+	lo.ApplyOptions(opts)
+
+	if lo.FieldSelector == nil {
+		return c.Client.List(ctx, list, opts...)
+	}
+
+	requirements := lo.FieldSelector.Requirements()
+	if len(requirements) != 1 || (requirements[0].Operator != selection.Equals && requirements[0].Operator != selection.DoubleEquals) {
+		return fmt.Errorf("non-exact field matches are not supported: %s", lo.FieldSelector)
+	}
+	r := requirements[0]
+
+	gvk, err := apiutil.GVKForObject(list, s)
+	if err != nil {
+		return err
+	}
+	gvk.Kind = strings.TrimSuffix(gvk.Kind, "List")
+
+	extract, ok := c.indexes[indexKey{gvk: gvk, field: r.Field}]
+	if !ok {
+		return fmt.Errorf("field %s of %s is not indexed", r.Field, gvk.Kind)
+	}
+
+	lo.FieldSelector = nil
+	if err := c.Client.List(ctx, list, lo); err != nil {
+		return err
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+
+	matched := []runtime.Object{}
+	for _, item := range items {
+		for _, v := range extract(item) {
+			if v == r.Value {
+				matched = append(matched, item)
+				break
+			}
+		}
+	}
+
+	return meta.SetList(list, matched)
+}
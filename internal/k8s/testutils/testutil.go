@@ -55,7 +55,7 @@ func TestK8SClient() (client.Client, func(), error) {
 		return nil, nil, err
 	}
 
-	return cli, func() {
+	return NewIndexedClient(cli), func() {
 		if err := testEnv.Stop(); err != nil {
 			panic(fmt.Sprintf("failed to stop envtest instance: %s", err))
 		}
@@ -0,0 +1,116 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	apiv1 "github.com/110y/bootes/internal/k8s/api/v1"
+)
+
+var _ client.Reader = (*tlsSecretReader)(nil)
+
+// TLSSecretInformer watches only Secrets labeled with apiv1.TLSSecretLabel,
+// since the informer cache of the manager can not select objects by labels and would hold all Secrets in the cluster.
+type TLSSecretInformer struct {
+	informer toolscache.SharedIndexInformer
+	lister   corelisters.SecretLister
+}
+
+// NewTLSSecretInformer creates the informer and adds it to the manager, which starts it along with the controllers.
+func NewTLSSecretInformer(mgr manager.Manager) (*TLSSecretInformer, error) {
+	cs, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes clientset: %w", err)
+	}
+
+	f := informers.NewSharedInformerFactoryWithOptions(cs, 0, informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+		o.LabelSelector = apiv1.TLSSecretLabel
+	}))
+	secrets := f.Core().V1().Secrets()
+
+	i := &TLSSecretInformer{
+		// NOTE: gets the informer before starting the factory, since the factory starts only the informers requested so far.
+		informer: secrets.Informer(),
+		lister:   secrets.Lister(),
+	}
+
+	err = mgr.Add(manager.RunnableFunc(func(stop <-chan struct{}) error {
+		f.Start(stop)
+		<-stop
+		return nil
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to add tls secret informer: %w", err)
+	}
+
+	return i, nil
+}
+
+// Reader returns the reader of the labeled Secrets, which reads them from the informer.
+func (i *TLSSecretInformer) Reader() client.Reader {
+	return &tlsSecretReader{lister: i.lister}
+}
+
+type tlsSecretReader struct {
+	lister corelisters.SecretLister
+}
+
+func (r *tlsSecretReader) Get(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return fmt.Errorf("unsupported object: %T", obj)
+	}
+
+	s, err := r.lister.Secrets(key.Namespace).Get(key.Name)
+	if err != nil {
+		return err
+	}
+
+	s.DeepCopyInto(secret)
+
+	return nil
+}
+
+func (r *tlsSecretReader) List(_ context.Context, list runtime.Object, opts ...client.ListOption) error {
+	secrets, ok := list.(*corev1.SecretList)
+	if !ok {
+		return fmt.Errorf("unsupported list: %T", list)
+	}
+
+	lo := &client.ListOptions{}
+	lo.ApplyOptions(opts)
+
+	selector := lo.LabelSelector
+	if selector == nil {
+		selector = labels.Everything()
+	}
+
+	var items []*corev1.Secret
+	var err error
+	if lo.Namespace == "" {
+		items, err = r.lister.List(selector)
+	} else {
+		items, err = r.lister.Secrets(lo.Namespace).List(selector)
+	}
+	if err != nil {
+		return err
+	}
+
+	secrets.Items = make([]corev1.Secret, len(items))
+	for i, s := range items {
+		s.DeepCopyInto(&secrets.Items[i])
+	}
+
+	return nil
+}
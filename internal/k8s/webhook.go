@@ -0,0 +1,17 @@
+package k8s
+
+import (
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	"github.com/110y/bootes/internal/k8s/internal/webhook"
+)
+
+// SetupValidatingWebhook registers the validating admission webhook for all bootes resources to the webhook server of the manager.
+// The webhook server serves TLS using the certificate found in ManagerConfig.WebhookCertDir.
+func SetupValidatingWebhook(mgr manager.Manager, l logr.Logger) {
+	mgr.GetWebhookServer().Register(webhook.ValidatorPath, &ctrlwebhook.Admission{
+		Handler: webhook.NewValidator(l),
+	})
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"sync"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
@@ -12,14 +13,33 @@ var (
 )
 
 type environments struct {
-	HealthProbeServerPort int `envconfig:"HEALTH_PROBE_SERVER_PORT" required:"true"`
+	HealthProbeServerPort  int           `envconfig:"HEALTH_PROBE_SERVER_PORT" required:"true"`
+	HealthReconcileTimeout time.Duration `envconfig:"HEALTH_RECONCILE_TIMEOUT" default:"5m"`
 
 	XDSGRPCPort             int  `envconfig:"XDS_GRPC_PORT" required:"true"`
 	XDSGRPCEnableChannelz   bool `envconfig:"XDS_GRPC_ENABLE_CHANNELZ"`
 	XDSGRPCEnableReflection bool `envconfig:"XDS_GRPC_ENABLE_REFLECTION"`
 
+	XDSNodeEvictionGracePeriod time.Duration `envconfig:"XDS_NODE_EVICTION_GRACE_PERIOD" default:"1m"`
+	XDSNodeIDFormat            string        `envconfig:"XDS_NODE_ID_FORMAT" default:"name.namespace"`
+	XDSNodeIDTemplate          string        `envconfig:"XDS_NODE_ID_TEMPLATE"`
+	XDSTrustNodeMetadata       bool          `envconfig:"XDS_TRUST_NODE_METADATA"`
+	XDSUpdateQuietPeriod       time.Duration `envconfig:"XDS_UPDATE_QUIET_PERIOD" default:"100ms"`
+	XDSUpdateMaxDelay          time.Duration `envconfig:"XDS_UPDATE_MAX_DELAY" default:"1s"`
+
+	XDSDebugServerEnabled bool `envconfig:"XDS_DEBUG_SERVER_ENABLED"`
+	XDSDebugServerPort    int  `envconfig:"XDS_DEBUG_SERVER_PORT" default:"6060"`
+
 	K8SMetricsServerPort int `envconfig:"K8S_METRICS_SERVER_PORT" required:"true"`
 
+	K8SLeaderElectionEnabled   bool   `envconfig:"K8S_LEADER_ELECTION_ENABLED"`
+	K8SLeaderElectionNamespace string `envconfig:"K8S_LEADER_ELECTION_NAMESPACE"`
+	K8SLeaderElectionID        string `envconfig:"K8S_LEADER_ELECTION_ID" default:"bootes-leader-election"`
+
+	K8SWebhookEnabled    bool   `envconfig:"K8S_WEBHOOK_ENABLED"`
+	K8SWebhookServerPort int    `envconfig:"K8S_WEBHOOK_SERVER_PORT" default:"9443"`
+	K8SWebhookCertDir    string `envconfig:"K8S_WEBHOOK_CERT_DIR"`
+
 	TraceUseStdout              bool   `envconfig:"TRACE_USE_STDOUT"`
 	TraceUseJaeger              bool   `envconfig:"TRACE_USE_JAEGER"`
 	TraceJaegerEndpoint         string `envconfig:"TRACE_JAEGER_ENDPOINT"`
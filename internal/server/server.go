@@ -8,11 +8,18 @@ import (
 	"sync"
 	"syscall"
 
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
 	"github.com/110y/bootes/internal/k8s"
 	"github.com/110y/bootes/internal/k8s/store"
 	"github.com/110y/bootes/internal/observer/trace"
 	"github.com/110y/bootes/internal/xds"
+	"github.com/110y/bootes/internal/xds/ack"
 	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/debug"
+	"github.com/110y/bootes/internal/xds/nodeid"
+	"github.com/110y/bootes/internal/xds/snapshot"
+	"github.com/110y/bootes/internal/xds/workload"
 )
 
 func Run() {
@@ -52,37 +59,103 @@ func run(ctx context.Context) int {
 	}
 	defer flush()
 
+	f, err := nodeid.New(env.XDSNodeIDFormat, env.XDSNodeIDTemplate)
+	if err != nil {
+		sl.Error(err, "failed to create node id format")
+		return 1
+	}
+
 	xl := l.WithName("xds")
-	sc := xds.NewSnapshotCache(xl.WithName("snapshot_cache"))
-	c := cache.New(sc)
+	sc := xds.NewSnapshotCache(f, xl.WithName("snapshot_cache"))
+	scv3 := xds.NewSnapshotCacheV3(f, xl.WithName("snapshot_cache_v3"))
+	c := cache.New(sc, scv3)
 
 	mgr, err := k8s.NewManager(&k8s.ManagerConfig{
-		HealthzServerPort: env.HealthProbeServerPort,
-		MetricsServerPort: env.K8SMetricsServerPort,
+		HealthzServerPort:       env.HealthProbeServerPort,
+		MetricsServerPort:       env.K8SMetricsServerPort,
+		WebhookServerPort:       env.K8SWebhookServerPort,
+		WebhookCertDir:          env.K8SWebhookCertDir,
+		LeaderElection:          env.K8SLeaderElectionEnabled,
+		LeaderElectionNamespace: env.K8SLeaderElectionNamespace,
+		LeaderElectionID:        env.K8SLeaderElectionID,
 	})
 	if err != nil {
 		sl.Error(err, "failed to create k8s manager")
 		return 1
 	}
 
-	s := store.New(mgr.GetClient(), mgr.GetAPIReader())
+	ti, err := k8s.NewTLSSecretInformer(mgr)
+	if err != nil {
+		sl.Error(err, "failed to create tls secret informer")
+		return 1
+	}
+
+	ir := k8s.NewInvalidResourceReporter(mgr, l.WithName("invalid_resource_reporter"))
+	s := store.New(mgr.GetClient(), mgr.GetAPIReader(), store.WithInvalidResourceHandler(ir.Report), store.WithForgetHandler(ir.Forget), store.WithTLSSecretReader(ti.Reader()))
+	var wopts []workload.Option
+	if env.XDSTrustNodeMetadata {
+		wopts = append(wopts, workload.WithNodeMetadata())
+	}
+	wr := workload.NewResolver(s, workload.NewRegistry(), f, wopts...)
+
+	if env.K8SWebhookEnabled {
+		k8s.SetupValidatingWebhook(mgr, l.WithName("webhook"))
+	}
+
+	tracker, err := ack.NewTracker(metrics.Registry)
+	if err != nil {
+		sl.Error(err, "failed to create ack tracker")
+		return 1
+	}
+
+	if err := cache.RegisterMetrics(metrics.Registry, c); err != nil {
+		sl.Error(err, "failed to register cache metrics")
+		return 1
+	}
+
+	if err := snapshot.RegisterMetrics(metrics.Registry); err != nil {
+		sl.Error(err, "failed to register snapshot metrics")
+		return 1
+	}
 
-	xs, err := xds.NewServer(ctx, sc, c, s, xl, &xds.Config{
-		Port:                 env.XDSGRPCPort,
-		EnableGRPCChannelz:   env.XDSGRPCEnableChannelz,
-		EnableGRPCReflection: env.XDSGRPCEnableReflection,
+	xs, err := xds.NewServer(ctx, sc, scv3, c, s, wr, tracker, k8s.NewNACKReporter(mgr, s, wr), xl, &xds.Config{
+		Port:                    env.XDSGRPCPort,
+		EnableGRPCChannelz:      env.XDSGRPCEnableChannelz,
+		EnableGRPCReflection:    env.XDSGRPCEnableReflection,
+		NodeEvictionGracePeriod: env.XDSNodeEvictionGracePeriod,
+		NodeIDFormat:            f,
+		MetricsRegisterer:       metrics.Registry,
 	})
 	if err != nil {
 		sl.Error(err, "failed to create xds server")
 		return 1
 	}
 
-	ctrl, err := k8s.NewController(mgr, s, c, l.WithName("k8s"))
+	if env.XDSDebugServerEnabled {
+		d := debug.New(xs, c, s, wr, tracker)
+		if err := mgr.Add(debug.NewServer(env.XDSDebugServerPort, d, tracker)); err != nil {
+			sl.Error(err, "failed to add debug server")
+			return 1
+		}
+	}
+
+	q := cache.NewQueue(c, env.XDSUpdateQuietPeriod, env.XDSUpdateMaxDelay, xl.WithName("update_queue"))
+	if err := cache.RegisterQueueMetrics(metrics.Registry, q); err != nil {
+		sl.Error(err, "failed to register queue metrics")
+		return 1
+	}
+
+	ctrl, err := k8s.NewController(mgr, s, c, q, wr, xs, ti, env.HealthReconcileTimeout, l.WithName("k8s"))
 	if err != nil {
 		sl.Error(err, "failed to create k8s controller")
 		return 1
 	}
 
+	if err := k8s.AddHealthChecks(mgr, "xds", xs.Ready, xs.Alive); err != nil {
+		sl.Error(err, "failed to register health checks of xds server")
+		return 1
+	}
+
 	xdsErrChan := make(chan error, 1)
 	xdsStopChan := make(chan struct{}, 1)
 	go func() {
@@ -0,0 +1,29 @@
+package ack
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HandlerPath is the path on which Handler is served.
+const HandlerPath = "/xds/acks"
+
+// Handler returns a handler which responds states in JSON.
+// The states can be filtered by the `node` and the `type_url` query parameters,
+// and only rejected ones are responded if `nacked` is `true`.
+func (t *Tracker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		q := r.URL.Query()
+		states := t.States(q.Get("node"), q.Get("type_url"), q.Get("nacked") == "true")
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(states); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
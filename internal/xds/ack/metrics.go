@@ -0,0 +1,46 @@
+package ack
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricsNamespace = "bootes"
+
+type metrics struct {
+	acks        *prometheus.CounterVec
+	nacks       *prometheus.CounterVec
+	nackedNodes *prometheus.GaugeVec
+}
+
+func newMetrics(registerer prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		acks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "xds",
+			Name:      "acks_total",
+			Help:      "Total number of responses accepted by nodes.",
+		}, []string{"type_url"}),
+		nacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "xds",
+			Name:      "nacks_total",
+			Help:      "Total number of responses rejected by nodes.",
+		}, []string{"type_url"}),
+		nackedNodes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "xds",
+			Name:      "nacked_nodes",
+			Help:      "Number of nodes whose last response has been rejected.",
+		}, []string{"type_url"}),
+	}
+
+	for _, c := range []prometheus.Collector{m.acks, m.nacks, m.nackedNodes} {
+		if err := registerer.Register(c); err != nil {
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+	}
+
+	return m, nil
+}
@@ -0,0 +1,207 @@
+package ack
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/genproto/googleapis/rpc/status"
+)
+
+// Transition is a change of the acceptance of a type URL by a node.
+type Transition int
+
+const (
+	// TransitionNone means the acceptance has not changed.
+	TransitionNone Transition = iota
+	// TransitionRejected means the node has newly rejected a response.
+	TransitionRejected
+	// TransitionRecovered means the node has accepted a response after rejecting previous ones.
+	TransitionRecovered
+)
+
+// State is the acceptance of a type URL by a node.
+type State struct {
+	Node         string `json:"node"`
+	TypeURL      string `json:"typeUrl"`
+	AckedVersion string `json:"ackedVersion,omitempty"`
+	NACK         *NACK  `json:"nack,omitempty"`
+}
+
+// NACK is the last rejection which has not been recovered yet.
+type NACK struct {
+	Version string    `json:"version,omitempty"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// StreamID identifies a stream, whose ID is unique only within the server serving it.
+type StreamID struct {
+	Server string
+	ID     int64
+}
+
+// Tracker tracks ACKs and NACKs of responses sent to each node per type URL,
+// by correlating nonces of requests with the ones of responses.
+type Tracker struct {
+	mu       sync.RWMutex
+	states   map[string]map[string]*State
+	versions map[StreamID]map[string]string
+	metrics  *metrics
+}
+
+func NewTracker(registerer prometheus.Registerer) (*Tracker, error) {
+	m, err := newMetrics(registerer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tracker{
+		states:   map[string]map[string]*State{},
+		versions: map[StreamID]map[string]string{},
+		metrics:  m,
+	}, nil
+}
+
+// OnResponse records the version of the response identified by the nonce on the stream.
+func (t *Tracker) OnResponse(streamID StreamID, nonce, version string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	versions, ok := t.versions[streamID]
+	if !ok {
+		versions = map[string]string{}
+		t.versions[streamID] = versions
+	}
+	versions[nonce] = version
+}
+
+// OnRequest records whether the node has accepted the response identified by the nonce, and returns the transition.
+func (t *Tracker) OnRequest(streamID StreamID, node, typeURL, nonce string, errorDetail *status.Status) Transition {
+	// NOTE: requests without nonces are not replies to responses.
+	if nonce == "" {
+		return TransitionNone
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	version := t.versions[streamID][nonce]
+	delete(t.versions[streamID], nonce)
+
+	st := t.getOrCreateState(node, typeURL)
+
+	if errorDetail != nil {
+		t.metrics.nacks.WithLabelValues(typeURL).Inc()
+
+		prev := st.NACK
+		st.NACK = &NACK{
+			Version: version,
+			Message: errorDetail.GetMessage(),
+			Time:    time.Now(),
+		}
+
+		if prev == nil {
+			t.metrics.nackedNodes.WithLabelValues(typeURL).Inc()
+			return TransitionRejected
+		}
+
+		if prev.Message != st.NACK.Message {
+			return TransitionRejected
+		}
+
+		return TransitionNone
+	}
+
+	t.metrics.acks.WithLabelValues(typeURL).Inc()
+
+	st.AckedVersion = version
+	if st.NACK != nil {
+		st.NACK = nil
+		t.metrics.nackedNodes.WithLabelValues(typeURL).Dec()
+		return TransitionRecovered
+	}
+
+	return TransitionNone
+}
+
+// OnStreamClosed discards versions of responses sent on the stream.
+func (t *Tracker) OnStreamClosed(streamID StreamID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.versions, streamID)
+}
+
+// Forget discards states of the evicted node, and the rejections by the node which have not been recovered yet.
+func (t *Tracker) Forget(node string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for typeURL, st := range t.states[node] {
+		if st.NACK != nil {
+			t.metrics.nackedNodes.WithLabelValues(typeURL).Dec()
+		}
+	}
+
+	delete(t.states, node)
+}
+
+// States returns states sorted by nodes and type URLs, filtered by the node and the type URL if they are not empty.
+func (t *Tracker) States(node, typeURL string, nackedOnly bool) []State {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	results := []State{}
+	for n, states := range t.states {
+		if node != "" && n != node {
+			continue
+		}
+
+		for u, st := range states {
+			if typeURL != "" && u != typeURL {
+				continue
+			}
+
+			if nackedOnly && st.NACK == nil {
+				continue
+			}
+
+			s := *st
+			if st.NACK != nil {
+				nack := *st.NACK
+				s.NACK = &nack
+			}
+			results = append(results, s)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Node != results[j].Node {
+			return results[i].Node < results[j].Node
+		}
+		return results[i].TypeURL < results[j].TypeURL
+	})
+
+	return results
+}
+
+func (t *Tracker) getOrCreateState(node, typeURL string) *State {
+	states, ok := t.states[node]
+	if !ok {
+		states = map[string]*State{}
+		t.states[node] = states
+	}
+
+	st, ok := states[typeURL]
+	if !ok {
+		st = &State{
+			Node:    node,
+			TypeURL: typeURL,
+		}
+		states[typeURL] = st
+	}
+
+	return st
+}
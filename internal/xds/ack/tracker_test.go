@@ -0,0 +1,183 @@
+package ack_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/genproto/googleapis/rpc/status"
+
+	"github.com/110y/bootes/internal/xds/ack"
+)
+
+const typeURL = "type.googleapis.com/envoy.api.v2.Cluster"
+
+var streamID = ack.StreamID{Server: "v2", ID: 1}
+
+type request struct {
+	nonce       string
+	errorDetail *status.Status
+}
+
+func TestTrackerOnRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		requests    []request
+		transitions []ack.Transition
+		expected    []ack.State
+	}{
+		"should track accepted version": {
+			requests: []request{
+				{nonce: "1"},
+			},
+			transitions: []ack.Transition{ack.TransitionNone},
+			expected: []ack.State{
+				{Node: "node-1", TypeURL: typeURL, AckedVersion: "version-1"},
+			},
+		},
+		"should ignore requests without nonces": {
+			requests: []request{
+				{},
+			},
+			transitions: []ack.Transition{ack.TransitionNone},
+			expected:    []ack.State{},
+		},
+		"should report rejection only when it has changed": {
+			requests: []request{
+				{nonce: "1"},
+				{nonce: "2", errorDetail: &status.Status{Message: "invalid"}},
+				{nonce: "3", errorDetail: &status.Status{Message: "invalid"}},
+				{nonce: "4", errorDetail: &status.Status{Message: "still invalid"}},
+			},
+			transitions: []ack.Transition{ack.TransitionNone, ack.TransitionRejected, ack.TransitionNone, ack.TransitionRejected},
+			expected: []ack.State{
+				{
+					Node:         "node-1",
+					TypeURL:      typeURL,
+					AckedVersion: "version-1",
+					NACK: &ack.NACK{
+						Version: "version-4",
+						Message: "still invalid",
+					},
+				},
+			},
+		},
+		"should report recovery": {
+			requests: []request{
+				{nonce: "1", errorDetail: &status.Status{Message: "invalid"}},
+				{nonce: "2"},
+			},
+			transitions: []ack.Transition{ack.TransitionRejected, ack.TransitionRecovered},
+			expected: []ack.State{
+				{Node: "node-1", TypeURL: typeURL, AckedVersion: "version-2"},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tracker, err := ack.NewTracker(prometheus.NewRegistry())
+			if err != nil {
+				t.Fatalf("error: %s", err)
+			}
+
+			for _, nonce := range []string{"1", "2", "3", "4"} {
+				tracker.OnResponse(streamID, nonce, "version-"+nonce)
+			}
+
+			transitions := make([]ack.Transition, len(test.requests))
+			for i, req := range test.requests {
+				transitions[i] = tracker.OnRequest(streamID, "node-1", typeURL, req.nonce, req.errorDetail)
+			}
+
+			if diff := cmp.Diff(test.transitions, transitions); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+
+			actual := tracker.States("", "", false)
+			if diff := cmp.Diff(test.expected, actual, cmpopts.IgnoreFields(ack.NACK{}, "Time")); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTrackerOnRequestDistinguishesServers(t *testing.T) {
+	t.Parallel()
+
+	tracker, err := ack.NewTracker(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+
+	// NOTE: IDs of streams are unique only within each server.
+	v2 := ack.StreamID{Server: "v2", ID: 1}
+	v3 := ack.StreamID{Server: "v3", ID: 1}
+
+	tracker.OnResponse(v2, "1", "version-v2")
+	tracker.OnResponse(v3, "1", "version-v3")
+	tracker.OnStreamClosed(v3)
+
+	tracker.OnRequest(v2, "node-1", typeURL, "1", nil)
+
+	expected := []ack.State{
+		{Node: "node-1", TypeURL: typeURL, AckedVersion: "version-v2"},
+	}
+	if diff := cmp.Diff(expected, tracker.States("", "", false)); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+}
+
+func TestTrackerForget(t *testing.T) {
+	t.Parallel()
+
+	registry := prometheus.NewRegistry()
+	tracker, err := ack.NewTracker(registry)
+	if err != nil {
+		t.Fatalf("error: %s", err)
+	}
+
+	tracker.OnResponse(streamID, "1", "version-1")
+	tracker.OnResponse(streamID, "2", "version-2")
+	tracker.OnRequest(streamID, "node-1", typeURL, "1", &status.Status{Message: "invalid"})
+	tracker.OnRequest(streamID, "node-2", typeURL, "2", &status.Status{Message: "invalid"})
+
+	tracker.Forget("node-1")
+
+	expected := []ack.State{
+		{
+			Node:    "node-2",
+			TypeURL: typeURL,
+			NACK: &ack.NACK{
+				Version: "version-2",
+				Message: "invalid",
+			},
+		},
+	}
+	if diff := cmp.Diff(expected, tracker.States("", "", false), cmpopts.IgnoreFields(ack.NACK{}, "Time")); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+
+	// NOTE: only the rejection by the node which has not been forgotten remains.
+	metrics := `
+# HELP bootes_xds_nacked_nodes Number of nodes whose last response has been rejected.
+# TYPE bootes_xds_nacked_nodes gauge
+bootes_xds_nacked_nodes{type_url="type.googleapis.com/envoy.api.v2.Cluster"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(metrics), "bootes_xds_nacked_nodes"); err != nil {
+		t.Errorf("unexpected metrics: %s", err)
+	}
+
+	// NOTE: forgetting the node again must not decrease the gauge twice.
+	tracker.Forget("node-1")
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(metrics), "bootes_xds_nacked_nodes"); err != nil {
+		t.Errorf("unexpected metrics: %s", err)
+	}
+}
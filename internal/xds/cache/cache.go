@@ -3,10 +3,11 @@ package cache
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
 	xdscache "github.com/envoyproxy/go-control-plane/pkg/cache/v2"
-	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
+	xdscachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
 
 	apiv1 "github.com/110y/bootes/internal/k8s/api/v1"
 	"github.com/110y/bootes/internal/observer/trace"
@@ -16,205 +17,419 @@ var _ Cache = (*cache)(nil)
 
 type Cache interface {
 	IsCachedNode(node string) bool
-	UpdateAllResources(ctx context.Context, node, version string, clusters []*apiv1.Cluster, listeners []*apiv1.Listener, routes []*apiv1.Route, endpoints []*apiv1.Endpoint) error
-	UpdateClusters(ctx context.Context, node, version string, clusters []*apiv1.Cluster) error
-	UpdateListeners(ctx context.Context, node, version string, listeners []*apiv1.Listener) error
-	UpdateRoutes(ctx context.Context, node, version string, routes []*apiv1.Route) error
-	UpdateEndpoints(ctx context.Context, node, version string, endpoints []*apiv1.Endpoint) error
+	IsBuildingNode(node string) bool
+	BeginBuild(node string) func()
+	ClearNode(node string)
+	CachedNodes() int
+	Update(ctx context.Context, node string, fn func(s *Snapshot) error) error
+	UpdateIfCached(ctx context.Context, node string, fn func(s *Snapshot) error) (bool, error)
+	UpdateAllResources(ctx context.Context, node string, clusters []*apiv1.Cluster, listeners []*apiv1.Listener, routes []*apiv1.Route, endpoints []*apiv1.Endpoint, secrets []*apiv1.Secret, runtimes []*apiv1.Runtime) error
+	UpdateClusters(ctx context.Context, node string, clusters []*apiv1.Cluster) error
+	UpdateListeners(ctx context.Context, node string, listeners []*apiv1.Listener) error
+	UpdateRoutes(ctx context.Context, node string, routes []*apiv1.Route) error
+	UpdateEndpoints(ctx context.Context, node string, endpoints []*apiv1.Endpoint) error
+	UpdateSecrets(ctx context.Context, node string, secrets []*apiv1.Secret) error
+	UpdateRuntimes(ctx context.Context, node string, runtimes []*apiv1.Runtime) error
+	UpdateScopedRoutes(ctx context.Context, node string, scopedRoutes []*apiv1.ScopedRoute) error
+	UpdateVirtualHosts(ctx context.Context, node string, virtualHosts []*apiv1.VirtualHost) error
+	GetResources(node, typeURL string) (string, map[string]types.Resource)
+	Watch(node string) (<-chan struct{}, func())
+	Pushes() map[string]uint64
 }
 
 type cache struct {
-	snapshotCache xdscache.SnapshotCache
+	snapshotCache   xdscache.SnapshotCache
+	snapshotCacheV3 xdscachev3.SnapshotCache
+	resources       *resourceStore
+	locks           *nodeLocks
+
+	nodesMu  sync.Mutex
+	nodes    map[string]struct{}
+	building map[string]int
+
+	pushesMu sync.Mutex
+	pushes   map[string]uint64
 }
 
-func New(snapshotCache xdscache.SnapshotCache) Cache {
+func New(snapshotCache xdscache.SnapshotCache, snapshotCacheV3 xdscachev3.SnapshotCache) Cache {
 	return &cache{
-		snapshotCache: snapshotCache,
+		snapshotCache:   snapshotCache,
+		snapshotCacheV3: snapshotCacheV3,
+		resources:       newResourceStore(),
+		locks:           newNodeLocks(),
+		nodes:           map[string]struct{}{},
+		building:        map[string]int{},
+		pushes:          map[string]uint64{},
 	}
 }
 
 func (c *cache) IsCachedNode(node string) bool {
-	_, err := c.snapshotCache.GetSnapshot(node)
-	if err != nil {
+	if _, err := c.snapshotCache.GetSnapshot(node); err != nil {
+		return false
+	}
+
+	if _, err := c.snapshotCacheV3.GetSnapshot(node); err != nil {
 		return false
 	}
 
 	return true
 }
 
-func (c *cache) UpdateAllResources(ctx context.Context, node, version string, clusters []*apiv1.Cluster, listeners []*apiv1.Listener, routes []*apiv1.Route, endpoints []*apiv1.Endpoint) error {
-	ctx, span := trace.NewSpan(ctx, "Cache.UpdateAllResources")
+// BeginBuild marks the node as being built until the returned function is called.
+// Resources of the node must be listed after it has been marked, so that changes made meanwhile are either listed or enqueued by reconcilers, which check IsBuildingNode.
+func (c *cache) BeginBuild(node string) func() {
+	c.nodesMu.Lock()
+	c.building[node]++
+	c.nodesMu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.nodesMu.Lock()
+			defer c.nodesMu.Unlock()
+
+			c.building[node]--
+			if c.building[node] == 0 {
+				delete(c.building, node)
+			}
+		})
+	}
+}
+
+// IsBuildingNode reports whether all resources of the node are being built, e.g. when it has connected for the first time.
+func (c *cache) IsBuildingNode(node string) bool {
+	c.nodesMu.Lock()
+	defer c.nodesMu.Unlock()
+
+	_, ok := c.building[node]
+	return ok
+}
+
+// ClearNode removes all resources of the node, e.g. when it has been evicted.
+func (c *cache) ClearNode(node string) {
+	unlock := c.locks.lock(node)
+	defer unlock()
+
+	c.snapshotCache.ClearSnapshot(node)
+	c.snapshotCacheV3.ClearSnapshot(node)
+	c.resources.clear(node)
+
+	c.nodesMu.Lock()
+	delete(c.nodes, node)
+	c.nodesMu.Unlock()
+}
+
+// CachedNodes returns the number of nodes which have snapshots.
+func (c *cache) CachedNodes() int {
+	c.nodesMu.Lock()
+	defer c.nodesMu.Unlock()
+
+	return len(c.nodes)
+}
+
+// Update replaces resources of the node by the ones set to the snapshot by fn at once.
+// Resources of the types fn does not set are kept as they are.
+// Updates of a node are serialized so that concurrent updates of different types never overwrite each other,
+// thus fn must not call methods of the cache for the same node.
+func (c *cache) Update(ctx context.Context, node string, fn func(s *Snapshot) error) error {
+	_, span := trace.NewSpan(ctx, "Cache.Update")
 	defer span.End()
 
-	cr := make([]types.Resource, len(clusters))
-	for i, c := range clusters {
-		cr[i] = c.Spec.Config
-	}
+	unlock := c.locks.lock(node)
+	defer unlock()
 
-	lr := make([]types.Resource, len(listeners))
-	for i, l := range listeners {
-		lr[i] = l.Spec.Config
+	return c.update(node, fn)
+}
+
+// UpdateIfCached is the same as Update except that it does nothing if the node has not been cached, and reports whether the node has been updated.
+// The node is checked under the lock of the node, so that a part of resources of the node evicted meanwhile is never restored.
+func (c *cache) UpdateIfCached(ctx context.Context, node string, fn func(s *Snapshot) error) (bool, error) {
+	_, span := trace.NewSpan(ctx, "Cache.UpdateIfCached")
+	defer span.End()
+
+	unlock := c.locks.lock(node)
+	defer unlock()
+
+	if !c.IsCachedNode(node) {
+		return false, nil
 	}
 
-	rr := make([]types.Resource, len(routes))
-	for i, r := range routes {
-		rr[i] = r.Spec.Config
+	return true, c.update(node, fn)
+}
+
+// update applies the snapshot set by fn to the node, which must be called with the lock of the node held.
+func (c *cache) update(node string, fn func(s *Snapshot) error) error {
+	s := &Snapshot{}
+	if err := fn(s); err != nil {
+		return err
 	}
 
-	er := make([]types.Resource, len(endpoints))
-	for i, e := range endpoints {
-		er[i] = e.Spec.Config
+	// NOTE: update resources served over the incremental xDS protocol first since IsCachedNode only checks snapshots.
+	for typeURL, resources := range s.resources {
+		version, err := resourcesVersion(resources)
+		if err != nil {
+			return fmt.Errorf("failed to compute version of %s: %w", typeURL, err)
+		}
+
+		if c.resources.set(node, typeURL, version, resources) {
+			c.countPush(typeURL)
+		}
 	}
 
-	var s xdscache.Snapshot
-	oldSnapshot, err := c.snapshotCache.GetSnapshot(node)
-	if err != nil {
-		s = xdscache.NewSnapshot(version, er, cr, rr, lr, nil)
-	} else {
-		runtimes := getResourceFromSnapshot(&oldSnapshot, resource.RuntimeType)
+	if len(s.updates) == 0 {
+		return nil
+	}
 
-		xdscache.NewSnapshot(version, er, cr, rr, lr, runtimes)
+	if err := c.updateSnapshot(node, s.updates); err != nil {
+		return fmt.Errorf("failed to update snapshot: %w", err)
 	}
 
-	if err := c.snapshotCache.SetSnapshot(node, s); err != nil {
-		return fmt.Errorf("failed to update all resources snapshot: %w", err)
+	if err := c.updateSnapshotV3(node, s.updates); err != nil {
+		return fmt.Errorf("failed to update v3 snapshot: %w", err)
 	}
 
 	return nil
 }
 
-func (c *cache) UpdateClusters(ctx context.Context, node, version string, clusters []*apiv1.Cluster) error {
-	ctx, span := trace.NewSpan(ctx, "Cache.UpdateClusters")
+func (c *cache) UpdateAllResources(ctx context.Context, node string, clusters []*apiv1.Cluster, listeners []*apiv1.Listener, routes []*apiv1.Route, endpoints []*apiv1.Endpoint, secrets []*apiv1.Secret, runtimes []*apiv1.Runtime) error {
+	_, span := trace.NewSpan(ctx, "Cache.UpdateAllResources")
 	defer span.End()
 
-	snapshot := c.newClusterSnapshot(node, version, clusters)
-	if err := c.snapshotCache.SetSnapshot(node, snapshot); err != nil {
-		return fmt.Errorf("failed to update cluster snapshot: %w", err)
+	err := c.Update(ctx, node, func(s *Snapshot) error {
+		s.SetClusters(clusters)
+		s.SetListeners(listeners)
+		s.SetRoutes(routes)
+		s.SetEndpoints(endpoints)
+		s.SetSecrets(secrets)
+		s.SetRuntimes(runtimes)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update all resources: %w", err)
 	}
 
 	return nil
 }
 
-func (c *cache) UpdateListeners(ctx context.Context, node, version string, listeners []*apiv1.Listener) error {
-	ctx, span := trace.NewSpan(ctx, "Cache.UpdateListeners")
+func (c *cache) UpdateClusters(ctx context.Context, node string, clusters []*apiv1.Cluster) error {
+	_, span := trace.NewSpan(ctx, "Cache.UpdateClusters")
 	defer span.End()
 
-	snapshot := c.newListenerSnapshot(node, version, listeners)
-	if err := c.snapshotCache.SetSnapshot(node, snapshot); err != nil {
-		return fmt.Errorf("failed to update listener snapshot: %w", err)
+	err := c.Update(ctx, node, func(s *Snapshot) error {
+		s.SetClusters(clusters)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update clusters: %w", err)
 	}
 
 	return nil
 }
 
-func (c *cache) UpdateRoutes(ctx context.Context, node, version string, routes []*apiv1.Route) error {
-	ctx, span := trace.NewSpan(ctx, "Cache.UpdateRoutes")
+func (c *cache) UpdateListeners(ctx context.Context, node string, listeners []*apiv1.Listener) error {
+	_, span := trace.NewSpan(ctx, "Cache.UpdateListeners")
 	defer span.End()
 
-	snapshot := c.newRouteSnapshot(node, version, routes)
-	if err := c.snapshotCache.SetSnapshot(node, snapshot); err != nil {
-		return fmt.Errorf("failed to update route snapshot: %w", err)
+	err := c.Update(ctx, node, func(s *Snapshot) error {
+		s.SetListeners(listeners)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update listeners: %w", err)
 	}
 
 	return nil
 }
 
-func (c *cache) UpdateEndpoints(ctx context.Context, node, version string, endpoints []*apiv1.Endpoint) error {
-	ctx, span := trace.NewSpan(ctx, "Cache.UpdateEndpoints")
+func (c *cache) UpdateRoutes(ctx context.Context, node string, routes []*apiv1.Route) error {
+	_, span := trace.NewSpan(ctx, "Cache.UpdateRoutes")
 	defer span.End()
 
-	snapshot := c.newEndpointSnapshot(node, version, endpoints)
-	if err := c.snapshotCache.SetSnapshot(node, snapshot); err != nil {
-		return fmt.Errorf("failed to update endpoint snapshot: %w", err)
+	err := c.Update(ctx, node, func(s *Snapshot) error {
+		s.SetRoutes(routes)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update routes: %w", err)
 	}
 
 	return nil
 }
 
-func (c *cache) newClusterSnapshot(node, version string, clusters []*apiv1.Cluster) xdscache.Snapshot {
-	resources := make([]types.Resource, len(clusters))
-	for i, c := range clusters {
-		resources[i] = c.Spec.Config
-	}
+func (c *cache) UpdateEndpoints(ctx context.Context, node string, endpoints []*apiv1.Endpoint) error {
+	_, span := trace.NewSpan(ctx, "Cache.UpdateEndpoints")
+	defer span.End()
 
-	s, err := c.snapshotCache.GetSnapshot(node)
+	err := c.Update(ctx, node, func(s *Snapshot) error {
+		s.SetEndpoints(endpoints)
+		return nil
+	})
 	if err != nil {
-		return xdscache.NewSnapshot(version, nil, resources, nil, nil, nil)
+		return fmt.Errorf("failed to update endpoints: %w", err)
 	}
 
-	endpoints := getResourceFromSnapshot(&s, resource.EndpointType)
-	routes := getResourceFromSnapshot(&s, resource.RouteType)
-	listeners := getResourceFromSnapshot(&s, resource.ListenerType)
-	runtimes := getResourceFromSnapshot(&s, resource.RuntimeType)
+	return nil
+}
+
+func (c *cache) UpdateSecrets(ctx context.Context, node string, secrets []*apiv1.Secret) error {
+	_, span := trace.NewSpan(ctx, "Cache.UpdateSecrets")
+	defer span.End()
 
-	return xdscache.NewSnapshot(version, endpoints, resources, routes, listeners, runtimes)
+	err := c.Update(ctx, node, func(s *Snapshot) error {
+		s.SetSecrets(secrets)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update secrets: %w", err)
+	}
+
+	return nil
 }
 
-func (c *cache) newListenerSnapshot(node, version string, listeners []*apiv1.Listener) xdscache.Snapshot {
-	resources := make([]types.Resource, len(listeners))
-	for i, l := range listeners {
-		resources[i] = l.Spec.Config
+func (c *cache) UpdateRuntimes(ctx context.Context, node string, runtimes []*apiv1.Runtime) error {
+	_, span := trace.NewSpan(ctx, "Cache.UpdateRuntimes")
+	defer span.End()
+
+	err := c.Update(ctx, node, func(s *Snapshot) error {
+		s.SetRuntimes(runtimes)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update runtimes: %w", err)
 	}
 
-	s, err := c.snapshotCache.GetSnapshot(node)
+	return nil
+}
+
+func (c *cache) UpdateScopedRoutes(ctx context.Context, node string, scopedRoutes []*apiv1.ScopedRoute) error {
+	_, span := trace.NewSpan(ctx, "Cache.UpdateScopedRoutes")
+	defer span.End()
+
+	err := c.Update(ctx, node, func(s *Snapshot) error {
+		s.SetScopedRoutes(scopedRoutes)
+		return nil
+	})
 	if err != nil {
-		return xdscache.NewSnapshot(version, nil, nil, nil, resources, nil)
+		return fmt.Errorf("failed to update scoped routes: %w", err)
 	}
 
-	endpoints := getResourceFromSnapshot(&s, resource.EndpointType)
-	clusters := getResourceFromSnapshot(&s, resource.ClusterType)
-	routes := getResourceFromSnapshot(&s, resource.RouteType)
-	runtimes := getResourceFromSnapshot(&s, resource.RuntimeType)
+	return nil
+}
+
+func (c *cache) UpdateVirtualHosts(ctx context.Context, node string, virtualHosts []*apiv1.VirtualHost) error {
+	_, span := trace.NewSpan(ctx, "Cache.UpdateVirtualHosts")
+	defer span.End()
 
-	return xdscache.NewSnapshot(version, endpoints, clusters, routes, resources, runtimes)
+	err := c.Update(ctx, node, func(s *Snapshot) error {
+		s.SetVirtualHosts(virtualHosts)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update virtual hosts: %w", err)
+	}
+
+	return nil
 }
 
-func (c *cache) newRouteSnapshot(node, version string, routes []*apiv1.Route) xdscache.Snapshot {
-	resources := make([]types.Resource, len(routes))
-	for i, r := range routes {
-		resources[i] = r.Spec.Config
+// GetResources returns the version and the v3 resources of the typeURL for the node, which are served over the incremental xDS protocol.
+func (c *cache) GetResources(node, typeURL string) (string, map[string]types.Resource) {
+	switch typeURL {
+	case ScopedRouteTypeV3, VirtualHostTypeV3:
+		return c.resources.get(node, typeURL)
 	}
 
-	s, err := c.snapshotCache.GetSnapshot(node)
+	s, err := c.snapshotCacheV3.GetSnapshot(node)
 	if err != nil {
-		return xdscache.NewSnapshot(version, nil, nil, resources, nil, nil)
+		return "", map[string]types.Resource{}
 	}
 
-	endpoints := getResourceFromSnapshot(&s, resource.EndpointType)
-	clusters := getResourceFromSnapshot(&s, resource.ClusterType)
-	listeners := getResourceFromSnapshot(&s, resource.ListenerType)
-	runtimes := getResourceFromSnapshot(&s, resource.RuntimeType)
+	cache := s.GetResources(typeURL)
+	resources := make(map[string]types.Resource, len(cache))
+	for name, r := range cache {
+		resources[name] = r
+	}
 
-	return xdscache.NewSnapshot(version, endpoints, clusters, resources, listeners, runtimes)
+	return s.GetVersion(typeURL), resources
 }
 
-func (c *cache) newEndpointSnapshot(node, version string, endpoints []*apiv1.Endpoint) xdscache.Snapshot {
-	resources := make([]types.Resource, len(endpoints))
-	for i, r := range endpoints {
-		resources[i] = r.Spec.Config
+// Pushes returns the number of updates of resources of nodes by v3 type URL, each of which is pushed to the node.
+func (c *cache) Pushes() map[string]uint64 {
+	c.pushesMu.Lock()
+	defer c.pushesMu.Unlock()
+
+	pushes := make(map[string]uint64, len(c.pushes))
+	for typeURL, n := range c.pushes {
+		pushes[typeURL] = n
 	}
 
-	s, err := c.snapshotCache.GetSnapshot(node)
-	if err != nil {
-		return xdscache.NewSnapshot(version, nil, nil, resources, nil, nil)
+	return pushes
+}
+
+func (c *cache) countPush(typeURL string) {
+	c.pushesMu.Lock()
+	defer c.pushesMu.Unlock()
+
+	c.pushes[typeURL]++
+}
+
+// Watch returns a channel notified when v3 resources of the node are updated.
+// The returned function must be called to stop watching.
+func (c *cache) Watch(node string) (<-chan struct{}, func()) {
+	return c.resources.watch(node)
+}
+
+// updateSnapshot sets the snapshot of the node with the updates applied.
+// Types which the node has never had are set empty so that proxies can complete their initial requests of them.
+// The snapshot is left untouched when the updates do not change any version,
+// since SnapshotCache responds to all open watches of the node on every SetSnapshot.
+func (c *cache) updateSnapshot(node string, updates map[types.ResponseType]typeUpdate) error {
+	current, err := c.snapshotCache.GetSnapshot(node)
+	exists := err == nil
+
+	snapshot := current
+	if !exists {
+		for i := range snapshot.Resources {
+			r, err := newResources(nil)
+			if err != nil {
+				return err
+			}
+			snapshot.Resources[i] = r
+		}
 	}
 
-	clusters := getResourceFromSnapshot(&s, resource.ClusterType)
-	listeners := getResourceFromSnapshot(&s, resource.ListenerType)
-	routes := getResourceFromSnapshot(&s, resource.RouteType)
-	runtimes := getResourceFromSnapshot(&s, resource.RuntimeType)
+	for _, u := range updates {
+		r, err := newResources(u.resources)
+		if err != nil {
+			return fmt.Errorf("failed to create resources: %w", err)
+		}
+		snapshot.Resources[u.responseType] = r
+	}
+
+	if exists && snapshotVersions(snapshot) == snapshotVersions(current) {
+		return nil
+	}
+
+	return c.snapshotCache.SetSnapshot(node, snapshot)
+}
+
+// newResources creates resources of a type versioned by their contents.
+func newResources(items []types.Resource) (xdscache.Resources, error) {
+	resources := xdscache.NewResources("", items)
+
+	version, err := resourcesVersion(resources.Items)
+	if err != nil {
+		return xdscache.Resources{}, err
+	}
+	resources.Version = version
 
-	return xdscache.NewSnapshot(version, resources, clusters, routes, listeners, runtimes)
+	return resources, nil
 }
 
-func getResourceFromSnapshot(snapshot *xdscache.Snapshot, typeURL string) []types.Resource {
-	cache := snapshot.GetResources(typeURL)
-	resources := make([]types.Resource, len(cache))
-	i := 0
-	for _, e := range cache {
-		resources[i] = e
-		i++
+func snapshotVersions(s xdscache.Snapshot) [types.UnknownType]string {
+	var versions [types.UnknownType]string
+	for i, r := range s.Resources {
+		versions[i] = r.Version
 	}
 
-	return resources
+	return versions
 }
@@ -0,0 +1,775 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	envoyapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	auth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	endpointv3 "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
+	runtimev3 "github.com/envoyproxy/go-control-plane/envoy/service/runtime/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	xdscache "github.com/envoyproxy/go-control-plane/pkg/cache/v2"
+	xdscachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	resource "github.com/envoyproxy/go-control-plane/pkg/resource/v2"
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/google/go-cmp/cmp"
+
+	apiv1 "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/xds/cache"
+)
+
+func newCache() cache.Cache {
+	return cache.New(
+		xdscache.NewSnapshotCache(false, xdscache.IDHash{}, nil),
+		xdscachev3.NewSnapshotCache(false, xdscachev3.IDHash{}, nil),
+	)
+}
+
+func newCluster(name, altStatName string) *apiv1.Cluster {
+	return &apiv1.Cluster{
+		Spec: apiv1.ClusterSpec{
+			Config:   &envoyapi.Cluster{Name: name, AltStatName: altStatName},
+			ConfigV3: &clusterv3.Cluster{Name: name, AltStatName: altStatName},
+		},
+	}
+}
+
+func newListener(name string) *apiv1.Listener {
+	return &apiv1.Listener{
+		Spec: apiv1.ListenerSpec{
+			Config:   &envoyapi.Listener{Name: name},
+			ConfigV3: &listenerv3.Listener{Name: name},
+		},
+	}
+}
+
+func newRoute(name string) *apiv1.Route {
+	return &apiv1.Route{
+		Spec: apiv1.RouteSpec{
+			Config:   &envoyapi.RouteConfiguration{Name: name},
+			ConfigV3: &routev3.RouteConfiguration{Name: name},
+		},
+	}
+}
+
+func newEndpoint(name string) *apiv1.Endpoint {
+	return &apiv1.Endpoint{
+		Spec: apiv1.EndpointSpec{
+			Config:   &envoyapi.ClusterLoadAssignment{ClusterName: name},
+			ConfigV3: &endpointv3.ClusterLoadAssignment{ClusterName: name},
+		},
+	}
+}
+
+func newSecret(name string) *apiv1.Secret {
+	return &apiv1.Secret{
+		Spec: apiv1.SecretSpec{
+			Config:   &auth.Secret{Name: name},
+			ConfigV3: &tlsv3.Secret{Name: name},
+		},
+	}
+}
+
+func newRuntime(name string) *apiv1.Runtime {
+	return &apiv1.Runtime{
+		Spec: apiv1.RuntimeSpec{
+			Config:   &discovery.Runtime{Name: name},
+			ConfigV3: &runtimev3.Runtime{Name: name},
+		},
+	}
+}
+
+// resourceType pairs the type URLs of the same type in each API version.
+type resourceType struct {
+	typeURL   string
+	typeURLV3 string
+}
+
+var (
+	clusterType  = resourceType{resource.ClusterType, resourcev3.ClusterType}
+	listenerType = resourceType{resource.ListenerType, resourcev3.ListenerType}
+	routeType    = resourceType{resource.RouteType, resourcev3.RouteType}
+	endpointType = resourceType{resource.EndpointType, resourcev3.EndpointType}
+	secretType   = resourceType{resource.SecretType, resourcev3.SecretType}
+	runtimeType  = resourceType{resource.RuntimeType, resourcev3.RuntimeType}
+
+	resourceTypes = []resourceType{clusterType, listenerType, routeType, endpointType, secretType, runtimeType}
+)
+
+type update struct {
+	name     string
+	update   func(ctx context.Context, c cache.Cache, node string) error
+	expected map[resourceType][]string
+}
+
+func updateAll(suffix string) update {
+	return update{
+		name: "all",
+		update: func(ctx context.Context, c cache.Cache, node string) error {
+			return c.UpdateAllResources(
+				ctx,
+				node,
+				[]*apiv1.Cluster{newCluster("cluster-"+suffix, "")},
+				[]*apiv1.Listener{newListener("listener-" + suffix)},
+				[]*apiv1.Route{newRoute("route-" + suffix)},
+				[]*apiv1.Endpoint{newEndpoint("endpoint-" + suffix)},
+				[]*apiv1.Secret{newSecret("secret-" + suffix)},
+				[]*apiv1.Runtime{newRuntime("runtime-" + suffix)},
+			)
+		},
+		expected: map[resourceType][]string{
+			clusterType:  {"cluster-" + suffix},
+			listenerType: {"listener-" + suffix},
+			routeType:    {"route-" + suffix},
+			endpointType: {"endpoint-" + suffix},
+			secretType:   {"secret-" + suffix},
+			runtimeType:  {"runtime-" + suffix},
+		},
+	}
+}
+
+// updates returns an update of each type.
+func updates() []update {
+	return []update{
+		{
+			name: "clusters",
+			update: func(ctx context.Context, c cache.Cache, node string) error {
+				return c.UpdateClusters(ctx, node, []*apiv1.Cluster{newCluster("cluster-1", ""), newCluster("cluster-2", "")})
+			},
+			expected: map[resourceType][]string{clusterType: {"cluster-1", "cluster-2"}},
+		},
+		{
+			name: "listeners",
+			update: func(ctx context.Context, c cache.Cache, node string) error {
+				return c.UpdateListeners(ctx, node, []*apiv1.Listener{newListener("listener-1")})
+			},
+			expected: map[resourceType][]string{listenerType: {"listener-1"}},
+		},
+		{
+			name: "routes",
+			update: func(ctx context.Context, c cache.Cache, node string) error {
+				return c.UpdateRoutes(ctx, node, []*apiv1.Route{newRoute("route-1")})
+			},
+			expected: map[resourceType][]string{routeType: {"route-1"}},
+		},
+		{
+			name: "endpoints",
+			update: func(ctx context.Context, c cache.Cache, node string) error {
+				return c.UpdateEndpoints(ctx, node, []*apiv1.Endpoint{newEndpoint("endpoint-1")})
+			},
+			expected: map[resourceType][]string{endpointType: {"endpoint-1"}},
+		},
+		{
+			name: "secrets",
+			update: func(ctx context.Context, c cache.Cache, node string) error {
+				return c.UpdateSecrets(ctx, node, []*apiv1.Secret{newSecret("secret-1")})
+			},
+			expected: map[resourceType][]string{secretType: {"secret-1"}},
+		},
+		{
+			name: "runtimes",
+			update: func(ctx context.Context, c cache.Cache, node string) error {
+				return c.UpdateRuntimes(ctx, node, []*apiv1.Runtime{newRuntime("runtime-1")})
+			},
+			expected: map[resourceType][]string{runtimeType: {"runtime-1"}},
+		},
+	}
+}
+
+// permutations returns all orderings of n elements.
+func permutations(n int) [][]int {
+	if n == 0 {
+		return [][]int{{}}
+	}
+
+	var result [][]int
+	for _, p := range permutations(n - 1) {
+		for i := 0; i <= len(p); i++ {
+			q := make([]int, 0, n)
+			q = append(q, p[:i]...)
+			q = append(q, n-1)
+			q = append(q, p[i:]...)
+			result = append(result, q)
+		}
+	}
+
+	return result
+}
+
+func TestCacheUpdateOrderings(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		initial []update
+		final   []update
+	}{
+		"should keep resources of other types on node without resources": {},
+		"should keep resources of other types on node with resources": {
+			initial: []update{updateAll("initial")},
+		},
+		"should replace resources of all types after updates of each type": {
+			final: []update{updateAll("final")},
+		},
+	}
+
+	us := updates()
+	orderings := permutations(len(us))
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, ordering := range orderings {
+				ordering := ordering
+
+				names := make([]string, len(ordering))
+				for i, u := range ordering {
+					names[i] = us[u].name
+				}
+
+				t.Run(strings.Join(names, ","), func(t *testing.T) {
+					t.Parallel()
+
+					ctx := context.Background()
+					snapshotCache := xdscache.NewSnapshotCache(false, xdscache.IDHash{}, nil)
+					c := cache.New(snapshotCache, xdscachev3.NewSnapshotCache(false, xdscachev3.IDHash{}, nil))
+
+					expected := map[resourceType][]string{}
+					for _, u := range test.initial {
+						if err := u.update(ctx, c, "node-1"); err != nil {
+							t.Fatalf("failed to update initial resources: %s", err)
+						}
+
+						for rt, names := range u.expected {
+							expected[rt] = names
+						}
+					}
+
+					steps := make([]update, 0, len(ordering)+len(test.final))
+					for _, i := range ordering {
+						steps = append(steps, us[i])
+					}
+					steps = append(steps, test.final...)
+
+					for _, u := range steps {
+						if err := u.update(ctx, c, "node-1"); err != nil {
+							t.Fatalf("failed to update %s: %s", u.name, err)
+						}
+
+						for rt, names := range u.expected {
+							expected[rt] = names
+						}
+
+						snapshot, err := snapshotCache.GetSnapshot("node-1")
+						if err != nil {
+							t.Fatalf("failed to get snapshot after updating %s: %s", u.name, err)
+						}
+
+						for _, rt := range resourceTypes {
+							if snapshot.GetVersion(rt.typeURL) == "" {
+								t.Errorf("empty version of %s after updating %s", rt.typeURL, u.name)
+							}
+
+							if diff := cmp.Diff(expected[rt], resourceNames(snapshot.GetResources(rt.typeURL))); diff != "" {
+								t.Errorf("%s after updating %s\n(-expected, +actual)\n%s", rt.typeURL, u.name, diff)
+							}
+
+							version, resources := c.GetResources("node-1", rt.typeURLV3)
+							if version == "" {
+								t.Errorf("empty version of %s after updating %s", rt.typeURLV3, u.name)
+							}
+
+							if diff := cmp.Diff(expected[rt], resourceNames(resources)); diff != "" {
+								t.Errorf("%s after updating %s\n(-expected, +actual)\n%s", rt.typeURLV3, u.name, diff)
+							}
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+func resourceNames(resources map[string]types.Resource) []string {
+	var names []string
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestCacheVersions(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		update       func(ctx context.Context, c cache.Cache, node string) error
+		other        func(ctx context.Context, c cache.Cache, node string) error
+		typeURL      string
+		expectedSame bool
+	}{
+		"should derive the same version from the same resources in any order": {
+			update: func(ctx context.Context, c cache.Cache, node string) error {
+				return c.UpdateClusters(ctx, node, []*apiv1.Cluster{newCluster("cluster-1", ""), newCluster("cluster-2", "")})
+			},
+			other: func(ctx context.Context, c cache.Cache, node string) error {
+				return c.UpdateClusters(ctx, node, []*apiv1.Cluster{newCluster("cluster-2", ""), newCluster("cluster-1", "")})
+			},
+			typeURL:      resourcev3.ClusterType,
+			expectedSame: true,
+		},
+		"should derive different versions from different contents": {
+			update: func(ctx context.Context, c cache.Cache, node string) error {
+				return c.UpdateClusters(ctx, node, []*apiv1.Cluster{newCluster("cluster-1", "stat-1")})
+			},
+			other: func(ctx context.Context, c cache.Cache, node string) error {
+				return c.UpdateClusters(ctx, node, []*apiv1.Cluster{newCluster("cluster-1", "stat-2")})
+			},
+			typeURL:      resourcev3.ClusterType,
+			expectedSame: false,
+		},
+		"should not derive versions from resources of other types": {
+			update: func(ctx context.Context, c cache.Cache, node string) error {
+				return c.UpdateClusters(ctx, node, []*apiv1.Cluster{newCluster("cluster-1", "")})
+			},
+			other: func(ctx context.Context, c cache.Cache, node string) error {
+				if err := c.UpdateClusters(ctx, node, []*apiv1.Cluster{newCluster("cluster-1", "")}); err != nil {
+					return err
+				}
+
+				return c.UpdateListeners(ctx, node, []*apiv1.Listener{newListener("listener-1")})
+			},
+			typeURL:      resourcev3.ClusterType,
+			expectedSame: true,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			c := newCache()
+
+			if err := test.update(ctx, c, "node-1"); err != nil {
+				t.Fatalf("failed to update node-1: %s", err)
+			}
+
+			if err := test.other(ctx, c, "node-2"); err != nil {
+				t.Fatalf("failed to update node-2: %s", err)
+			}
+
+			v1, _ := c.GetResources("node-1", test.typeURL)
+			v2, _ := c.GetResources("node-2", test.typeURL)
+
+			if v1 == "" || v2 == "" {
+				t.Fatalf("empty version: %q, %q", v1, v2)
+			}
+
+			if (v1 == v2) != test.expectedSame {
+				t.Errorf("unexpected versions: %q, %q", v1, v2)
+			}
+		})
+	}
+}
+
+func TestCacheUpdateWithoutChanges(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	c := newCache()
+
+	clusters := []*apiv1.Cluster{newCluster("cluster-1", "")}
+	if err := c.UpdateClusters(ctx, "node-1", clusters); err != nil {
+		t.Fatalf("failed to update clusters: %s", err)
+	}
+
+	version, _ := c.GetResources("node-1", resourcev3.ClusterType)
+
+	ch, cancel := c.Watch("node-1")
+	defer cancel()
+
+	if err := c.UpdateClusters(ctx, "node-1", clusters); err != nil {
+		t.Fatalf("failed to update clusters: %s", err)
+	}
+
+	select {
+	case <-ch:
+		t.Fatal("watcher notified without changes")
+	default:
+	}
+
+	if actual, _ := c.GetResources("node-1", resourcev3.ClusterType); actual != version {
+		t.Errorf("unexpected version: %q, expected %q", actual, version)
+	}
+
+	if err := c.UpdateClusters(ctx, "node-1", []*apiv1.Cluster{newCluster("cluster-1", "stat-1")}); err != nil {
+		t.Fatalf("failed to update clusters: %s", err)
+	}
+
+	select {
+	case <-ch:
+	default:
+		t.Fatal("watcher not notified of changes")
+	}
+}
+
+func TestCachePushes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	c := newCache()
+
+	// NOTE: the first update of a node pushes all types since the node has never had them.
+	if err := c.UpdateClusters(ctx, "node-1", []*apiv1.Cluster{newCluster("cluster-1", "")}); err != nil {
+		t.Fatalf("failed to update clusters: %s", err)
+	}
+
+	if err := c.UpdateClusters(ctx, "node-1", []*apiv1.Cluster{newCluster("cluster-1", "")}); err != nil {
+		t.Fatalf("failed to update clusters: %s", err)
+	}
+
+	err := c.Update(ctx, "node-1", func(s *cache.Snapshot) error {
+		s.SetClusters([]*apiv1.Cluster{newCluster("cluster-2", "")})
+		s.SetRoutes(nil)
+		s.SetScopedRoutes(nil)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to update resources: %s", err)
+	}
+
+	expected := map[string]uint64{
+		resourcev3.ClusterType:  2,
+		resourcev3.ListenerType: 1,
+		resourcev3.RouteType:    1,
+		resourcev3.EndpointType: 1,
+		resourcev3.SecretType:   1,
+		resourcev3.RuntimeType:  1,
+		cache.ScopedRouteTypeV3: 1,
+	}
+	if diff := cmp.Diff(expected, c.Pushes()); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+}
+
+func TestCacheConcurrentUpdates(t *testing.T) {
+	t.Parallel()
+
+	const rounds = 100
+
+	ctx := context.Background()
+	snapshotCache := xdscache.NewSnapshotCache(false, xdscache.IDHash{}, nil)
+	c := cache.New(snapshotCache, xdscachev3.NewSnapshotCache(false, xdscachev3.IDHash{}, nil))
+
+	updates := map[resourceType]func(name string) error{
+		clusterType: func(name string) error {
+			return c.UpdateClusters(ctx, "node-1", []*apiv1.Cluster{newCluster(name, "")})
+		},
+		listenerType: func(name string) error {
+			return c.UpdateListeners(ctx, "node-1", []*apiv1.Listener{newListener(name)})
+		},
+		routeType: func(name string) error {
+			return c.UpdateRoutes(ctx, "node-1", []*apiv1.Route{newRoute(name)})
+		},
+		endpointType: func(name string) error {
+			return c.UpdateEndpoints(ctx, "node-1", []*apiv1.Endpoint{newEndpoint(name)})
+		},
+		secretType: func(name string) error {
+			return c.UpdateSecrets(ctx, "node-1", []*apiv1.Secret{newSecret(name)})
+		},
+		runtimeType: func(name string) error {
+			return c.UpdateRuntimes(ctx, "node-1", []*apiv1.Runtime{newRuntime(name)})
+		},
+	}
+
+	for round := 0; round < rounds; round++ {
+		name := fmt.Sprintf("round-%d", round)
+
+		// NOTE: start all updates of the round at once to make them overlap.
+		start := make(chan struct{})
+
+		var wg sync.WaitGroup
+		for rt, update := range updates {
+			rt, update := rt, update
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				<-start
+				if err := update(name); err != nil {
+					t.Errorf("failed to update %s: %s", rt.typeURLV3, err)
+				}
+			}()
+		}
+
+		close(start)
+		wg.Wait()
+
+		snapshot, err := snapshotCache.GetSnapshot("node-1")
+		if err != nil {
+			t.Fatalf("failed to get snapshot: %s", err)
+		}
+
+		for _, rt := range resourceTypes {
+			if diff := cmp.Diff([]string{name}, resourceNames(snapshot.GetResources(rt.typeURL))); diff != "" {
+				t.Fatalf("%s lost\n(-expected, +actual)\n%s", rt.typeURL, diff)
+			}
+
+			_, resources := c.GetResources("node-1", rt.typeURLV3)
+			if diff := cmp.Diff([]string{name}, resourceNames(resources)); diff != "" {
+				t.Fatalf("%s lost\n(-expected, +actual)\n%s", rt.typeURLV3, diff)
+			}
+		}
+	}
+}
+
+func TestCacheUpdateTransaction(t *testing.T) {
+	t.Parallel()
+
+	const (
+		writers = 4
+		n       = 50
+	)
+
+	ctx := context.Background()
+	snapshotCache := xdscache.NewSnapshotCache(false, xdscache.IDHash{}, nil)
+	c := cache.New(snapshotCache, xdscachev3.NewSnapshotCache(false, xdscachev3.IDHash{}, nil))
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		w := w
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < n; i++ {
+				suffix := fmt.Sprintf("%d-%d", w, i)
+				err := c.Update(ctx, "node-1", func(s *cache.Snapshot) error {
+					s.SetClusters([]*apiv1.Cluster{newCluster("cluster-"+suffix, "")})
+					s.SetRoutes([]*apiv1.Route{newRoute("route-" + suffix)})
+					return nil
+				})
+				if err != nil {
+					t.Errorf("failed to update: %s", err)
+					return
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	observed := make(chan error, 1)
+	go func() {
+		defer close(observed)
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			snapshot, err := snapshotCache.GetSnapshot("node-1")
+			if err != nil {
+				continue
+			}
+
+			clusters := resourceNames(snapshot.GetResources(resource.ClusterType))
+			routes := resourceNames(snapshot.GetResources(resource.RouteType))
+			if len(clusters) != 1 || len(routes) != 1 || strings.TrimPrefix(clusters[0], "cluster-") != strings.TrimPrefix(routes[0], "route-") {
+				observed <- fmt.Errorf("observed partial update: clusters %v, routes %v", clusters, routes)
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+
+	if err := <-observed; err != nil {
+		t.Error(err)
+	}
+
+	err := c.Update(ctx, "node-1", func(s *cache.Snapshot) error {
+		s.SetClusters([]*apiv1.Cluster{newCluster("cluster-aborted", "")})
+		return fmt.Errorf("aborted")
+	})
+	if err == nil {
+		t.Fatal("error from the transaction not returned")
+	}
+
+	_, clusters := c.GetResources("node-1", resourcev3.ClusterType)
+	_, routes := c.GetResources("node-1", resourcev3.RouteType)
+	if len(clusters) != 1 || len(routes) != 1 {
+		t.Fatalf("unexpected resources: clusters %v, routes %v", resourceNames(clusters), resourceNames(routes))
+	}
+
+	if strings.TrimPrefix(resourceNames(clusters)[0], "cluster-") != strings.TrimPrefix(resourceNames(routes)[0], "route-") {
+		t.Errorf("clusters and routes updated by different transactions: %v, %v", resourceNames(clusters), resourceNames(routes))
+	}
+}
+
+func TestCacheUpdateLocksNode(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	c := newCache()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	transaction := make(chan error, 1)
+	go func() {
+		transaction <- c.Update(ctx, "node-1", func(s *cache.Snapshot) error {
+			close(entered)
+			<-release
+			s.SetClusters([]*apiv1.Cluster{newCluster("cluster-1", "")})
+			return nil
+		})
+	}()
+
+	<-entered
+
+	if err := c.UpdateRoutes(ctx, "node-2", []*apiv1.Route{newRoute("route-1")}); err != nil {
+		t.Fatalf("failed to update routes of another node: %s", err)
+	}
+
+	routes := make(chan error, 1)
+	go func() {
+		routes <- c.UpdateRoutes(ctx, "node-1", []*apiv1.Route{newRoute("route-1")})
+	}()
+
+	select {
+	case <-routes:
+		t.Fatal("routes updated during the transaction")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+
+	if err := <-transaction; err != nil {
+		t.Fatalf("failed to update in the transaction: %s", err)
+	}
+
+	if err := <-routes; err != nil {
+		t.Fatalf("failed to update routes: %s", err)
+	}
+
+	_, clusters := c.GetResources("node-1", resourcev3.ClusterType)
+	if diff := cmp.Diff([]string{"cluster-1"}, resourceNames(clusters)); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+
+	_, rs := c.GetResources("node-1", resourcev3.RouteType)
+	if diff := cmp.Diff([]string{"route-1"}, resourceNames(rs)); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+}
+
+func TestCacheUpdateIfCached(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		cached           bool
+		cleared          bool
+		expectedUpdated  bool
+		expectedClusters []string
+	}{
+		"should update cached node": {
+			cached:           true,
+			expectedUpdated:  true,
+			expectedClusters: []string{"cluster-1"},
+		},
+		"should not update node which is not cached": {
+			cached:          false,
+			expectedUpdated: false,
+		},
+		"should not update node which has been cleared": {
+			cached:          true,
+			cleared:         true,
+			expectedUpdated: false,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			c := newCache()
+
+			if test.cached {
+				if err := c.UpdateAllResources(ctx, "node-1", nil, nil, nil, nil, nil, nil); err != nil {
+					t.Fatalf("failed to cache node: %s", err)
+				}
+			}
+
+			if test.cleared {
+				c.ClearNode("node-1")
+			}
+
+			updated, err := c.UpdateIfCached(ctx, "node-1", func(s *cache.Snapshot) error {
+				s.SetClusters([]*apiv1.Cluster{newCluster("cluster-1", "")})
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("failed to update: %s", err)
+			}
+
+			if diff := cmp.Diff(test.expectedUpdated, updated); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+
+			if diff := cmp.Diff(test.expectedUpdated, c.IsCachedNode("node-1")); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+
+			_, clusters := c.GetResources("node-1", resourcev3.ClusterType)
+			if diff := cmp.Diff(test.expectedClusters, resourceNames(clusters)); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCacheBeginBuild(t *testing.T) {
+	t.Parallel()
+
+	c := newCache()
+
+	if c.IsBuildingNode("node-1") {
+		t.Fatal("node must not be building before builds begin")
+	}
+
+	// NOTE: the node may be built by multiple streams concurrently.
+	done1 := c.BeginBuild("node-1")
+	done2 := c.BeginBuild("node-1")
+
+	done1()
+	done1()
+	if !c.IsBuildingNode("node-1") {
+		t.Error("node must be building until all builds complete")
+	}
+
+	done2()
+	if c.IsBuildingNode("node-1") {
+		t.Error("node must not be building after all builds complete")
+	}
+}
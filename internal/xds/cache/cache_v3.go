@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"fmt"
+
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	xdscachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+)
+
+// updateSnapshotV3 is the v3 counterpart of updateSnapshot.
+// Since SnapshotCache only responds to state-of-the-world watches, watchers of the node are notified as well.
+func (c *cache) updateSnapshotV3(node string, updates map[types.ResponseType]typeUpdate) error {
+	current, err := c.snapshotCacheV3.GetSnapshot(node)
+	exists := err == nil
+
+	snapshot := current
+	if !exists {
+		for i := range snapshot.Resources {
+			r, err := newResourcesV3(nil)
+			if err != nil {
+				return err
+			}
+			snapshot.Resources[i] = r
+		}
+	}
+
+	for _, u := range updates {
+		r, err := newResourcesV3(u.resourcesV3)
+		if err != nil {
+			return fmt.Errorf("failed to create resources: %w", err)
+		}
+		snapshot.Resources[u.responseType] = r
+	}
+
+	if exists && snapshotVersionsV3(snapshot) == snapshotVersionsV3(current) {
+		return nil
+	}
+
+	if err := c.snapshotCacheV3.SetSnapshot(node, snapshot); err != nil {
+		return err
+	}
+
+	c.nodesMu.Lock()
+	c.nodes[node] = struct{}{}
+	c.nodesMu.Unlock()
+
+	versions := snapshotVersionsV3(snapshot)
+	currentVersions := snapshotVersionsV3(current)
+	for i, typeURL := range typeURLsV3 {
+		if versions[i] != currentVersions[i] {
+			c.countPush(typeURL)
+		}
+	}
+
+	c.resources.notify(node)
+
+	return nil
+}
+
+// typeURLsV3 are the v3 type URLs of the resources in snapshots, indexed by their response types.
+var typeURLsV3 = [types.UnknownType]string{
+	types.Endpoint: resourcev3.EndpointType,
+	types.Cluster:  resourcev3.ClusterType,
+	types.Route:    resourcev3.RouteType,
+	types.Listener: resourcev3.ListenerType,
+	types.Secret:   resourcev3.SecretType,
+	types.Runtime:  resourcev3.RuntimeType,
+}
+
+func newResourcesV3(items []types.Resource) (xdscachev3.Resources, error) {
+	resources := xdscachev3.NewResources("", items)
+
+	version, err := resourcesVersion(resources.Items)
+	if err != nil {
+		return xdscachev3.Resources{}, err
+	}
+	resources.Version = version
+
+	return resources, nil
+}
+
+func snapshotVersionsV3(s xdscachev3.Snapshot) [types.UnknownType]string {
+	var versions [types.UnknownType]string
+	for i, r := range s.Resources {
+		versions[i] = r.Version
+	}
+
+	return versions
+}
@@ -0,0 +1,47 @@
+package cache
+
+import "sync"
+
+// nodeLocks holds a lock of each node which is being updated.
+// Locks are removed once no one holds or waits for them, so that they do not pile up for nodes which have gone.
+type nodeLocks struct {
+	mu    sync.Mutex
+	locks map[string]*nodeLock
+}
+
+type nodeLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newNodeLocks() *nodeLocks {
+	return &nodeLocks{
+		locks: map[string]*nodeLock{},
+	}
+}
+
+// lock acquires the lock of the node, and returns the function to release it.
+func (l *nodeLocks) lock(node string) func() {
+	l.mu.Lock()
+	nl, ok := l.locks[node]
+	if !ok {
+		nl = &nodeLock{}
+		l.locks[node] = nl
+	}
+	nl.refs++
+	l.mu.Unlock()
+
+	nl.mu.Lock()
+
+	return func() {
+		nl.mu.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		nl.refs--
+		if nl.refs == 0 {
+			delete(l.locks, node)
+		}
+	}
+}
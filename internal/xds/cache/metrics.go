@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterMetrics registers the metrics of the cache.
+func RegisterMetrics(registerer prometheus.Registerer, c Cache) error {
+	cachedNodes := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "bootes",
+		Subsystem: "xds",
+		Name:      "cached_nodes",
+		Help:      "Number of nodes whose resources are cached.",
+	}, func() float64 {
+		return float64(c.CachedNodes())
+	})
+
+	for _, collector := range []prometheus.Collector{cachedNodes, newPushesCollector(c)} {
+		if err := registerer.Register(collector); err != nil {
+			return fmt.Errorf("failed to register metrics: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// pushesCollector collects the number of pushes counted by the cache.
+type pushesCollector struct {
+	cache Cache
+	desc  *prometheus.Desc
+}
+
+func newPushesCollector(c Cache) *pushesCollector {
+	return &pushesCollector{
+		cache: c,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName("bootes", "xds", "pushes_total"),
+			"Total number of updates of resources of nodes, each of which is pushed to the node.",
+			[]string{"type_url"},
+			nil,
+		),
+	}
+}
+
+func (c *pushesCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *pushesCollector) Collect(ch chan<- prometheus.Metric) {
+	for typeURL, n := range c.cache.Pushes() {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, float64(n), typeURL)
+	}
+}
+
+// RegisterQueueMetrics registers the metrics of the queue.
+func RegisterQueueMetrics(registerer prometheus.Registerer, q *Queue) error {
+	pendingNodes := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "bootes",
+		Subsystem: "xds",
+		Name:      "pending_nodes",
+		Help:      "Number of nodes which have pending updates.",
+	}, func() float64 {
+		return float64(q.Pending())
+	})
+
+	if err := registerer.Register(pendingNodes); err != nil {
+		return fmt.Errorf("failed to register metrics: %w", err)
+	}
+
+	return nil
+}
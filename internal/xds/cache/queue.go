@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// Queue defers updates of each node and applies them to the cache at once,
+// so that changes of many resources made in a short time, e.g. by a single `kubectl apply`, are pushed to proxies only once.
+// Pending updates of a node are applied when no more updates have been enqueued for the quiet period,
+// or when the max delay has passed since the first pending update was enqueued.
+type Queue struct {
+	cache       Cache
+	quietPeriod time.Duration
+	maxDelay    time.Duration
+	logger      logr.Logger
+
+	mu      sync.Mutex
+	pending map[string]*pendingUpdate
+}
+
+type pendingUpdate struct {
+	snapshot *Snapshot
+	deadline time.Time
+	timer    *time.Timer
+	groups   []*flushGroup
+}
+
+// flushGroup calls onFlushed with the number of the nodes whose updates have been applied, once updates of all the nodes have been flushed.
+type flushGroup struct {
+	mu        sync.Mutex
+	remaining int
+	applied   int
+	onFlushed func(applied int)
+}
+
+func (g *flushGroup) done(applied bool) {
+	g.mu.Lock()
+	g.remaining--
+	if applied {
+		g.applied++
+	}
+	remaining, n := g.remaining, g.applied
+	g.mu.Unlock()
+
+	if remaining == 0 {
+		g.onFlushed(n)
+	}
+}
+
+func NewQueue(c Cache, quietPeriod, maxDelay time.Duration, l logr.Logger) *Queue {
+	return &Queue{
+		cache:       c,
+		quietPeriod: quietPeriod,
+		maxDelay:    maxDelay,
+		logger:      l,
+		pending:     map[string]*pendingUpdate{},
+	}
+}
+
+// Enqueue merges the resources set to the snapshot by fn into the pending update of the node.
+// Resources of the same type enqueued later replace the earlier ones.
+func (q *Queue) Enqueue(node string, fn func(s *Snapshot)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.enqueue(node, fn, nil)
+}
+
+// EnqueueAll enqueues the updates of the nodes, and calls onFlushed with the number of the nodes to which the updates have been applied,
+// once pending updates of all the nodes have been flushed. Updates of nodes which have not been cached by then are not counted.
+// onFlushed is called immediately with zero if there are no updates, and may be nil.
+func (q *Queue) EnqueueAll(updates map[string]func(s *Snapshot), onFlushed func(applied int)) {
+	var g *flushGroup
+	if onFlushed != nil {
+		if len(updates) == 0 {
+			onFlushed(0)
+			return
+		}
+
+		g = &flushGroup{
+			remaining: len(updates),
+			onFlushed: onFlushed,
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for node, fn := range updates {
+		q.enqueue(node, fn, g)
+	}
+}
+
+// enqueue must be called with the lock held.
+func (q *Queue) enqueue(node string, fn func(s *Snapshot), g *flushGroup) {
+	now := time.Now()
+
+	p, ok := q.pending[node]
+	if !ok {
+		p = &pendingUpdate{
+			snapshot: &Snapshot{},
+			deadline: now.Add(q.maxDelay),
+		}
+		q.pending[node] = p
+	}
+
+	fn(p.snapshot)
+	if g != nil {
+		p.groups = append(p.groups, g)
+	}
+
+	delay := q.quietPeriod
+	if d := p.deadline.Sub(now); d < delay {
+		delay = d
+	}
+
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	p.timer = time.AfterFunc(delay, func() {
+		q.flush(node, p)
+	})
+}
+
+// Pending returns the number of nodes which have pending updates.
+func (q *Queue) Pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.pending)
+}
+
+func (q *Queue) flush(node string, p *pendingUpdate) {
+	q.mu.Lock()
+	// NOTE: the update has already been applied by the timer which was stopped too late.
+	if q.pending[node] != p {
+		q.mu.Unlock()
+		return
+	}
+	delete(q.pending, node)
+	q.mu.Unlock()
+
+	// NOTE: resources of nodes which have been evicted meanwhile will be built when they connect again.
+	updated, err := q.cache.UpdateIfCached(context.Background(), node, func(s *Snapshot) error {
+		s.merge(p.snapshot)
+		return nil
+	})
+	if err != nil {
+		q.logger.Error(err, "failed to apply pending updates", "node", node)
+	}
+
+	for _, g := range p.groups {
+		g.done(updated && err == nil)
+	}
+}
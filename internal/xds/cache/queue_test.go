@@ -0,0 +1,221 @@
+package cache_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/go-logr/logr"
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	apiv1 "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/xds/cache"
+)
+
+// countingCache counts transactions applied to the cache.
+type countingCache struct {
+	cache.Cache
+
+	mu      sync.Mutex
+	updates int
+}
+
+func (c *countingCache) Update(ctx context.Context, node string, fn func(s *cache.Snapshot) error) error {
+	c.mu.Lock()
+	c.updates++
+	c.mu.Unlock()
+
+	return c.Cache.Update(ctx, node, fn)
+}
+
+func (c *countingCache) UpdateIfCached(ctx context.Context, node string, fn func(s *cache.Snapshot) error) (bool, error) {
+	updated, err := c.Cache.UpdateIfCached(ctx, node, fn)
+	if updated {
+		c.mu.Lock()
+		c.updates++
+		c.mu.Unlock()
+	}
+
+	return updated, err
+}
+
+func (c *countingCache) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.updates
+}
+
+func waitPending(t *testing.T, q *cache.Queue) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for q.Pending() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("pending updates not applied")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// NOTE: wait for the last update since it is applied after it has been removed from the queue.
+	time.Sleep(10 * time.Millisecond)
+}
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		quietPeriod      time.Duration
+		maxDelay         time.Duration
+		cached           bool
+		enqueue          func(q *cache.Queue)
+		expectedUpdates  func(updates int) bool
+		expectedClusters []string
+		expectedRoutes   []string
+	}{
+		"should merge updates enqueued within the quiet period": {
+			quietPeriod: 100 * time.Millisecond,
+			maxDelay:    10 * time.Second,
+			cached:      true,
+			enqueue: func(q *cache.Queue) {
+				q.Enqueue("node-1", func(s *cache.Snapshot) {
+					s.SetClusters([]*apiv1.Cluster{newCluster("cluster-1", "")})
+				})
+				q.Enqueue("node-1", func(s *cache.Snapshot) {
+					s.SetRoutes([]*apiv1.Route{newRoute("route-1")})
+				})
+				q.Enqueue("node-1", func(s *cache.Snapshot) {
+					s.SetClusters([]*apiv1.Cluster{newCluster("cluster-2", "")})
+				})
+			},
+			expectedUpdates: func(updates int) bool {
+				return updates == 1
+			},
+			expectedClusters: []string{"cluster-2"},
+			expectedRoutes:   []string{"route-1"},
+		},
+		"should apply updates after the max delay even if updates keep being enqueued": {
+			quietPeriod: 100 * time.Millisecond,
+			maxDelay:    200 * time.Millisecond,
+			cached:      true,
+			enqueue: func(q *cache.Queue) {
+				for i := 0; i < 20; i++ {
+					q.Enqueue("node-1", func(s *cache.Snapshot) {
+						s.SetClusters([]*apiv1.Cluster{newCluster("cluster-1", "")})
+					})
+					time.Sleep(50 * time.Millisecond)
+				}
+			},
+			expectedUpdates: func(updates int) bool {
+				return updates >= 2
+			},
+			expectedClusters: []string{"cluster-1"},
+		},
+		"should drop updates of nodes which are not cached": {
+			quietPeriod: 10 * time.Millisecond,
+			maxDelay:    100 * time.Millisecond,
+			cached:      false,
+			enqueue: func(q *cache.Queue) {
+				q.Enqueue("node-1", func(s *cache.Snapshot) {
+					s.SetClusters([]*apiv1.Cluster{newCluster("cluster-1", "")})
+				})
+			},
+			expectedUpdates: func(updates int) bool {
+				return updates == 0
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &countingCache{Cache: newCache()}
+			if test.cached {
+				if err := c.Cache.UpdateAllResources(context.Background(), "node-1", nil, nil, nil, nil, nil, nil); err != nil {
+					t.Fatalf("failed to cache node: %s", err)
+				}
+			}
+
+			q := cache.NewQueue(c, test.quietPeriod, test.maxDelay, logr.Logger(log.NullLogger{}))
+			test.enqueue(q)
+			waitPending(t, q)
+
+			if updates := c.count(); !test.expectedUpdates(updates) {
+				t.Errorf("unexpected number of updates: %d", updates)
+			}
+
+			_, clusters := c.GetResources("node-1", resourcev3.ClusterType)
+			if diff := cmp.Diff(test.expectedClusters, resourceNames(clusters)); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+
+			_, routes := c.GetResources("node-1", resourcev3.RouteType)
+			if diff := cmp.Diff(test.expectedRoutes, resourceNames(routes)); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestQueueEnqueueAll(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		nodes    []string
+		expected int
+	}{
+		"should count only nodes to which updates have been applied": {
+			nodes:    []string{"node-1", "node-2"},
+			expected: 1,
+		},
+		"should call back immediately without updates": {
+			nodes:    []string{},
+			expected: 0,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := newCache()
+			if err := c.UpdateAllResources(context.Background(), "node-1", nil, nil, nil, nil, nil, nil); err != nil {
+				t.Fatalf("failed to cache node: %s", err)
+			}
+
+			updates := map[string]func(s *cache.Snapshot){}
+			for _, node := range test.nodes {
+				updates[node] = func(s *cache.Snapshot) {
+					s.SetClusters([]*apiv1.Cluster{newCluster("cluster-1", "")})
+				}
+			}
+
+			flushed := make(chan int, 1)
+			q := cache.NewQueue(c, 10*time.Millisecond, 100*time.Millisecond, logr.Logger(log.NullLogger{}))
+			q.EnqueueAll(updates, func(applied int) {
+				_, clusters := c.GetResources("node-1", resourcev3.ClusterType)
+				if len(test.nodes) != 0 && len(clusters) == 0 {
+					t.Error("called back before updates have been applied")
+				}
+
+				flushed <- applied
+			})
+
+			select {
+			case applied := <-flushed:
+				if diff := cmp.Diff(test.expected, applied); diff != "" {
+					t.Errorf("\n(-expected, +actual)\n%s", diff)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("not called back")
+			}
+		})
+	}
+}
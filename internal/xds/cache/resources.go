@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/golang/protobuf/proto"
+
+	apiv1 "github.com/110y/bootes/internal/k8s/api/v1"
+)
+
+// Type URLs of the resources which are only served over the incremental xDS protocol since SnapshotCache does not support them.
+const (
+	ScopedRouteTypeV3 = "type.googleapis.com/envoy.config.route.v3.ScopedRouteConfiguration"
+	VirtualHostTypeV3 = "type.googleapis.com/envoy.config.route.v3.VirtualHost"
+)
+
+// resourceStore holds per node resources which SnapshotCache does not support, and watchers of v3 resources of each node.
+type resourceStore struct {
+	mu        sync.RWMutex
+	nodes     map[string]*nodeResources
+	nextWatch int64
+}
+
+type nodeResources struct {
+	versions  map[string]string
+	resources map[string]map[string]types.Resource
+	watches   map[int64]chan struct{}
+}
+
+func newResourceStore() *resourceStore {
+	return &resourceStore{
+		nodes: map[string]*nodeResources{},
+	}
+}
+
+// set replaces all resources of the typeURL for the node and notifies watchers of the node, and returns whether they have been replaced.
+// Nothing happens when the node already has the resources of the version.
+func (s *resourceStore) set(node, typeURL, version string, resources map[string]types.Resource) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.getOrCreateNode(node)
+	if current, ok := n.versions[typeURL]; ok && current == version {
+		return false
+	}
+
+	n.versions[typeURL] = version
+	n.resources[typeURL] = resources
+
+	n.notify()
+
+	return true
+}
+
+// notify notifies watchers of the node that its resources have been updated.
+func (s *resourceStore) notify(node string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if n, ok := s.nodes[node]; ok {
+		n.notify()
+	}
+}
+
+// clear removes all resources of the node.
+// The node is kept while it is watched so that the watchers are notified of the removal.
+func (s *resourceStore) clear(node string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := s.nodes[node]
+	if !ok {
+		return
+	}
+
+	if len(n.watches) == 0 {
+		delete(s.nodes, node)
+		return
+	}
+
+	n.versions = map[string]string{}
+	n.resources = map[string]map[string]types.Resource{}
+
+	n.notify()
+}
+
+func (s *resourceStore) get(node, typeURL string) (string, map[string]types.Resource) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n, ok := s.nodes[node]
+	if !ok {
+		return "", map[string]types.Resource{}
+	}
+
+	resources := make(map[string]types.Resource, len(n.resources[typeURL]))
+	for name, r := range n.resources[typeURL] {
+		resources[name] = r
+	}
+
+	return n.versions[typeURL], resources
+}
+
+func (s *resourceStore) watch(node string) (<-chan struct{}, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.getOrCreateNode(node)
+
+	id := s.nextWatch
+	s.nextWatch++
+
+	ch := make(chan struct{}, 1)
+	n.watches[id] = ch
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(n.watches, id)
+	}
+}
+
+func (s *resourceStore) getOrCreateNode(node string) *nodeResources {
+	n, ok := s.nodes[node]
+	if !ok {
+		n = &nodeResources{
+			versions:  map[string]string{},
+			resources: map[string]map[string]types.Resource{},
+			watches:   map[int64]chan struct{}{},
+		}
+		s.nodes[node] = n
+	}
+
+	return n
+}
+
+func (n *nodeResources) notify() {
+	for _, w := range n.watches {
+		select {
+		case w <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func scopedRouteResources(scopedRoutes []*apiv1.ScopedRoute) map[string]types.Resource {
+	resources := make(map[string]types.Resource, len(scopedRoutes))
+	for _, sr := range scopedRoutes {
+		resources[sr.Spec.ConfigV3.GetName()] = sr.Spec.ConfigV3
+	}
+
+	return resources
+}
+
+// virtualHostResources names each virtual host as `<route configuration name>/<virtual host name>`
+// since Envoy removes virtual hosts fetched via VHDS by their names.
+func virtualHostResources(virtualHosts []*apiv1.VirtualHost) map[string]types.Resource {
+	resources := make(map[string]types.Resource, len(virtualHosts))
+	for _, vh := range virtualHosts {
+		name := VirtualHostResourceName(vh.Spec.RouteConfigurationName, vh.Spec.ConfigV3.GetName())
+
+		config := vh.Spec.ConfigV3
+		if config.GetName() != name {
+			config = proto.Clone(config).(*routev3.VirtualHost)
+			config.Name = name
+		}
+
+		resources[name] = config
+	}
+
+	return resources
+}
+
+// VirtualHostResourceName returns the VHDS resource name of the virtual host which belongs to the route configuration.
+func VirtualHostResourceName(routeConfigurationName, virtualHostName string) string {
+	prefix := routeConfigurationName + "/"
+	if strings.HasPrefix(virtualHostName, prefix) {
+		return virtualHostName
+	}
+
+	return prefix + virtualHostName
+}
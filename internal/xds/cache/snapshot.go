@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+
+	apiv1 "github.com/110y/bootes/internal/k8s/api/v1"
+)
+
+// Snapshot collects resources of a node which Update replaces at once.
+// When resources of the same type are set more than once, the last ones win.
+type Snapshot struct {
+	updates   map[types.ResponseType]typeUpdate
+	resources map[string]map[string]types.Resource
+}
+
+func (s *Snapshot) SetClusters(clusters []*apiv1.Cluster) {
+	s.setType(clusterUpdate(clusters))
+}
+
+func (s *Snapshot) SetListeners(listeners []*apiv1.Listener) {
+	s.setType(listenerUpdate(listeners))
+}
+
+func (s *Snapshot) SetRoutes(routes []*apiv1.Route) {
+	s.setType(routeUpdate(routes))
+}
+
+func (s *Snapshot) SetEndpoints(endpoints []*apiv1.Endpoint) {
+	s.setType(endpointUpdate(endpoints))
+}
+
+func (s *Snapshot) SetSecrets(secrets []*apiv1.Secret) {
+	s.setType(secretUpdate(secrets))
+}
+
+func (s *Snapshot) SetRuntimes(runtimes []*apiv1.Runtime) {
+	s.setType(runtimeUpdate(runtimes))
+}
+
+func (s *Snapshot) SetScopedRoutes(scopedRoutes []*apiv1.ScopedRoute) {
+	s.setResources(ScopedRouteTypeV3, scopedRouteResources(scopedRoutes))
+}
+
+func (s *Snapshot) SetVirtualHosts(virtualHosts []*apiv1.VirtualHost) {
+	s.setResources(VirtualHostTypeV3, virtualHostResources(virtualHosts))
+}
+
+func (s *Snapshot) setType(u typeUpdate) {
+	if s.updates == nil {
+		s.updates = map[types.ResponseType]typeUpdate{}
+	}
+
+	s.updates[u.responseType] = u
+}
+
+func (s *Snapshot) setResources(typeURL string, resources map[string]types.Resource) {
+	if s.resources == nil {
+		s.resources = map[string]map[string]types.Resource{}
+	}
+
+	s.resources[typeURL] = resources
+}
+
+// merge overwrites resources of the snapshot by the ones set to the other.
+func (s *Snapshot) merge(other *Snapshot) {
+	for _, u := range other.updates {
+		s.setType(u)
+	}
+
+	for typeURL, resources := range other.resources {
+		s.setResources(typeURL, resources)
+	}
+}
+
+// typeUpdate holds the resources of a type served by SnapshotCache, which replace the current ones of a node.
+type typeUpdate struct {
+	responseType types.ResponseType
+	resources    []types.Resource
+	resourcesV3  []types.Resource
+}
+
+func clusterUpdate(clusters []*apiv1.Cluster) typeUpdate {
+	u := typeUpdate{
+		responseType: types.Cluster,
+		resources:    make([]types.Resource, len(clusters)),
+		resourcesV3:  make([]types.Resource, len(clusters)),
+	}
+
+	for i, c := range clusters {
+		u.resources[i] = c.Spec.Config
+		u.resourcesV3[i] = c.Spec.ConfigV3
+	}
+
+	return u
+}
+
+func listenerUpdate(listeners []*apiv1.Listener) typeUpdate {
+	u := typeUpdate{
+		responseType: types.Listener,
+		resources:    make([]types.Resource, len(listeners)),
+		resourcesV3:  make([]types.Resource, len(listeners)),
+	}
+
+	for i, l := range listeners {
+		u.resources[i] = l.Spec.Config
+		u.resourcesV3[i] = l.Spec.ConfigV3
+	}
+
+	return u
+}
+
+func routeUpdate(routes []*apiv1.Route) typeUpdate {
+	u := typeUpdate{
+		responseType: types.Route,
+		resources:    make([]types.Resource, len(routes)),
+		resourcesV3:  make([]types.Resource, len(routes)),
+	}
+
+	for i, r := range routes {
+		u.resources[i] = r.Spec.Config
+		u.resourcesV3[i] = r.Spec.ConfigV3
+	}
+
+	return u
+}
+
+func endpointUpdate(endpoints []*apiv1.Endpoint) typeUpdate {
+	u := typeUpdate{
+		responseType: types.Endpoint,
+		resources:    make([]types.Resource, len(endpoints)),
+		resourcesV3:  make([]types.Resource, len(endpoints)),
+	}
+
+	for i, e := range endpoints {
+		u.resources[i] = e.Spec.Config
+		u.resourcesV3[i] = e.Spec.ConfigV3
+	}
+
+	return u
+}
+
+func secretUpdate(secrets []*apiv1.Secret) typeUpdate {
+	u := typeUpdate{
+		responseType: types.Secret,
+		resources:    make([]types.Resource, len(secrets)),
+		resourcesV3:  make([]types.Resource, len(secrets)),
+	}
+
+	for i, s := range secrets {
+		u.resources[i] = s.Spec.Config
+		u.resourcesV3[i] = s.Spec.ConfigV3
+	}
+
+	return u
+}
+
+func runtimeUpdate(runtimes []*apiv1.Runtime) typeUpdate {
+	u := typeUpdate{
+		responseType: types.Runtime,
+		resources:    make([]types.Resource, len(runtimes)),
+		resourcesV3:  make([]types.Resource, len(runtimes)),
+	}
+
+	for i, r := range runtimes {
+		u.resources[i] = r.Spec.Config
+		u.resourcesV3[i] = r.Spec.ConfigV3
+	}
+
+	return u
+}
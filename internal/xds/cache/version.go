@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/golang/protobuf/proto"
+	protov2 "google.golang.org/protobuf/proto"
+)
+
+// resourcesVersion derives the version of the resources from their names and contents,
+// so that the same resources always have the same version no matter when or by which replica they are built.
+func resourcesVersion(resources map[string]types.Resource) (string, error) {
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		b, err := marshalResource(resources[name])
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal resource %s: %w", name, err)
+		}
+
+		sum := sha256.Sum256(b)
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write(sum[:])
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// ConfigVersion returns the version of the configuration derived from its contents.
+func ConfigVersion(config types.Resource) (string, error) {
+	b, err := marshalResource(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal configuration: %w", err)
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(b)), nil
+}
+
+func marshalResource(r types.Resource) ([]byte, error) {
+	return protov2.MarshalOptions{Deterministic: true}.Marshal(proto.MessageV2(r))
+}
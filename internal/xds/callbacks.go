@@ -4,23 +4,46 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	envoyapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
 	server "github.com/envoyproxy/go-control-plane/pkg/server/v2"
 	"github.com/go-logr/logr"
-	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/status"
 
-	api "github.com/110y/bootes/internal/k8s/api/v1"
 	"github.com/110y/bootes/internal/k8s/store"
 	"github.com/110y/bootes/internal/observer/trace"
+	"github.com/110y/bootes/internal/xds/ack"
 	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/snapshot"
+	"github.com/110y/bootes/internal/xds/workload"
 )
 
 var _ server.Callbacks = (*callbacks)(nil)
 
+// Names of servers which share callbacks, used to distinguish their streams since IDs of streams are unique only within each server.
+const (
+	serverV2    = "v2"
+	serverV3    = "v3"
+	serverDelta = "delta"
+)
+
+// NACKReporter reports changes of acceptance of resources by nodes.
+type NACKReporter interface {
+	ReportRejected(ctx context.Context, node, typeURL, message string) error
+	ReportRecovered(ctx context.Context, node, typeURL string) error
+}
+
 type callbacks struct {
+	server                 string
 	cache                  cache.Cache
-	store                  store.Store
+	builder                *snapshot.Builder
+	resolver               *workload.Resolver
+	tracker                *ack.Tracker
+	reports                *reportQueue
+	streams                *nodeStreams
+	metrics                *metrics
+	loggerNACK             logr.Logger
 	loggerOnStreamOpen     logr.Logger
 	loggerOnStreamClosed   logr.Logger
 	loggerOnStreamRequest  logr.Logger
@@ -29,10 +52,17 @@ type callbacks struct {
 	loggerOnFetchResponse  logr.Logger
 }
 
-func newCallbacks(c cache.Cache, s store.Store, l logr.Logger) *callbacks {
+func newCallbacks(c cache.Cache, s store.Store, wr *workload.Resolver, t *ack.Tracker, r NACKReporter, m *metrics, evictionGracePeriod time.Duration, l logr.Logger) *callbacks {
 	return &callbacks{
+		server:                 serverV2,
 		cache:                  c,
-		store:                  s,
+		builder:                snapshot.NewBuilder(s, c),
+		resolver:               wr,
+		tracker:                t,
+		reports:                newReportQueue(r),
+		streams:                newNodeStreams(c, wr, t, m, evictionGracePeriod, l.WithName("node_streams")),
+		metrics:                m,
+		loggerNACK:             l.WithName("nack"),
 		loggerOnStreamOpen:     l.WithName("on_stream_open"),
 		loggerOnStreamClosed:   l.WithName("on_stream_closed"),
 		loggerOnStreamRequest:  l.WithName("on_stream_request"),
@@ -42,6 +72,21 @@ func newCallbacks(c cache.Cache, s store.Store, l logr.Logger) *callbacks {
 	}
 }
 
+// forServer returns the callbacks for the server, which share states with the original ones.
+func (c *callbacks) forServer(server string) *callbacks {
+	cb := *c
+	cb.server = server
+
+	return &cb
+}
+
+func (c *callbacks) streamID(id int64) ack.StreamID {
+	return ack.StreamID{
+		Server: c.server,
+		ID:     id,
+	}
+}
+
 func (c *callbacks) OnStreamOpen(_ context.Context, streamID int64, _ string) error {
 	streamLogger(c.loggerOnStreamOpen, streamID).Info("open")
 	return nil
@@ -49,68 +94,63 @@ func (c *callbacks) OnStreamOpen(_ context.Context, streamID int64, _ string) er
 
 func (c *callbacks) OnStreamClosed(streamID int64) {
 	streamLogger(c.loggerOnStreamClosed, streamID).Info("closed")
+	c.tracker.OnStreamClosed(c.streamID(streamID))
+	c.streams.onStreamClosed(c.streamID(streamID))
+	c.metrics.onStreamClosed(c.streamID(streamID))
 }
 
 func (c *callbacks) OnStreamRequest(streamID int64, req *envoyapi.DiscoveryRequest) error {
 	ctx, span := trace.NewSpan(context.Background(), "Callbacks.OnStreamRequest")
 	defer span.End()
 
-	version := uuid.New().String()
-	logger := requestLogger(streamLogger(c.loggerOnStreamRequest, streamID), req).WithValues("version", version)
+	logger := requestLogger(streamLogger(c.loggerOnStreamRequest, streamID), req.VersionInfo, req.GetNode().GetId())
 
-	node := req.GetNode().GetId()
-	if node == "" {
-		logger.Info("empty node id passed")
-		return fmt.Errorf("empty node id")
-	}
+	node := c.resolver.NodeKey(req.GetNode().GetId())
+	c.streams.onRequest(c.streamID(streamID), node)
+	c.metrics.onStreamRequest(c.streamID(streamID), node, req.GetTypeUrl())
+	c.trackRequest(streamID, node, req.GetTypeUrl(), req.GetResponseNonce(), req.GetErrorDetail())
 
-	if c.cache.IsCachedNode(node) {
-		// NOTE: use cache, no need to fetch resources again.
-		return nil
-	}
+	return c.onStreamRequest(ctx, req.GetNode(), logger)
+}
 
-	name, namespace := store.ToNamespacedName(node)
+// trackRequest tracks whether the node has accepted the response replied by the request,
+// and reports it asynchronously in order so as not to block the stream when its acceptance has changed.
+func (c *callbacks) trackRequest(streamID int64, node, typeURL, nonce string, errorDetail *status.Status) {
+	logger := streamLogger(c.loggerNACK, streamID).WithValues("node", node, "type", typeURL)
 
-	pod, err := c.store.GetPod(ctx, name, namespace)
-	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			logger.Info("pod not found by node id")
-			return fmt.Errorf("pod not found by node id")
-		}
-		logger.Error(err, "failed to get pod")
-		return fmt.Errorf("failed to get pod: %w", err)
-	}
-
-	clusters, err := c.listClustersByNodeAndLabels(ctx, namespace, pod.Labels)
-	if err != nil {
-		msg := "failed to list cluster configurations"
-		logger.Error(err, msg)
-		return fmt.Errorf("%s: %w", msg, err)
+	switch c.tracker.OnRequest(c.streamID(streamID), node, typeURL, nonce, errorDetail) {
+	case ack.TransitionRejected:
+		logger.Info("response rejected", "error", errorDetail.GetMessage())
+		c.reports.enqueue(report{node: node, typeURL: typeURL, message: errorDetail.GetMessage(), rejected: true, logger: logger})
+	case ack.TransitionRecovered:
+		logger.Info("response accepted after rejection")
+		c.reports.enqueue(report{node: node, typeURL: typeURL, logger: logger})
 	}
+}
 
-	listeners, err := c.listListenersByNodeAndLabels(ctx, namespace, pod.Labels)
-	if err != nil {
-		msg := "failed to list listener configurations"
-		logger.Error(err, msg)
-		return fmt.Errorf("%s: %w", msg, err)
+func (c *callbacks) onStreamRequest(ctx context.Context, node workload.Node, logger logr.Logger) error {
+	if node.GetId() == "" {
+		logger.Info("empty node id passed")
+		return fmt.Errorf("empty node id")
 	}
 
-	routes, err := c.listRoutesByNodeAndLabels(ctx, namespace, pod.Labels)
-	if err != nil {
-		msg := "failed to list route configurations"
-		logger.Error(err, msg)
-		return fmt.Errorf("%s: %w", msg, err)
+	if c.cache.IsCachedNode(c.resolver.NodeKey(node.GetId())) {
+		// NOTE: use cache, no need to fetch resources again.
+		return nil
 	}
 
-	endpoints, err := c.listEndpointsByNodeAndLabels(ctx, namespace, pod.Labels)
+	w, err := c.resolver.Resolve(ctx, node)
 	if err != nil {
-		msg := "failed to list endpoint configurations"
-		logger.Error(err, msg)
-		return fmt.Errorf("%s: %w", msg, err)
+		if errors.Is(err, workload.ErrNotFound) {
+			logger.Info("workload not found by node id")
+			return err
+		}
+		logger.Error(err, "failed to resolve workload")
+		return fmt.Errorf("failed to resolve workload: %w", err)
 	}
 
-	if err := c.cache.UpdateAllResources(ctx, node, version, clusters, listeners, routes, endpoints); err != nil {
-		msg := "failed to update resources"
+	if err := c.builder.Build(ctx, w.Node, w.Namespace, w.Labels); err != nil {
+		msg := "failed to build resources"
 		logger.Error(err, msg)
 		return fmt.Errorf("%s: %w", msg, err)
 	}
@@ -118,67 +158,35 @@ func (c *callbacks) OnStreamRequest(streamID int64, req *envoyapi.DiscoveryReque
 	return nil
 }
 
-func (c *callbacks) OnStreamResponse(streamID int64, req *envoyapi.DiscoveryRequest, _ *envoyapi.DiscoveryResponse) {
-	streamRequestLog(c.loggerOnStreamResponse, streamID, req)
+func (c *callbacks) OnStreamResponse(streamID int64, req *envoyapi.DiscoveryRequest, resp *envoyapi.DiscoveryResponse) {
+	streamRequestLog(c.loggerOnStreamResponse, streamID, req.VersionInfo, req.GetNode().GetId())
+	c.tracker.OnResponse(c.streamID(streamID), resp.GetNonce(), resp.GetVersionInfo())
+	c.metrics.onResponse(c.resolver.NodeKey(req.GetNode().GetId()), resp.GetTypeUrl(), resp)
 }
 
 func (c callbacks) OnFetchRequest(_ context.Context, req *envoyapi.DiscoveryRequest) error {
-	requestLog(c.loggerOnFetchRequest, req)
+	requestLog(c.loggerOnFetchRequest, req.VersionInfo, req.GetNode().GetId())
+	c.metrics.onRequest(c.resolver.NodeKey(req.GetNode().GetId()))
 	return nil
 }
 
-func (c *callbacks) OnFetchResponse(req *envoyapi.DiscoveryRequest, _ *envoyapi.DiscoveryResponse) {
-	requestLog(c.loggerOnFetchResponse, req)
-}
-
-func (c *callbacks) listClustersByNodeAndLabels(ctx context.Context, namespace string, labels map[string]string) ([]*api.Cluster, error) {
-	clusters, err := c.store.ListClustersByNamespace(ctx, namespace)
-	if err != nil {
-		return nil, err
-	}
-
-	return store.FilterClustersByLabels(clusters.Items, labels), nil
-}
-
-func (c *callbacks) listListenersByNodeAndLabels(ctx context.Context, namespace string, labels map[string]string) ([]*api.Listener, error) {
-	listeners, err := c.store.ListListenersByNamespace(ctx, namespace)
-	if err != nil {
-		return nil, err
-	}
-
-	return store.FilterListenersByLabels(listeners.Items, labels), nil
-}
-
-func (c *callbacks) listRoutesByNodeAndLabels(ctx context.Context, namespace string, labels map[string]string) ([]*api.Route, error) {
-	routes, err := c.store.ListRoutesByNamespace(ctx, namespace)
-	if err != nil {
-		return nil, err
-	}
-
-	return store.FilterRoutesByLabels(routes.Items, labels), nil
-}
-
-func (c *callbacks) listEndpointsByNodeAndLabels(ctx context.Context, namespace string, labels map[string]string) ([]*api.Endpoint, error) {
-	endpoints, err := c.store.ListEndpointsByNamespace(ctx, namespace)
-	if err != nil {
-		return nil, err
-	}
-
-	return store.FilterEndpointsByLabels(endpoints.Items, labels), nil
+func (c *callbacks) OnFetchResponse(req *envoyapi.DiscoveryRequest, resp *envoyapi.DiscoveryResponse) {
+	requestLog(c.loggerOnFetchResponse, req.VersionInfo, req.GetNode().GetId())
+	c.metrics.onResponse(c.resolver.NodeKey(req.GetNode().GetId()), resp.GetTypeUrl(), resp)
 }
 
 func streamLogger(l logr.Logger, id int64) logr.Logger {
 	return l.WithValues("stream", id)
 }
 
-func streamRequestLog(l logr.Logger, id int64, req *envoyapi.DiscoveryRequest) {
-	requestLog(streamLogger(l, id), req)
+func streamRequestLog(l logr.Logger, id int64, currentVersion, node string) {
+	requestLog(streamLogger(l, id), currentVersion, node)
 }
 
-func requestLogger(l logr.Logger, req *envoyapi.DiscoveryRequest) logr.Logger {
-	return l.WithValues("current_version", req.VersionInfo, "node", req.GetNode().GetId())
+func requestLogger(l logr.Logger, currentVersion, node string) logr.Logger {
+	return l.WithValues("current_version", currentVersion, "node", node)
 }
 
-func requestLog(l logr.Logger, req *envoyapi.DiscoveryRequest) {
-	requestLogger(l, req).Info("request")
+func requestLog(l logr.Logger, currentVersion, node string) {
+	requestLogger(l, currentVersion, node).Info("request")
 }
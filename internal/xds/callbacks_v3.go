@@ -0,0 +1,78 @@
+package xds
+
+import (
+	"context"
+
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	serverv3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
+
+	"github.com/110y/bootes/internal/observer/trace"
+	"github.com/110y/bootes/internal/xds/internal/delta"
+)
+
+var (
+	_ serverv3.Callbacks = (*callbacksV3)(nil)
+	_ delta.Callbacks    = (*callbacksV3)(nil)
+)
+
+// callbacksV3 is the xDS v3 counterpart of callbacks, which shares its stream handling with the v2 one.
+type callbacksV3 struct {
+	*callbacks
+}
+
+func newCallbacksV3(c *callbacks) *callbacksV3 {
+	return &callbacksV3{
+		callbacks: c,
+	}
+}
+
+func (c *callbacksV3) OnStreamRequest(streamID int64, req *discoveryv3.DiscoveryRequest) error {
+	ctx, span := trace.NewSpan(context.Background(), "CallbacksV3.OnStreamRequest")
+	defer span.End()
+
+	logger := requestLogger(streamLogger(c.loggerOnStreamRequest, streamID), req.VersionInfo, req.GetNode().GetId())
+
+	node := c.resolver.NodeKey(req.GetNode().GetId())
+	c.streams.onRequest(c.streamID(streamID), node)
+	c.metrics.onStreamRequest(c.streamID(streamID), node, req.GetTypeUrl())
+	c.trackRequest(streamID, node, req.GetTypeUrl(), req.GetResponseNonce(), req.GetErrorDetail())
+
+	return c.onStreamRequest(ctx, req.GetNode(), logger)
+}
+
+func (c *callbacksV3) OnStreamResponse(streamID int64, req *discoveryv3.DiscoveryRequest, resp *discoveryv3.DiscoveryResponse) {
+	streamRequestLog(c.loggerOnStreamResponse, streamID, req.VersionInfo, req.GetNode().GetId())
+	c.tracker.OnResponse(c.streamID(streamID), resp.GetNonce(), resp.GetVersionInfo())
+	c.metrics.onResponse(c.resolver.NodeKey(req.GetNode().GetId()), resp.GetTypeUrl(), resp)
+}
+
+func (c *callbacksV3) OnFetchRequest(_ context.Context, req *discoveryv3.DiscoveryRequest) error {
+	requestLog(c.loggerOnFetchRequest, req.VersionInfo, req.GetNode().GetId())
+	c.metrics.onRequest(c.resolver.NodeKey(req.GetNode().GetId()))
+	return nil
+}
+
+func (c *callbacksV3) OnFetchResponse(req *discoveryv3.DiscoveryRequest, resp *discoveryv3.DiscoveryResponse) {
+	requestLog(c.loggerOnFetchResponse, req.VersionInfo, req.GetNode().GetId())
+	c.metrics.onResponse(c.resolver.NodeKey(req.GetNode().GetId()), resp.GetTypeUrl(), resp)
+}
+
+func (c *callbacksV3) OnStreamDeltaRequest(streamID int64, req *discoveryv3.DeltaDiscoveryRequest) error {
+	ctx, span := trace.NewSpan(context.Background(), "CallbacksV3.OnStreamDeltaRequest")
+	defer span.End()
+
+	logger := requestLogger(streamLogger(c.loggerOnStreamRequest, streamID), "", req.GetNode().GetId()).WithValues("type", req.GetTypeUrl())
+
+	node := c.resolver.NodeKey(req.GetNode().GetId())
+	c.streams.onRequest(c.streamID(streamID), node)
+	c.metrics.onStreamRequest(c.streamID(streamID), node, req.GetTypeUrl())
+	c.trackRequest(streamID, node, req.GetTypeUrl(), req.GetResponseNonce(), req.GetErrorDetail())
+
+	return c.onStreamRequest(ctx, req.GetNode(), logger)
+}
+
+func (c *callbacksV3) OnStreamDeltaResponse(streamID int64, req *discoveryv3.DeltaDiscoveryRequest, resp *discoveryv3.DeltaDiscoveryResponse) {
+	streamRequestLog(c.loggerOnStreamResponse, streamID, resp.GetSystemVersionInfo(), req.GetNode().GetId())
+	c.tracker.OnResponse(c.streamID(streamID), resp.GetNonce(), resp.GetSystemVersionInfo())
+	c.metrics.onResponse(c.resolver.NodeKey(req.GetNode().GetId()), resp.GetTypeUrl(), resp)
+}
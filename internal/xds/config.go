@@ -1,9 +1,26 @@
 package xds
 
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/110y/bootes/internal/xds/nodeid"
+)
+
 type Config struct {
 	Port                 int
 	EnableGRPCChannelz   bool
 	EnableGRPCReflection bool
+
+	// NodeEvictionGracePeriod is the period after which resources of a node are evicted from the cache once all of its streams have been closed.
+	NodeEvictionGracePeriod time.Duration
+
+	// NodeIDFormat converts node IDs into the keys by which resources of the nodes are cached.
+	NodeIDFormat nodeid.Format
+
+	// MetricsRegisterer registers the metrics of requests and responses on the streams.
+	MetricsRegisterer prometheus.Registerer
 }
 
 func (c *Config) validate() error {
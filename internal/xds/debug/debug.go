@@ -0,0 +1,199 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/xds/ack"
+	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/workload"
+)
+
+// ErrNodeNotFound is returned when the node is neither connected nor cached.
+var ErrNodeNotFound = errors.New("node not found")
+
+// typeURLs are the v3 type URLs of resources dumped for each node.
+var typeURLs = []string{
+	resourcev3.ClusterType,
+	resourcev3.ListenerType,
+	resourcev3.RouteType,
+	resourcev3.EndpointType,
+	resourcev3.SecretType,
+	resourcev3.RuntimeType,
+	cache.ScopedRouteTypeV3,
+	cache.VirtualHostTypeV3,
+}
+
+// Nodes returns the open streams of each connected node, which the xDS server implements.
+type Nodes interface {
+	ConnectedNodes() map[string][]ack.StreamID
+}
+
+// Node is a connected node.
+type Node struct {
+	Node    string   `json:"node"`
+	Streams []Stream `json:"streams"`
+}
+
+// Stream is an open stream of a node, whose ID is unique only within the server.
+type Stream struct {
+	Server string `json:"server"`
+	ID     int64  `json:"id"`
+}
+
+// ConfigDump is what Bootes serves to a node, and why.
+type ConfigDump struct {
+	Node      string         `json:"node"`
+	Streams   []Stream       `json:"streams"`
+	Workload  *Workload      `json:"workload,omitempty"`
+	Resources []TypeResource `json:"resources"`
+	Matches   []Match        `json:"matches"`
+}
+
+// Workload is the namespace and the labels of the node, against which workloadSelector of resources are matched.
+type Workload struct {
+	Namespace string            `json:"namespace"`
+	Labels    map[string]string `json:"labels"`
+}
+
+// TypeResource is the cached resources of a type URL, and the last acceptance of them by the node.
+type TypeResource struct {
+	TypeURL      string                     `json:"typeUrl"`
+	Version      string                     `json:"version"`
+	AckedVersion string                     `json:"ackedVersion,omitempty"`
+	NACK         *ack.NACK                  `json:"nack,omitempty"`
+	Resources    map[string]json.RawMessage `json:"resources"`
+}
+
+// Debugger inspects the states of nodes kept by the xDS server, the cache and the tracker.
+type Debugger struct {
+	nodes    Nodes
+	cache    cache.Cache
+	store    store.Store
+	resolver *workload.Resolver
+	tracker  *ack.Tracker
+}
+
+func New(n Nodes, c cache.Cache, s store.Store, wr *workload.Resolver, t *ack.Tracker) *Debugger {
+	return &Debugger{
+		nodes:    n,
+		cache:    c,
+		store:    s,
+		resolver: wr,
+		tracker:  t,
+	}
+}
+
+// Nodes returns the connected nodes sorted by their keys.
+func (d *Debugger) Nodes() []Node {
+	connected := d.nodes.ConnectedNodes()
+
+	results := make([]Node, 0, len(connected))
+	for node, streams := range connected {
+		results = append(results, Node{
+			Node:    node,
+			Streams: newStreams(streams),
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Node < results[j].Node
+	})
+
+	return results
+}
+
+// ConfigDump returns the resources cached for the node, filtered by the type URL if it is not empty,
+// and the resources in the store matched against the workload of the node.
+func (d *Debugger) ConfigDump(ctx context.Context, node, typeURL string) (*ConfigDump, error) {
+	streams, connected := d.nodes.ConnectedNodes()[node]
+	if !connected && !d.cache.IsCachedNode(node) {
+		return nil, ErrNodeNotFound
+	}
+
+	dump := &ConfigDump{
+		Node:      node,
+		Streams:   newStreams(streams),
+		Resources: []TypeResource{},
+		Matches:   []Match{},
+	}
+
+	states := map[string]ack.State{}
+	for _, st := range d.tracker.States(node, typeURL, false) {
+		states[st.TypeURL] = st
+	}
+
+	for _, u := range typeURLs {
+		if typeURL != "" && u != typeURL {
+			continue
+		}
+
+		r, err := d.typeResource(node, u, states[u])
+		if err != nil {
+			return nil, err
+		}
+		dump.Resources = append(dump.Resources, *r)
+	}
+
+	w, err := d.resolver.Lookup(ctx, node)
+	if errors.Is(err, workload.ErrNotFound) {
+		return dump, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up workload: %w", err)
+	}
+
+	dump.Workload = &Workload{
+		Namespace: w.Namespace,
+		Labels:    w.Labels,
+	}
+
+	dump.Matches, err = d.matches(ctx, w)
+	if err != nil {
+		return nil, err
+	}
+
+	return dump, nil
+}
+
+func (d *Debugger) typeResource(node, typeURL string, st ack.State) (*TypeResource, error) {
+	version, resources := d.cache.GetResources(node, typeURL)
+
+	r := &TypeResource{
+		TypeURL:      typeURL,
+		Version:      version,
+		AckedVersion: st.AckedVersion,
+		NACK:         st.NACK,
+		Resources:    make(map[string]json.RawMessage, len(resources)),
+	}
+
+	for name, resource := range resources {
+		b, err := protojson.Marshal(proto.MessageV2(redact(resource)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s %s: %w", typeURL, name, err)
+		}
+		r.Resources[name] = b
+	}
+
+	return r, nil
+}
+
+func newStreams(streamIDs []ack.StreamID) []Stream {
+	streams := make([]Stream, 0, len(streamIDs))
+	for _, id := range streamIDs {
+		streams = append(streams, Stream{
+			Server: id.Server,
+			ID:     id.ID,
+		})
+	}
+
+	return streams
+}
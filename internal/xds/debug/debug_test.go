@@ -0,0 +1,350 @@
+package debug_test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	envoyapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	envoyauth "github.com/envoyproxy/go-control-plane/envoy/api/v2/auth"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	xdscache "github.com/envoyproxy/go-control-plane/pkg/cache/v2"
+	xdscachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/xds/ack"
+	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/debug"
+	"github.com/110y/bootes/internal/xds/nodeid"
+	"github.com/110y/bootes/internal/xds/workload"
+)
+
+type fakeStore struct {
+	store.Store
+	pods     map[string]*corev1.Pod
+	clusters []*api.Cluster
+}
+
+func (s *fakeStore) GetPod(_ context.Context, name, namespace string) (*corev1.Pod, error) {
+	pod, ok := s.pods[store.ToNodeName(name, namespace)]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	return pod, nil
+}
+
+func (s *fakeStore) GetWorkloadEntry(context.Context, string, string) (*api.WorkloadEntry, error) {
+	return nil, store.ErrNotFound
+}
+
+func (s *fakeStore) ListClustersByNamespace(context.Context, string) (*api.ClusterList, error) {
+	return &api.ClusterList{Items: s.clusters}, nil
+}
+
+func (s *fakeStore) ListListenersByNamespace(context.Context, string) (*api.ListenerList, error) {
+	return &api.ListenerList{}, nil
+}
+
+func (s *fakeStore) ListRoutesByNamespace(context.Context, string) (*api.RouteList, error) {
+	return &api.RouteList{}, nil
+}
+
+func (s *fakeStore) ListEndpointsByNamespace(context.Context, string) (*api.EndpointList, error) {
+	return &api.EndpointList{}, nil
+}
+
+func (s *fakeStore) ListSecretsByNamespace(context.Context, string) (*api.SecretList, error) {
+	return &api.SecretList{}, nil
+}
+
+func (s *fakeStore) ListRuntimesByNamespace(context.Context, string) (*api.RuntimeList, error) {
+	return &api.RuntimeList{}, nil
+}
+
+func (s *fakeStore) ListScopedRoutesByNamespace(context.Context, string) (*api.ScopedRouteList, error) {
+	return &api.ScopedRouteList{}, nil
+}
+
+func (s *fakeStore) ListVirtualHostsByNamespace(context.Context, string) (*api.VirtualHostList, error) {
+	return &api.VirtualHostList{}, nil
+}
+
+type fakeNodes map[string][]ack.StreamID
+
+func (n fakeNodes) ConnectedNodes() map[string][]ack.StreamID {
+	return n
+}
+
+func newCluster(name, namespace string, ws *api.WorkloadSelector) *api.Cluster {
+	return &api.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: api.ClusterSpec{
+			WorkloadSelector: ws,
+			Config:           &envoyapi.Cluster{Name: name},
+			ConfigV3:         &clusterv3.Cluster{Name: name},
+		},
+	}
+}
+
+func TestDebuggerConfigDump(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	cluster1 := newCluster("cluster-1", "ns-1", &api.WorkloadSelector{Labels: map[string]string{"app": "envoy"}})
+	cluster2 := newCluster("cluster-2", "ns-1", &api.WorkloadSelector{Labels: map[string]string{"app": "other"}})
+	cluster3 := newCluster("cluster-3", "ns-2", nil)
+
+	s := &fakeStore{
+		pods: map[string]*corev1.Pod{
+			"pod-1.ns-1": {
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-1",
+					Namespace: "ns-1",
+					Labels:    map[string]string{"app": "envoy"},
+				},
+			},
+		},
+		clusters: []*api.Cluster{cluster3, cluster2, cluster1},
+	}
+
+	c := cache.New(
+		xdscache.NewSnapshotCache(false, xdscache.IDHash{}, nil),
+		xdscachev3.NewSnapshotCache(false, xdscachev3.IDHash{}, nil),
+	)
+	if err := c.UpdateClusters(ctx, "pod-1.ns-1", []*api.Cluster{cluster1, cluster3}); err != nil {
+		t.Fatalf("failed to update clusters: %s", err)
+	}
+	version, _ := c.GetResources("pod-1.ns-1", resourcev3.ClusterType)
+
+	tracker, err := ack.NewTracker(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("failed to create tracker: %s", err)
+	}
+	streamID := ack.StreamID{Server: "v3", ID: 1}
+	tracker.OnResponse(streamID, "nonce-1", version)
+	tracker.OnRequest(streamID, "pod-1.ns-1", resourcev3.ClusterType, "nonce-1", nil)
+
+	nodes := fakeNodes{"pod-1.ns-1": {streamID}}
+	d := debug.New(nodes, c, s, workload.NewResolver(s, workload.NewRegistry(), nodeid.NameNamespace{}), tracker)
+
+	dump, err := d.ConfigDump(ctx, "pod-1.ns-1", resourcev3.ClusterType)
+	if err != nil {
+		t.Fatalf("failed to dump config: %s", err)
+	}
+
+	if diff := cmp.Diff([]debug.Stream{{Server: "v3", ID: 1}}, dump.Streams); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+
+	expectedWorkload := &debug.Workload{Namespace: "ns-1", Labels: map[string]string{"app": "envoy"}}
+	if diff := cmp.Diff(expectedWorkload, dump.Workload); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+
+	if len(dump.Resources) != 1 {
+		t.Fatalf("unexpected number of type URLs: %d", len(dump.Resources))
+	}
+
+	r := dump.Resources[0]
+	if r.TypeURL != resourcev3.ClusterType || r.Version != version || r.AckedVersion != version || r.NACK != nil {
+		t.Errorf("unexpected resources: %+v", r)
+	}
+
+	names := []string{}
+	for name := range r.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if diff := cmp.Diff([]string{"cluster-1", "cluster-3"}, names); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+
+	expectedMatches := []debug.Match{
+		{
+			Kind:      api.ClusterKind,
+			Namespace: "ns-1",
+			Name:      "cluster-1",
+			Matched:   true,
+			Reason:    `workloadSelector "app=envoy" matches the labels`,
+		},
+		{
+			Kind:      api.ClusterKind,
+			Namespace: "ns-1",
+			Name:      "cluster-2",
+			Matched:   false,
+			Reason:    `workloadSelector "app=other" does not match the labels`,
+		},
+		{
+			Kind:      api.ClusterKind,
+			Namespace: "ns-2",
+			Name:      "cluster-3",
+			Matched:   true,
+			Exported:  true,
+			Reason:    "no workloadSelector, which selects all workloads",
+		},
+	}
+	if diff := cmp.Diff(expectedMatches, dump.Matches); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+}
+
+func TestDebuggerConfigDumpNodeNotFound(t *testing.T) {
+	t.Parallel()
+
+	c := cache.New(
+		xdscache.NewSnapshotCache(false, xdscache.IDHash{}, nil),
+		xdscachev3.NewSnapshotCache(false, xdscachev3.IDHash{}, nil),
+	)
+
+	tracker, err := ack.NewTracker(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("failed to create tracker: %s", err)
+	}
+
+	s := &fakeStore{}
+	d := debug.New(fakeNodes{}, c, s, workload.NewResolver(s, workload.NewRegistry(), nodeid.NameNamespace{}), tracker)
+
+	if _, err := d.ConfigDump(context.Background(), "pod-1.ns-1", ""); !errors.Is(err, debug.ErrNodeNotFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDebuggerConfigDumpRedactsSecrets(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	c := cache.New(
+		xdscache.NewSnapshotCache(false, xdscache.IDHash{}, nil),
+		xdscachev3.NewSnapshotCache(false, xdscachev3.IDHash{}, nil),
+	)
+
+	secret := &api.Secret{
+		Spec: api.SecretSpec{
+			Config: &envoyauth.Secret{Name: "secret-1"},
+			ConfigV3: &tlsv3.Secret{
+				Name: "secret-1",
+				Type: &tlsv3.Secret_TlsCertificate{
+					TlsCertificate: &tlsv3.TlsCertificate{
+						CertificateChain: &corev3.DataSource{Specifier: &corev3.DataSource_InlineString{InlineString: "certificate"}},
+						PrivateKey:       &corev3.DataSource{Specifier: &corev3.DataSource_InlineString{InlineString: "private-key"}},
+					},
+				},
+			},
+		},
+	}
+	if err := c.UpdateSecrets(ctx, "pod-1.ns-1", []*api.Secret{secret}); err != nil {
+		t.Fatalf("failed to update secrets: %s", err)
+	}
+
+	tracker, err := ack.NewTracker(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("failed to create tracker: %s", err)
+	}
+
+	s := &fakeStore{}
+	d := debug.New(fakeNodes{}, c, s, workload.NewResolver(s, workload.NewRegistry(), nodeid.NameNamespace{}), tracker)
+
+	dump, err := d.ConfigDump(ctx, "pod-1.ns-1", resourcev3.SecretType)
+	if err != nil {
+		t.Fatalf("failed to dump config: %s", err)
+	}
+
+	actual := string(dump.Resources[0].Resources["secret-1"])
+	if strings.Contains(actual, "private-key") || !strings.Contains(actual, "[redacted]") || !strings.Contains(actual, "certificate") {
+		t.Errorf("private key is not redacted: %s", actual)
+	}
+
+	// NOTE: the cached secret must be left as it is.
+	_, secrets := c.GetResources("pod-1.ns-1", resourcev3.SecretType)
+	if key := secrets["secret-1"].(*tlsv3.Secret).GetTlsCertificate().GetPrivateKey().GetInlineString(); key != "private-key" {
+		t.Errorf("cached private key has been modified: %s", key)
+	}
+}
+
+func TestDebuggerConfigDumpRedactsListeners(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	c := cache.New(
+		xdscache.NewSnapshotCache(false, xdscache.IDHash{}, nil),
+		xdscachev3.NewSnapshotCache(false, xdscachev3.IDHash{}, nil),
+	)
+
+	tc, err := ptypes.MarshalAny(&tlsv3.DownstreamTlsContext{
+		CommonTlsContext: &tlsv3.CommonTlsContext{
+			TlsCertificates: []*tlsv3.TlsCertificate{
+				{
+					CertificateChain: &corev3.DataSource{Specifier: &corev3.DataSource_InlineString{InlineString: "certificate"}},
+					PrivateKey:       &corev3.DataSource{Specifier: &corev3.DataSource_InlineString{InlineString: "private-key"}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal tls context: %s", err)
+	}
+
+	listener := &api.Listener{
+		Spec: api.ListenerSpec{
+			Config: &envoyapi.Listener{Name: "listener-1"},
+			ConfigV3: &listenerv3.Listener{
+				Name: "listener-1",
+				FilterChains: []*listenerv3.FilterChain{
+					{
+						TransportSocket: &corev3.TransportSocket{
+							Name:       "envoy.transport_sockets.tls",
+							ConfigType: &corev3.TransportSocket_TypedConfig{TypedConfig: tc},
+						},
+					},
+				},
+			},
+		},
+	}
+	if err := c.UpdateListeners(ctx, "pod-1.ns-1", []*api.Listener{listener}); err != nil {
+		t.Fatalf("failed to update listeners: %s", err)
+	}
+
+	tracker, err := ack.NewTracker(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatalf("failed to create tracker: %s", err)
+	}
+
+	s := &fakeStore{}
+	d := debug.New(fakeNodes{}, c, s, workload.NewResolver(s, workload.NewRegistry(), nodeid.NameNamespace{}), tracker)
+
+	dump, err := d.ConfigDump(ctx, "pod-1.ns-1", resourcev3.ListenerType)
+	if err != nil {
+		t.Fatalf("failed to dump config: %s", err)
+	}
+
+	actual := string(dump.Resources[0].Resources["listener-1"])
+	if strings.Contains(actual, "private-key") || !strings.Contains(actual, "[redacted]") || !strings.Contains(actual, "certificate") {
+		t.Errorf("private key is not redacted: %s", actual)
+	}
+
+	// NOTE: the cached listener must be left as it is.
+	_, listeners := c.GetResources("pod-1.ns-1", resourcev3.ListenerType)
+	cached := &tlsv3.DownstreamTlsContext{}
+	if err := ptypes.UnmarshalAny(listeners["listener-1"].(*listenerv3.Listener).GetFilterChains()[0].GetTransportSocket().GetTypedConfig(), cached); err != nil {
+		t.Fatalf("failed to unmarshal cached tls context: %s", err)
+	}
+	if key := cached.GetCommonTlsContext().GetTlsCertificates()[0].GetPrivateKey().GetInlineString(); key != "private-key" {
+		t.Errorf("cached private key has been modified: %s", key)
+	}
+}
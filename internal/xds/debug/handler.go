@@ -0,0 +1,64 @@
+package debug
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+const (
+	// NodesHandlerPath is the path on which NodesHandler is served.
+	NodesHandlerPath = "/xds/nodes"
+	// ConfigDumpHandlerPath is the path on which ConfigDumpHandler is served.
+	ConfigDumpHandlerPath = "/xds/config_dump"
+)
+
+// NodesHandler returns a handler which responds the connected nodes and their streams in JSON.
+func (d *Debugger) NodesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		writeJSON(w, d.Nodes())
+	})
+}
+
+// ConfigDumpHandler returns a handler which responds the config dump of the node given by the `node` query parameter in JSON.
+// The resources can be filtered by the `type_url` query parameter.
+func (d *Debugger) ConfigDumpHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		q := r.URL.Query()
+
+		node := q.Get("node")
+		if node == "" {
+			http.Error(w, "node is required", http.StatusBadRequest)
+			return
+		}
+
+		dump, err := d.ConfigDump(r.Context(), node, q.Get("type_url"))
+		if errors.Is(err, ErrNodeNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, dump)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
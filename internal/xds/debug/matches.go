@@ -0,0 +1,249 @@
+package debug
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/xds/workload"
+)
+
+// Match is whether a resource visible from the namespace of a node selects the node, and why.
+type Match struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Matched   bool   `json:"matched"`
+	// Exported is true if the resource is in another namespace and exported to the one of the node by spec.exportTo.
+	Exported bool   `json:"exported,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+type resource interface {
+	api.EnvoyResource
+	GetName() string
+	GetNamespace() string
+}
+
+// matches matches resources of all kinds visible from the namespace of the workload by the same filters as the snapshot builder.
+func (d *Debugger) matches(ctx context.Context, w *workload.Workload) ([]Match, error) {
+	fns := []func(context.Context, *workload.Workload) ([]Match, error){
+		d.matchClusters,
+		d.matchListeners,
+		d.matchRoutes,
+		d.matchEndpoints,
+		d.matchSecrets,
+		d.matchRuntimes,
+		d.matchScopedRoutes,
+		d.matchVirtualHosts,
+	}
+
+	results := []Match{}
+	for _, fn := range fns {
+		matches, err := fn(ctx, w)
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Slice(matches, func(i, j int) bool {
+			if matches[i].Namespace != matches[j].Namespace {
+				return matches[i].Namespace < matches[j].Namespace
+			}
+			return matches[i].Name < matches[j].Name
+		})
+		results = append(results, matches...)
+	}
+
+	return results, nil
+}
+
+func (d *Debugger) matchClusters(ctx context.Context, w *workload.Workload) ([]Match, error) {
+	clusters, err := d.store.ListClustersByNamespace(ctx, w.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %w", err)
+	}
+
+	matched := map[resource]struct{}{}
+	for _, c := range store.FilterClustersByLabels(clusters.Items, w.Labels) {
+		matched[c] = struct{}{}
+	}
+
+	matches := make([]Match, 0, len(clusters.Items))
+	for _, c := range clusters.Items {
+		_, ok := matched[c]
+		matches = append(matches, newMatch(api.ClusterKind, c, ok, w.Namespace))
+	}
+
+	return matches, nil
+}
+
+func (d *Debugger) matchListeners(ctx context.Context, w *workload.Workload) ([]Match, error) {
+	listeners, err := d.store.ListListenersByNamespace(ctx, w.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list listeners: %w", err)
+	}
+
+	matched := map[resource]struct{}{}
+	for _, l := range store.FilterListenersByLabels(listeners.Items, w.Labels) {
+		matched[l] = struct{}{}
+	}
+
+	matches := make([]Match, 0, len(listeners.Items))
+	for _, l := range listeners.Items {
+		_, ok := matched[l]
+		matches = append(matches, newMatch(api.ListenerKind, l, ok, w.Namespace))
+	}
+
+	return matches, nil
+}
+
+func (d *Debugger) matchRoutes(ctx context.Context, w *workload.Workload) ([]Match, error) {
+	routes, err := d.store.ListRoutesByNamespace(ctx, w.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+
+	matched := map[resource]struct{}{}
+	for _, r := range store.FilterRoutesByLabels(routes.Items, w.Labels) {
+		matched[r] = struct{}{}
+	}
+
+	matches := make([]Match, 0, len(routes.Items))
+	for _, r := range routes.Items {
+		_, ok := matched[r]
+		matches = append(matches, newMatch(api.RouteKind, r, ok, w.Namespace))
+	}
+
+	return matches, nil
+}
+
+func (d *Debugger) matchEndpoints(ctx context.Context, w *workload.Workload) ([]Match, error) {
+	endpoints, err := d.store.ListEndpointsByNamespace(ctx, w.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list endpoints: %w", err)
+	}
+
+	matched := map[resource]struct{}{}
+	for _, e := range store.FilterEndpointsByLabels(endpoints.Items, w.Labels) {
+		matched[e] = struct{}{}
+	}
+
+	matches := make([]Match, 0, len(endpoints.Items))
+	for _, e := range endpoints.Items {
+		_, ok := matched[e]
+		matches = append(matches, newMatch(api.EndpointKind, e, ok, w.Namespace))
+	}
+
+	return matches, nil
+}
+
+func (d *Debugger) matchSecrets(ctx context.Context, w *workload.Workload) ([]Match, error) {
+	secrets, err := d.store.ListSecretsByNamespace(ctx, w.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secrets: %w", err)
+	}
+
+	matched := map[resource]struct{}{}
+	for _, s := range store.FilterSecretsByLabels(secrets.Items, w.Labels) {
+		matched[s] = struct{}{}
+	}
+
+	matches := make([]Match, 0, len(secrets.Items))
+	for _, s := range secrets.Items {
+		_, ok := matched[s]
+		matches = append(matches, newMatch(api.SecretKind, s, ok, w.Namespace))
+	}
+
+	return matches, nil
+}
+
+func (d *Debugger) matchRuntimes(ctx context.Context, w *workload.Workload) ([]Match, error) {
+	runtimes, err := d.store.ListRuntimesByNamespace(ctx, w.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list runtimes: %w", err)
+	}
+
+	matched := map[resource]struct{}{}
+	for _, r := range store.FilterRuntimesByLabels(runtimes.Items, w.Labels) {
+		matched[r] = struct{}{}
+	}
+
+	matches := make([]Match, 0, len(runtimes.Items))
+	for _, r := range runtimes.Items {
+		_, ok := matched[r]
+		matches = append(matches, newMatch(api.RuntimeKind, r, ok, w.Namespace))
+	}
+
+	return matches, nil
+}
+
+func (d *Debugger) matchScopedRoutes(ctx context.Context, w *workload.Workload) ([]Match, error) {
+	scopedRoutes, err := d.store.ListScopedRoutesByNamespace(ctx, w.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scoped routes: %w", err)
+	}
+
+	matched := map[resource]struct{}{}
+	for _, r := range store.FilterScopedRoutesByLabels(scopedRoutes.Items, w.Labels) {
+		matched[r] = struct{}{}
+	}
+
+	matches := make([]Match, 0, len(scopedRoutes.Items))
+	for _, r := range scopedRoutes.Items {
+		_, ok := matched[r]
+		matches = append(matches, newMatch(api.ScopedRouteKind, r, ok, w.Namespace))
+	}
+
+	return matches, nil
+}
+
+func (d *Debugger) matchVirtualHosts(ctx context.Context, w *workload.Workload) ([]Match, error) {
+	virtualHosts, err := d.store.ListVirtualHostsByNamespace(ctx, w.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list virtual hosts: %w", err)
+	}
+
+	matched := map[resource]struct{}{}
+	for _, v := range store.FilterVirtualHostsByLabels(virtualHosts.Items, w.Labels) {
+		matched[v] = struct{}{}
+	}
+
+	matches := make([]Match, 0, len(virtualHosts.Items))
+	for _, v := range virtualHosts.Items {
+		_, ok := matched[v]
+		matches = append(matches, newMatch(api.VirtualHostKind, v, ok, w.Namespace))
+	}
+
+	return matches, nil
+}
+
+func newMatch(kind string, r resource, matched bool, namespace string) Match {
+	return Match{
+		Kind:      kind,
+		Namespace: r.GetNamespace(),
+		Name:      r.GetName(),
+		Matched:   matched,
+		Exported:  r.GetNamespace() != namespace,
+		Reason:    matchReason(r, matched),
+	}
+}
+
+func matchReason(r resource, matched bool) string {
+	ws := r.GetWorkloadSelector()
+	if ws == nil {
+		return "no workloadSelector, which selects all workloads"
+	}
+
+	selector, err := ws.AsSelector()
+	if err != nil {
+		return fmt.Sprintf("invalid workloadSelector: %s", err)
+	}
+
+	if matched {
+		return fmt.Sprintf("workloadSelector %q matches the labels", selector)
+	}
+
+	return fmt.Sprintf("workloadSelector %q does not match the labels", selector)
+}
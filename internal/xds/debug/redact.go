@@ -0,0 +1,117 @@
+package debug
+
+import (
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listenerv3 "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	tlsv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+)
+
+const redacted = "[redacted]"
+
+// redact returns a copy of the resource whose inline private keys, passwords and secrets are redacted in the same way as the config dump of Envoy,
+// since the dump is served along with the metrics. Other resources are returned as they are.
+// Besides Secrets, the TLS contexts configured inline in the transport sockets of Listeners and Clusters are redacted.
+func redact(resource types.Resource) types.Resource {
+	switch r := resource.(type) {
+	case *tlsv3.Secret:
+		s := proto.Clone(r).(*tlsv3.Secret)
+		redactSecret(s)
+		return s
+	case *listenerv3.Listener:
+		l := proto.Clone(r).(*listenerv3.Listener)
+		for _, fc := range l.GetFilterChains() {
+			redactTransportSocket(fc.GetTransportSocket())
+		}
+		return l
+	case *clusterv3.Cluster:
+		c := proto.Clone(r).(*clusterv3.Cluster)
+		redactTransportSocket(c.GetTransportSocket())
+		for _, m := range c.GetTransportSocketMatches() {
+			redactTransportSocket(m.GetTransportSocket())
+		}
+		return c
+	default:
+		return resource
+	}
+}
+
+func redactSecret(s *tlsv3.Secret) {
+	switch t := s.Type.(type) {
+	case *tlsv3.Secret_TlsCertificate:
+		redactTLSCertificate(t.TlsCertificate)
+	case *tlsv3.Secret_SessionTicketKeys:
+		redactSessionTicketKeys(t.SessionTicketKeys)
+	case *tlsv3.Secret_GenericSecret:
+		redactDataSource(t.GenericSecret.GetSecret())
+	}
+}
+
+// redactTransportSocket redacts the typed config of the transport socket if it is a TLS context.
+// Typed configs of other transport sockets are left as they are since they do not hold secrets known to be redacted.
+func redactTransportSocket(ts *corev3.TransportSocket) {
+	typed := ts.GetTypedConfig()
+	if typed == nil {
+		return
+	}
+
+	var tc proto.Message
+	switch {
+	case ptypes.Is(typed, &tlsv3.DownstreamTlsContext{}):
+		tc = &tlsv3.DownstreamTlsContext{}
+	case ptypes.Is(typed, &tlsv3.UpstreamTlsContext{}):
+		tc = &tlsv3.UpstreamTlsContext{}
+	default:
+		return
+	}
+
+	// NOTE: drop the config entirely if it can not be redacted, rather than leaking the secrets in it.
+	if err := ptypes.UnmarshalAny(typed, tc); err != nil {
+		ts.ConfigType = nil
+		return
+	}
+
+	switch c := tc.(type) {
+	case *tlsv3.DownstreamTlsContext:
+		redactCommonTLSContext(c.GetCommonTlsContext())
+		redactSessionTicketKeys(c.GetSessionTicketKeys())
+	case *tlsv3.UpstreamTlsContext:
+		redactCommonTLSContext(c.GetCommonTlsContext())
+	}
+
+	a, err := ptypes.MarshalAny(tc)
+	if err != nil {
+		ts.ConfigType = nil
+		return
+	}
+
+	ts.ConfigType = &corev3.TransportSocket_TypedConfig{TypedConfig: a}
+}
+
+func redactCommonTLSContext(c *tlsv3.CommonTlsContext) {
+	for _, cert := range c.GetTlsCertificates() {
+		redactTLSCertificate(cert)
+	}
+}
+
+func redactTLSCertificate(c *tlsv3.TlsCertificate) {
+	redactDataSource(c.GetPrivateKey())
+	redactDataSource(c.GetPassword())
+}
+
+func redactSessionTicketKeys(k *tlsv3.TlsSessionTicketKeys) {
+	for _, ds := range k.GetKeys() {
+		redactDataSource(ds)
+	}
+}
+
+// redactDataSource redacts the data source only if it is inline, since file names are not secrets.
+func redactDataSource(ds *corev3.DataSource) {
+	switch ds.GetSpecifier().(type) {
+	case *corev3.DataSource_InlineBytes, *corev3.DataSource_InlineString:
+		ds.Specifier = &corev3.DataSource_InlineString{InlineString: redacted}
+	}
+}
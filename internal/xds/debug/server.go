@@ -0,0 +1,60 @@
+package debug
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/110y/bootes/internal/xds/ack"
+)
+
+var _ manager.LeaderElectionRunnable = (*Server)(nil)
+
+// Server serves the handlers of the debugger and the ACK tracker only on the loopback interface of the replica,
+// since they expose the topology of all nodes, and are meant to be reached by `kubectl port-forward`.
+type Server struct {
+	addr    string
+	handler http.Handler
+}
+
+func NewServer(port int, d *Debugger, t *ack.Tracker) *Server {
+	mux := http.NewServeMux()
+	mux.Handle(NodesHandlerPath, d.NodesHandler())
+	mux.Handle(ConfigDumpHandlerPath, d.ConfigDumpHandler())
+	mux.Handle(ack.HandlerPath, t.Handler())
+
+	return &Server{
+		addr:    net.JoinHostPort("127.0.0.1", fmt.Sprint(port)),
+		handler: mux,
+	}
+}
+
+// Start serves the handlers until the stop channel is closed.
+func (s *Server) Start(stop <-chan struct{}) error {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.addr, err)
+	}
+
+	srv := &http.Server{Handler: s.handler}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(lis)
+	}()
+
+	select {
+	case <-stop:
+		return srv.Shutdown(context.Background())
+	case err := <-errCh:
+		return err
+	}
+}
+
+// NeedLeaderElection returns false since every replica serves the nodes connected to it.
+func (*Server) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,306 @@
+package delta
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	routeservicev3 "github.com/envoyproxy/go-control-plane/envoy/service/route/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/any"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protov2 "google.golang.org/protobuf/proto"
+
+	"github.com/110y/bootes/internal/xds/cache"
+)
+
+// Cache is the source of the v3 resources served over the incremental xDS protocol.
+type Cache interface {
+	GetResources(node, typeURL string) (string, map[string]types.Resource)
+	Watch(node string) (<-chan struct{}, func())
+}
+
+// Callbacks is a set of callbacks invoked on the incremental xDS streams.
+type Callbacks interface {
+	OnStreamOpen(ctx context.Context, streamID int64, typeURL string) error
+	OnStreamClosed(streamID int64)
+	OnStreamDeltaRequest(streamID int64, req *discoveryv3.DeltaDiscoveryRequest) error
+	OnStreamDeltaResponse(streamID int64, req *discoveryv3.DeltaDiscoveryRequest, resp *discoveryv3.DeltaDiscoveryResponse)
+}
+
+// Server serves v3 resources over the incremental xDS protocol, which the SnapshotCache based servers do not implement.
+// Only resources whose content has changed since the last response are sent, and removed resources are sent by their names.
+type Server struct {
+	routeservicev3.UnimplementedScopedRoutesDiscoveryServiceServer
+
+	ctx         context.Context
+	cache       Cache
+	callbacks   Callbacks
+	streamCount int64
+}
+
+type stream interface {
+	grpc.ServerStream
+	Send(*discoveryv3.DeltaDiscoveryResponse) error
+	Recv() (*discoveryv3.DeltaDiscoveryRequest, error)
+}
+
+func NewServer(ctx context.Context, c Cache, cb Callbacks) *Server {
+	return &Server{
+		ctx:       ctx,
+		cache:     c,
+		callbacks: cb,
+	}
+}
+
+func (s *Server) DeltaAggregatedResources(st discoveryv3.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
+	return s.handle(st, "")
+}
+
+func (s *Server) DeltaScopedRoutes(st routeservicev3.ScopedRoutesDiscoveryService_DeltaScopedRoutesServer) error {
+	return s.handle(st, cache.ScopedRouteTypeV3)
+}
+
+func (s *Server) DeltaVirtualHosts(st routeservicev3.VirtualHostDiscoveryService_DeltaVirtualHostsServer) error {
+	return s.handle(st, cache.VirtualHostTypeV3)
+}
+
+func (s *Server) handle(st stream, defaultTypeURL string) error {
+	streamID := atomic.AddInt64(&s.streamCount, 1)
+	ctx := st.Context()
+
+	if err := s.callbacks.OnStreamOpen(ctx, streamID, defaultTypeURL); err != nil {
+		return err
+	}
+	defer s.callbacks.OnStreamClosed(streamID)
+
+	reqCh := make(chan *discoveryv3.DeltaDiscoveryRequest)
+	errCh := make(chan error, 1)
+	go func() {
+		for {
+			req, err := st.Recv()
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+			select {
+			case reqCh <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		node    *corev3.Node
+		watchCh <-chan struct{}
+		cancel  func()
+	)
+	defer func() {
+		if cancel != nil {
+			cancel()
+		}
+	}()
+
+	w := &responseWriter{
+		stream:    st,
+		streamID:  streamID,
+		cache:     s.cache,
+		callbacks: s.callbacks,
+	}
+	subscriptions := map[string]*subscription{}
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return nil
+		case <-ctx.Done():
+			return nil
+		case err := <-errCh:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		case req := <-reqCh:
+			// NOTE: node may only be set on the first request.
+			if req.Node != nil {
+				node = req.Node
+			} else {
+				req.Node = node
+			}
+
+			if node.GetId() == "" {
+				return status.Error(codes.InvalidArgument, "empty node id")
+			}
+
+			if watchCh == nil {
+				watchCh, cancel = s.cache.Watch(node.GetId())
+			}
+
+			typeURL := req.GetTypeUrl()
+			if typeURL == "" {
+				typeURL = defaultTypeURL
+			}
+
+			if !isSupportedType(typeURL) {
+				return status.Errorf(codes.Unimplemented, "incremental xDS is not supported for %s", typeURL)
+			}
+
+			if err := s.callbacks.OnStreamDeltaRequest(streamID, req); err != nil {
+				return err
+			}
+
+			sub, ok := subscriptions[typeURL]
+			if !ok {
+				sub = newSubscription(typeURL, req)
+				subscriptions[typeURL] = sub
+			}
+
+			// NOTE: do not reply to NACKs which do not change subscriptions, since the reply would repeat the rejected resources.
+			// They are sent again on the next update of the resources.
+			if nacked := sub.update(req); nacked && len(req.GetResourceNamesSubscribe()) == 0 && len(req.GetResourceNamesUnsubscribe()) == 0 {
+				continue
+			}
+
+			// NOTE: always respond to the first request of each type since the client waits for it to complete initialization.
+			if err := w.respond(sub, req, !ok); err != nil {
+				return err
+			}
+		case <-watchCh:
+			for _, sub := range subscriptions {
+				if err := w.respond(sub, sub.lastRequest, false); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+type responseWriter struct {
+	stream    stream
+	streamID  int64
+	cache     Cache
+	callbacks Callbacks
+	nonce     int64
+}
+
+// respond sends resources which have been added or updated since the last response and names of removed ones.
+func (w *responseWriter) respond(sub *subscription, req *discoveryv3.DeltaDiscoveryRequest, force bool) error {
+	version, resources := w.cache.GetResources(sub.node, sub.typeURL)
+	desired := sub.resolve(resources)
+	sent := sub.sentVersions()
+	changes := map[string]string{}
+
+	resp := &discoveryv3.DeltaDiscoveryResponse{
+		SystemVersionInfo: version,
+		TypeUrl:           sub.typeURL,
+	}
+
+	for _, name := range sortedNames(desired) {
+		e := desired[name]
+
+		if e.resource == nil {
+			// NOTE: tell the client the alias can not be resolved by a resource without its body.
+			if _, ok := sub.answered[name]; ok {
+				continue
+			}
+
+			resp.Resources = append(resp.Resources, &discoveryv3.Resource{Name: name})
+			sub.markAnswered(name)
+			continue
+		}
+
+		b, err := marshalResource(e.resource)
+		if err != nil {
+			return fmt.Errorf("failed to marshal resource %s: %w", name, err)
+		}
+
+		v := hashResource(b)
+		if current, ok := sent[name]; ok && current == v && !sub.hasUnansweredAlias(e) {
+			continue
+		}
+
+		resp.Resources = append(resp.Resources, &discoveryv3.Resource{
+			Name:    name,
+			Aliases: e.aliases,
+			Version: v,
+			Resource: &any.Any{
+				TypeUrl: sub.typeURL,
+				Value:   b,
+			},
+		})
+		changes[name] = v
+		sub.markAnswered(e.aliases...)
+	}
+
+	for name := range sent {
+		if _, ok := desired[name]; ok {
+			continue
+		}
+
+		changes[name] = ""
+		resp.RemovedResources = append(resp.RemovedResources, name)
+	}
+	sort.Strings(resp.RemovedResources)
+
+	if len(resp.Resources) == 0 && len(resp.RemovedResources) == 0 && !force {
+		return nil
+	}
+
+	w.nonce++
+	resp.Nonce = strconv.FormatInt(w.nonce, 10)
+
+	if err := w.stream.Send(resp); err != nil {
+		return err
+	}
+	sub.addPending(resp.Nonce, changes)
+
+	w.callbacks.OnStreamDeltaResponse(w.streamID, req, resp)
+
+	return nil
+}
+
+func isSupportedType(typeURL string) bool {
+	switch typeURL {
+	case resourcev3.ClusterType,
+		resourcev3.EndpointType,
+		resourcev3.ListenerType,
+		resourcev3.RouteType,
+		resourcev3.SecretType,
+		resourcev3.RuntimeType,
+		cache.ScopedRouteTypeV3,
+		cache.VirtualHostTypeV3:
+		return true
+	default:
+		return false
+	}
+}
+
+func marshalResource(r types.Resource) ([]byte, error) {
+	return protov2.MarshalOptions{Deterministic: true}.Marshal(proto.MessageV2(r))
+}
+
+func hashResource(b []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(b))
+}
+
+func sortedNames(entries map[string]*entry) []string {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,372 @@
+package delta_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/google/go-cmp/cmp"
+	rpcstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+
+	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/internal/delta"
+)
+
+type fakeCache struct {
+	resources map[string]types.Resource
+	watchCh   chan struct{}
+}
+
+func (c *fakeCache) GetResources(_, _ string) (string, map[string]types.Resource) {
+	return "version", c.resources
+}
+
+func (c *fakeCache) Watch(_ string) (<-chan struct{}, func()) {
+	return c.watchCh, func() {}
+}
+
+type fakeCallbacks struct{}
+
+func (fakeCallbacks) OnStreamOpen(context.Context, int64, string) error { return nil }
+func (fakeCallbacks) OnStreamClosed(int64)                              {}
+func (fakeCallbacks) OnStreamDeltaRequest(int64, *discoveryv3.DeltaDiscoveryRequest) error {
+	return nil
+}
+func (fakeCallbacks) OnStreamDeltaResponse(int64, *discoveryv3.DeltaDiscoveryRequest, *discoveryv3.DeltaDiscoveryResponse) {
+}
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	reqCh chan *discoveryv3.DeltaDiscoveryRequest
+	resCh chan *discoveryv3.DeltaDiscoveryResponse
+}
+
+func (s *fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeStream) Send(res *discoveryv3.DeltaDiscoveryResponse) error {
+	s.resCh <- res
+	return nil
+}
+
+func (s *fakeStream) Recv() (*discoveryv3.DeltaDiscoveryRequest, error) {
+	req, ok := <-s.reqCh
+	if !ok {
+		return nil, io.EOF
+	}
+	return req, nil
+}
+
+type response struct {
+	resources map[string][]string
+	removed   []string
+}
+
+func TestDeltaVirtualHosts(t *testing.T) {
+	t.Parallel()
+
+	resources := map[string]types.Resource{
+		"route-1/vh-1": &routev3.VirtualHost{
+			Name:    "route-1/vh-1",
+			Domains: []string{"*.example.com"},
+		},
+		"route-1/vh-2": &routev3.VirtualHost{
+			Name:    "route-1/vh-2",
+			Domains: []string{"api.example.com"},
+		},
+		"route-2/vh-3": &routev3.VirtualHost{
+			Name:    "route-2/vh-3",
+			Domains: []string{"*"},
+		},
+	}
+
+	tests := map[string]struct {
+		requests []*discoveryv3.DeltaDiscoveryRequest
+		expected []response
+	}{
+		"should send all virtual hosts of the subscribed route configuration": {
+			requests: []*discoveryv3.DeltaDiscoveryRequest{
+				{ResourceNamesSubscribe: []string{"route-1"}},
+			},
+			expected: []response{
+				{
+					resources: map[string][]string{
+						"route-1/vh-1": nil,
+						"route-1/vh-2": nil,
+					},
+				},
+			},
+		},
+		"should resolve aliases by the most specific domain": {
+			requests: []*discoveryv3.DeltaDiscoveryRequest{
+				{ResourceNamesSubscribe: []string{"route-1/api.example.com", "route-1/www.example.com"}},
+			},
+			expected: []response{
+				{
+					resources: map[string][]string{
+						"route-1/vh-1": {"route-1/www.example.com"},
+						"route-1/vh-2": {"route-1/api.example.com"},
+					},
+				},
+			},
+		},
+		"should answer an alias of an already sent virtual host": {
+			requests: []*discoveryv3.DeltaDiscoveryRequest{
+				{ResourceNamesSubscribe: []string{"route-2"}},
+				{ResourceNamesSubscribe: []string{"route-2/foo.test"}},
+			},
+			expected: []response{
+				{
+					resources: map[string][]string{
+						"route-2/vh-3": nil,
+					},
+				},
+				{
+					resources: map[string][]string{
+						"route-2/vh-3": {"route-2/foo.test"},
+					},
+				},
+			},
+		},
+		"should answer unresolved aliases without resources": {
+			requests: []*discoveryv3.DeltaDiscoveryRequest{
+				{ResourceNamesSubscribe: []string{"route-1/unknown.test"}},
+			},
+			expected: []response{
+				{
+					resources: map[string][]string{
+						"route-1/unknown.test": nil,
+					},
+				},
+			},
+		},
+		"should remove virtual hosts which no longer exist": {
+			requests: []*discoveryv3.DeltaDiscoveryRequest{
+				{
+					ResourceNamesSubscribe: []string{"route-1"},
+					InitialResourceVersions: map[string]string{
+						"route-1/vh-0": "stale",
+					},
+				},
+			},
+			expected: []response{
+				{
+					resources: map[string][]string{
+						"route-1/vh-1": nil,
+						"route-1/vh-2": nil,
+					},
+					removed: []string{"route-1/vh-0"},
+				},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			c := &fakeCache{resources: resources, watchCh: make(chan struct{})}
+			s := delta.NewServer(ctx, c, fakeCallbacks{})
+
+			st := &fakeStream{
+				ctx:   ctx,
+				reqCh: make(chan *discoveryv3.DeltaDiscoveryRequest),
+				resCh: make(chan *discoveryv3.DeltaDiscoveryResponse, len(test.expected)),
+			}
+
+			go s.DeltaVirtualHosts(st) // nolint:errcheck
+
+			for i, req := range test.requests {
+				if i == 0 {
+					req.Node = &corev3.Node{Id: "node-1"}
+				}
+				req.TypeUrl = cache.VirtualHostTypeV3
+				st.reqCh <- req
+
+				actual := toResponse(t, receive(t, st.resCh))
+				if diff := cmp.Diff(test.expected[i], actual, cmp.AllowUnexported(response{})); diff != "" {
+					t.Errorf("\n(-expected, +actual)\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestDeltaScopedRoutesNotifiesUpdates(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := &fakeCache{
+		resources: map[string]types.Resource{
+			"scope-1": &routev3.ScopedRouteConfiguration{Name: "scope-1", RouteConfigurationName: "route-1"},
+		},
+		watchCh: make(chan struct{}),
+	}
+	s := delta.NewServer(ctx, c, fakeCallbacks{})
+
+	st := &fakeStream{
+		ctx:   ctx,
+		reqCh: make(chan *discoveryv3.DeltaDiscoveryRequest),
+		resCh: make(chan *discoveryv3.DeltaDiscoveryResponse, 2),
+	}
+
+	go s.DeltaScopedRoutes(st) // nolint:errcheck
+
+	st.reqCh <- &discoveryv3.DeltaDiscoveryRequest{
+		Node:    &corev3.Node{Id: "node-1"},
+		TypeUrl: cache.ScopedRouteTypeV3,
+	}
+
+	expected := response{resources: map[string][]string{"scope-1": nil}}
+	if diff := cmp.Diff(expected, toResponse(t, receive(t, st.resCh)), cmp.AllowUnexported(response{})); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+
+	c.resources = map[string]types.Resource{
+		"scope-2": &routev3.ScopedRouteConfiguration{Name: "scope-2", RouteConfigurationName: "route-2"},
+	}
+	c.watchCh <- struct{}{}
+
+	expected = response{resources: map[string][]string{"scope-2": nil}, removed: []string{"scope-1"}}
+	if diff := cmp.Diff(expected, toResponse(t, receive(t, st.resCh)), cmp.AllowUnexported(response{})); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+}
+
+func TestDeltaAggregatedResourcesSendsOnlyChangedResources(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := &fakeCache{
+		resources: map[string]types.Resource{
+			"cluster-1": &clusterv3.Cluster{Name: "cluster-1"},
+			"cluster-2": &clusterv3.Cluster{Name: "cluster-2"},
+			"cluster-3": &clusterv3.Cluster{Name: "cluster-3"},
+		},
+		watchCh: make(chan struct{}),
+	}
+	s := delta.NewServer(ctx, c, fakeCallbacks{})
+
+	st := &fakeStream{
+		ctx:   ctx,
+		reqCh: make(chan *discoveryv3.DeltaDiscoveryRequest),
+		resCh: make(chan *discoveryv3.DeltaDiscoveryResponse, 2),
+	}
+
+	go s.DeltaAggregatedResources(st) // nolint:errcheck
+
+	st.reqCh <- &discoveryv3.DeltaDiscoveryRequest{
+		Node:    &corev3.Node{Id: "node-1"},
+		TypeUrl: resourcev3.ClusterType,
+	}
+
+	expected := response{resources: map[string][]string{"cluster-1": nil, "cluster-2": nil, "cluster-3": nil}}
+	if diff := cmp.Diff(expected, toResponse(t, receive(t, st.resCh)), cmp.AllowUnexported(response{})); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+
+	c.resources = map[string]types.Resource{
+		"cluster-1": &clusterv3.Cluster{Name: "cluster-1"},
+		"cluster-2": &clusterv3.Cluster{Name: "cluster-2", ConnectTimeout: &duration.Duration{Seconds: 1}},
+	}
+	c.watchCh <- struct{}{}
+
+	expected = response{resources: map[string][]string{"cluster-2": nil}, removed: []string{"cluster-3"}}
+	if diff := cmp.Diff(expected, toResponse(t, receive(t, st.resCh)), cmp.AllowUnexported(response{})); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+}
+
+func TestDeltaAggregatedResourcesSendsNACKedResourcesAgain(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := &fakeCache{
+		resources: map[string]types.Resource{
+			"cluster-1": &clusterv3.Cluster{Name: "cluster-1"},
+			"cluster-2": &clusterv3.Cluster{Name: "cluster-2"},
+		},
+		watchCh: make(chan struct{}),
+	}
+	s := delta.NewServer(ctx, c, fakeCallbacks{})
+
+	st := &fakeStream{
+		ctx:   ctx,
+		reqCh: make(chan *discoveryv3.DeltaDiscoveryRequest),
+		resCh: make(chan *discoveryv3.DeltaDiscoveryResponse, 2),
+	}
+
+	go s.DeltaAggregatedResources(st) // nolint:errcheck
+
+	st.reqCh <- &discoveryv3.DeltaDiscoveryRequest{
+		Node:    &corev3.Node{Id: "node-1"},
+		TypeUrl: resourcev3.ClusterType,
+	}
+
+	res := receive(t, st.resCh)
+	expected := response{resources: map[string][]string{"cluster-1": nil, "cluster-2": nil}}
+	if diff := cmp.Diff(expected, toResponse(t, res), cmp.AllowUnexported(response{})); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+
+	st.reqCh <- &discoveryv3.DeltaDiscoveryRequest{
+		TypeUrl:       resourcev3.ClusterType,
+		ResponseNonce: res.GetNonce(),
+		ErrorDetail:   &rpcstatus.Status{Message: "rejected"},
+	}
+	st.reqCh <- &discoveryv3.DeltaDiscoveryRequest{
+		TypeUrl: resourcev3.ClusterType,
+	}
+
+	// NOTE: the rejected resources are not regarded as the ones the client has, even if they have not changed.
+	if diff := cmp.Diff(expected, toResponse(t, receive(t, st.resCh)), cmp.AllowUnexported(response{})); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+}
+
+func receive(t *testing.T, ch <-chan *discoveryv3.DeltaDiscoveryResponse) *discoveryv3.DeltaDiscoveryResponse {
+	t.Helper()
+
+	select {
+	case res := <-ch:
+		return res
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a response")
+		return nil
+	}
+}
+
+func toResponse(t *testing.T, res *discoveryv3.DeltaDiscoveryResponse) response {
+	t.Helper()
+
+	r := response{
+		resources: map[string][]string{},
+		removed:   res.GetRemovedResources(),
+	}
+	for _, resource := range res.GetResources() {
+		r.resources[resource.GetName()] = resource.GetAliases()
+	}
+
+	return r
+}
@@ -0,0 +1,300 @@
+package delta
+
+import (
+	"sort"
+	"strings"
+
+	routev3 "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+
+	"github.com/110y/bootes/internal/xds/cache"
+)
+
+// subscription is the state of a type URL on an incremental xDS stream.
+// versions holds the versions of the resources acknowledged by the client,
+// while the ones of the responses which have not been acknowledged yet are held in pending until the client ACKs or NACKs them.
+type subscription struct {
+	typeURL     string
+	node        string
+	wildcard    bool
+	names       map[string]struct{}
+	versions    map[string]string
+	pending     []*pendingResponse
+	answered    map[string]struct{}
+	lastRequest *discoveryv3.DeltaDiscoveryRequest
+}
+
+// pendingResponse is a response waiting for the ACK, whose changes map the names of the resources to their versions,
+// or to empty ones for the removed resources.
+type pendingResponse struct {
+	nonce   string
+	changes map[string]string
+}
+
+type entry struct {
+	resource types.Resource
+	aliases  []string
+}
+
+func newSubscription(typeURL string, req *discoveryv3.DeltaDiscoveryRequest) *subscription {
+	versions := make(map[string]string, len(req.GetInitialResourceVersions()))
+	for name, v := range req.GetInitialResourceVersions() {
+		versions[name] = v
+	}
+
+	return &subscription{
+		typeURL:  typeURL,
+		node:     req.GetNode().GetId(),
+		wildcard: len(req.GetResourceNamesSubscribe()) == 0,
+		names:    map[string]struct{}{},
+		versions: versions,
+		answered: map[string]struct{}{},
+	}
+}
+
+// update applies the request to the subscription, and reports whether the request NACKs the last response of the subscription.
+func (s *subscription) update(req *discoveryv3.DeltaDiscoveryRequest) bool {
+	nacked := s.acknowledge(req.GetResponseNonce(), req.GetErrorDetail() == nil)
+
+	for _, name := range req.GetResourceNamesSubscribe() {
+		s.names[name] = struct{}{}
+		// NOTE: answer aliases again since the client subscribes to them every time it needs them.
+		delete(s.answered, name)
+	}
+
+	for _, name := range req.GetResourceNamesUnsubscribe() {
+		delete(s.names, name)
+		delete(s.versions, name)
+		delete(s.answered, name)
+		for _, p := range s.pending {
+			delete(p.changes, name)
+		}
+	}
+
+	s.lastRequest = req
+
+	return nacked
+}
+
+// acknowledge applies the changes of the response of the nonce to the versions if the client accepts it, or discards them otherwise,
+// so that the resources rejected by the client are sent again on the next update.
+// It reports whether the response has been rejected.
+func (s *subscription) acknowledge(nonce string, accepted bool) bool {
+	if nonce == "" {
+		return false
+	}
+
+	for i, p := range s.pending {
+		if p.nonce != nonce {
+			continue
+		}
+
+		if accepted {
+			for name, v := range p.changes {
+				if v == "" {
+					delete(s.versions, name)
+				} else {
+					s.versions[name] = v
+				}
+			}
+		}
+
+		s.pending = append(s.pending[:i], s.pending[i+1:]...)
+		break
+	}
+
+	return !accepted
+}
+
+// sentVersions returns the versions of the resources which the client has, assuming that the pending responses will be acknowledged.
+func (s *subscription) sentVersions() map[string]string {
+	versions := make(map[string]string, len(s.versions))
+	for name, v := range s.versions {
+		versions[name] = v
+	}
+
+	for _, p := range s.pending {
+		for name, v := range p.changes {
+			if v == "" {
+				delete(versions, name)
+			} else {
+				versions[name] = v
+			}
+		}
+	}
+
+	return versions
+}
+
+func (s *subscription) addPending(nonce string, changes map[string]string) {
+	if len(changes) == 0 {
+		return
+	}
+
+	s.pending = append(s.pending, &pendingResponse{
+		nonce:   nonce,
+		changes: changes,
+	})
+}
+
+// hasUnansweredAlias reports whether the entry includes aliases which have not been answered yet.
+func (s *subscription) hasUnansweredAlias(e *entry) bool {
+	for _, alias := range e.aliases {
+		if _, ok := s.answered[alias]; !ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *subscription) markAnswered(aliases ...string) {
+	for _, alias := range aliases {
+		s.answered[alias] = struct{}{}
+	}
+}
+
+// resolve returns the resources which the client subscribes to, keyed by their names.
+// For virtual hosts, a subscribed name is either a route configuration name, which subscribes to all virtual hosts of it,
+// or an alias formed as `<route configuration name>/<host>` which Envoy uses to fetch a virtual host on demand.
+func (s *subscription) resolve(resources map[string]types.Resource) map[string]*entry {
+	desired := map[string]*entry{}
+	add := func(name string, r types.Resource, alias string) {
+		e, ok := desired[name]
+		if !ok || e.resource == nil {
+			e = &entry{resource: r}
+			desired[name] = e
+		}
+		if alias != "" {
+			e.aliases = append(e.aliases, alias)
+		}
+	}
+
+	if s.wildcard {
+		for name, r := range resources {
+			add(name, r, "")
+		}
+	}
+
+	for _, name := range sortedSubscribedNames(s.names) {
+		if r, ok := resources[name]; ok {
+			add(name, r, "")
+			continue
+		}
+
+		if s.typeURL != cache.VirtualHostTypeV3 {
+			continue
+		}
+
+		if found := addVirtualHostsOfRouteConfiguration(resources, name, add); found {
+			continue
+		}
+
+		i := strings.LastIndex(name, "/")
+		if i < 0 {
+			continue
+		}
+
+		if n, r, ok := findVirtualHostByDomain(resources, name[:i], name[i+1:]); ok {
+			add(n, r, name)
+			continue
+		}
+
+		if _, ok := desired[name]; !ok {
+			desired[name] = &entry{}
+		}
+	}
+
+	return desired
+}
+
+func addVirtualHostsOfRouteConfiguration(resources map[string]types.Resource, routeConfigurationName string, add func(string, types.Resource, string)) bool {
+	prefix := routeConfigurationName + "/"
+
+	found := false
+	for name, r := range resources {
+		if strings.HasPrefix(name, prefix) {
+			add(name, r, "")
+			found = true
+		}
+	}
+
+	return found
+}
+
+// findVirtualHostByDomain finds the virtual host of the route configuration matching the host
+// in the same precedence as Envoy: exact match, suffix wildcard, prefix wildcard and then the special wildcard `*`.
+func findVirtualHostByDomain(resources map[string]types.Resource, routeConfigurationName, host string) (string, types.Resource, bool) {
+	prefix := routeConfigurationName + "/"
+	host = strings.ToLower(host)
+
+	var (
+		bestName     string
+		bestResource types.Resource
+		bestRank     = -1
+		bestLength   = -1
+	)
+
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		vh, ok := resources[name].(*routev3.VirtualHost)
+		if !ok {
+			continue
+		}
+
+		for _, d := range vh.GetDomains() {
+			rank, ok := matchDomain(strings.ToLower(d), host)
+			if !ok {
+				continue
+			}
+
+			if rank > bestRank || (rank == bestRank && len(d) > bestLength) {
+				bestName, bestResource, bestRank, bestLength = name, vh, rank, len(d)
+			}
+		}
+	}
+
+	return bestName, bestResource, bestRank >= 0
+}
+
+const (
+	rankAnyWildcard = iota
+	rankPrefixWildcard
+	rankSuffixWildcard
+	rankExact
+)
+
+func matchDomain(domain, host string) (int, bool) {
+	switch {
+	case domain == "*":
+		return rankAnyWildcard, true
+	case domain == host:
+		return rankExact, true
+	case strings.HasPrefix(domain, "*"):
+		suffix := domain[1:]
+		return rankSuffixWildcard, len(host) > len(suffix) && strings.HasSuffix(host, suffix)
+	case strings.HasSuffix(domain, "*"):
+		prefix := domain[:len(domain)-1]
+		return rankPrefixWildcard, len(host) > len(prefix) && strings.HasPrefix(host, prefix)
+	default:
+		return 0, false
+	}
+}
+
+func sortedSubscribedNames(names map[string]struct{}) []string {
+	results := make([]string, 0, len(names))
+	for name := range names {
+		results = append(results, name)
+	}
+	sort.Strings(results)
+
+	return results
+}
@@ -4,15 +4,26 @@ import (
 	"context"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v2"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	routeservicev3 "github.com/envoyproxy/go-control-plane/envoy/service/route/v3"
 	"google.golang.org/grpc"
 	channelz "google.golang.org/grpc/channelz/service"
 	"google.golang.org/grpc/reflection"
 )
 
-func NewServer(ctx context.Context, xs discovery.AggregatedDiscoveryServiceServer, config *Config) *grpc.Server {
+// DeltaServer serves resources which are only available over the incremental xDS protocol.
+type DeltaServer interface {
+	routeservicev3.ScopedRoutesDiscoveryServiceServer
+	routeservicev3.VirtualHostDiscoveryServiceServer
+}
+
+func NewServer(ctx context.Context, xs discovery.AggregatedDiscoveryServiceServer, xsv3 discoveryv3.AggregatedDiscoveryServiceServer, ds DeltaServer, config *Config) *grpc.Server {
 	gs := grpc.NewServer()
 
 	discovery.RegisterAggregatedDiscoveryServiceServer(gs, xs)
+	discoveryv3.RegisterAggregatedDiscoveryServiceServer(gs, xsv3)
+	routeservicev3.RegisterScopedRoutesDiscoveryServiceServer(gs, ds)
+	routeservicev3.RegisterVirtualHostDiscoveryServiceServer(gs, ds)
 
 	if config.EnableChannelz {
 		channelz.RegisterChannelzServiceToServer(gs)
@@ -0,0 +1,116 @@
+package xds
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/110y/bootes/internal/xds/ack"
+)
+
+const metricsNamespace = "bootes"
+
+// metrics of requests and responses on the streams of all servers.
+type metrics struct {
+	streams   *prometheus.GaugeVec
+	requests  *prometheus.CounterVec
+	responses *prometheus.CounterVec
+	sentBytes *prometheus.CounterVec
+
+	mu          sync.Mutex
+	streamTypes map[ack.StreamID]map[string]struct{}
+}
+
+func newMetrics(registerer prometheus.Registerer) (*metrics, error) {
+	m := &metrics{
+		streams: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "xds",
+			Name:      "streams",
+			Help:      "Number of open streams which have requested the type URL.",
+		}, []string{"type_url"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "xds",
+			Name:      "requests_total",
+			Help:      "Total number of requests from nodes.",
+		}, []string{"node"}),
+		responses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "xds",
+			Name:      "responses_total",
+			Help:      "Total number of responses sent to nodes.",
+		}, []string{"node"}),
+		sentBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: "xds",
+			Name:      "sent_bytes_total",
+			Help:      "Total size of responses sent to nodes in bytes.",
+		}, []string{"type_url"}),
+		streamTypes: map[ack.StreamID]map[string]struct{}{},
+	}
+
+	for _, c := range []prometheus.Collector{m.streams, m.requests, m.responses, m.sentBytes} {
+		if err := registerer.Register(c); err != nil {
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+	}
+
+	return m, nil
+}
+
+// onStreamRequest counts the request, and the stream for the type URL when it is requested on the stream for the first time.
+func (m *metrics) onStreamRequest(streamID ack.StreamID, node, typeURL string) {
+	m.onRequest(node)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	types, ok := m.streamTypes[streamID]
+	if !ok {
+		types = map[string]struct{}{}
+		m.streamTypes[streamID] = types
+	}
+
+	if _, ok := types[typeURL]; ok {
+		return
+	}
+
+	types[typeURL] = struct{}{}
+	m.streams.WithLabelValues(typeURL).Inc()
+}
+
+func (m *metrics) onStreamClosed(streamID ack.StreamID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for typeURL := range m.streamTypes[streamID] {
+		m.streams.WithLabelValues(typeURL).Dec()
+	}
+
+	delete(m.streamTypes, streamID)
+}
+
+func (m *metrics) onRequest(node string) {
+	if node == "" {
+		return
+	}
+
+	m.requests.WithLabelValues(node).Inc()
+}
+
+func (m *metrics) onResponse(node, typeURL string, resp proto.Message) {
+	if node != "" {
+		m.responses.WithLabelValues(node).Inc()
+	}
+
+	m.sentBytes.WithLabelValues(typeURL).Add(float64(proto.Size(resp)))
+}
+
+// forgetNode deletes the metrics of the evicted node, so that the ones of nodes which never come back do not pile up.
+func (m *metrics) forgetNode(node string) {
+	m.requests.DeleteLabelValues(node)
+	m.responses.DeleteLabelValues(node)
+}
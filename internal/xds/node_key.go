@@ -0,0 +1,50 @@
+package xds
+
+import (
+	envoycore "github.com/envoyproxy/go-control-plane/envoy/api/v2/core"
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	xdscache "github.com/envoyproxy/go-control-plane/pkg/cache/v2"
+	xdscachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+
+	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/internal/delta"
+	"github.com/110y/bootes/internal/xds/nodeid"
+)
+
+var (
+	_ xdscache.NodeHash   = nodeHash{}
+	_ xdscachev3.NodeHash = nodeHashV3{}
+	_ delta.Cache         = nodeKeyCache{}
+)
+
+// nodeHash makes snapshot caches look up snapshots by the keys of nodes, which reconcilers produce, instead of their IDs.
+type nodeHash struct {
+	format nodeid.Format
+}
+
+func (h nodeHash) ID(node *envoycore.Node) string {
+	return nodeid.Key(h.format, node.GetId())
+}
+
+type nodeHashV3 struct {
+	format nodeid.Format
+}
+
+func (h nodeHashV3) ID(node *corev3.Node) string {
+	return nodeid.Key(h.format, node.GetId())
+}
+
+// nodeKeyCache is the counterpart of nodeHash for the delta server.
+type nodeKeyCache struct {
+	cache  cache.Cache
+	format nodeid.Format
+}
+
+func (c nodeKeyCache) GetResources(node, typeURL string) (string, map[string]types.Resource) {
+	return c.cache.GetResources(nodeid.Key(c.format, node), typeURL)
+}
+
+func (c nodeKeyCache) Watch(node string) (<-chan struct{}, func()) {
+	return c.cache.Watch(nodeid.Key(c.format, node))
+}
@@ -0,0 +1,47 @@
+package nodeid
+
+import (
+	"fmt"
+	"strings"
+)
+
+var _ Format = Istio{}
+
+const (
+	istioSeparator  = "~"
+	istioNodeType   = "sidecar"
+	istioDomainPart = "svc.cluster.local"
+)
+
+// Istio is the format of node IDs like `sidecar~<ip>~<name>.<namespace>~<namespace>.svc.cluster.local`.
+// Since the IP address can not be known from the workload, keys of nodes leave it empty.
+type Istio struct{}
+
+func (Istio) Parse(id string) (string, string, bool) {
+	parts := strings.Split(id, istioSeparator)
+	if len(parts) != 4 {
+		return "", "", false
+	}
+
+	// NOTE: take the namespace from the domain since the name may contain dots.
+	namespace := strings.SplitN(parts[3], ".", 2)[0]
+	if namespace == "" {
+		return "", "", false
+	}
+
+	name := strings.TrimSuffix(parts[2], "."+namespace)
+	if name == "" || name == parts[2] {
+		return "", "", false
+	}
+
+	return name, namespace, true
+}
+
+func (Istio) Key(name, namespace string) string {
+	return strings.Join([]string{
+		istioNodeType,
+		"",
+		fmt.Sprintf("%s.%s", name, namespace),
+		fmt.Sprintf("%s.%s", namespace, istioDomainPart),
+	}, istioSeparator)
+}
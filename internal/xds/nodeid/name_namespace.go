@@ -0,0 +1,23 @@
+package nodeid
+
+import (
+	"github.com/110y/bootes/internal/k8s/store"
+)
+
+var _ Format = NameNamespace{}
+
+// NameNamespace is the format of node IDs like `<name>.<namespace>`.
+type NameNamespace struct{}
+
+func (NameNamespace) Parse(id string) (string, string, bool) {
+	name, namespace := store.ToNamespacedName(id)
+	if name == "" || namespace == "" {
+		return "", "", false
+	}
+
+	return name, namespace, true
+}
+
+func (NameNamespace) Key(name, namespace string) string {
+	return store.ToNodeName(name, namespace)
+}
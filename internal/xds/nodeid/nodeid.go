@@ -0,0 +1,46 @@
+package nodeid
+
+import (
+	"fmt"
+)
+
+const (
+	FormatNameNamespace = "name.namespace"
+	FormatIstio         = "istio"
+	FormatTemplate      = "template"
+)
+
+// Format converts between node IDs and the workloads identified by them.
+// Key(Parse(id)) must be a node ID in the format which identifies the same workload,
+// so that keys produced by reconcilers match the keys of the connected nodes.
+type Format interface {
+	// Parse returns the name and the namespace of the workload identified by the node ID.
+	Parse(id string) (name, namespace string, ok bool)
+	// Key returns the key by which resources of the node of the workload are cached.
+	Key(name, namespace string) string
+}
+
+// New returns the Format of the name. The template is used only by FormatTemplate.
+func New(name, template string) (Format, error) {
+	switch name {
+	case "", FormatNameNamespace:
+		return NameNamespace{}, nil
+	case FormatIstio:
+		return Istio{}, nil
+	case FormatTemplate:
+		return NewTemplate(template)
+	default:
+		return nil, fmt.Errorf("unknown node id format: %s", name)
+	}
+}
+
+// Key returns the key of the node by which its resources are cached.
+// Node IDs not in the format, e.g. the ones of nodes identified by their metadata, are used as keys as they are.
+func Key(f Format, id string) string {
+	name, namespace, ok := f.Parse(id)
+	if !ok {
+		return id
+	}
+
+	return f.Key(name, namespace)
+}
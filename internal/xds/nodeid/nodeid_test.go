@@ -0,0 +1,156 @@
+package nodeid_test
+
+import (
+	"testing"
+
+	"github.com/110y/bootes/internal/xds/nodeid"
+)
+
+func TestFormats(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		format            string
+		template          string
+		id                string
+		expectedOK        bool
+		expectedName      string
+		expectedNamespace string
+		expectedKey       string
+	}{
+		"name.namespace": {
+			format:            nodeid.FormatNameNamespace,
+			id:                "pod-1.ns-1",
+			expectedOK:        true,
+			expectedName:      "pod-1",
+			expectedNamespace: "ns-1",
+			expectedKey:       "pod-1.ns-1",
+		},
+		"name.namespace without namespace": {
+			format:      nodeid.FormatNameNamespace,
+			id:          "ci-runner",
+			expectedKey: "ci-runner",
+		},
+		"istio sidecar": {
+			format:            nodeid.FormatIstio,
+			id:                "sidecar~10.0.0.1~pod-1.ns-1~ns-1.svc.cluster.local",
+			expectedOK:        true,
+			expectedName:      "pod-1",
+			expectedNamespace: "ns-1",
+			expectedKey:       "sidecar~~pod-1.ns-1~ns-1.svc.cluster.local",
+		},
+		"istio sidecar whose name contains dots": {
+			format:            nodeid.FormatIstio,
+			id:                "sidecar~10.0.0.1~pod.v1.ns-1~ns-1.svc.cluster.local",
+			expectedOK:        true,
+			expectedName:      "pod.v1",
+			expectedNamespace: "ns-1",
+			expectedKey:       "sidecar~~pod.v1.ns-1~ns-1.svc.cluster.local",
+		},
+		"istio sidecar with mismatched namespace": {
+			format:      nodeid.FormatIstio,
+			id:          "sidecar~10.0.0.1~pod-1.ns-1~ns-2.svc.cluster.local",
+			expectedKey: "sidecar~10.0.0.1~pod-1.ns-1~ns-2.svc.cluster.local",
+		},
+		"istio with name.namespace id": {
+			format:      nodeid.FormatIstio,
+			id:          "pod-1.ns-1",
+			expectedKey: "pod-1.ns-1",
+		},
+		"template": {
+			format:            nodeid.FormatTemplate,
+			template:          "{{.Namespace}}/{{.Name}}",
+			id:                "ns-1/pod.v1",
+			expectedOK:        true,
+			expectedName:      "pod.v1",
+			expectedNamespace: "ns-1",
+			expectedKey:       "ns-1/pod.v1",
+		},
+		"template with prefix": {
+			format:            nodeid.FormatTemplate,
+			template:          "envoy~{{.Name}}~{{.Namespace}}",
+			id:                "envoy~pod-1~ns-1",
+			expectedOK:        true,
+			expectedName:      "pod-1",
+			expectedNamespace: "ns-1",
+			expectedKey:       "envoy~pod-1~ns-1",
+		},
+		"template mismatch": {
+			format:      nodeid.FormatTemplate,
+			template:    "{{.Namespace}}/{{.Name}}",
+			id:          "pod-1.ns-1",
+			expectedKey: "pod-1.ns-1",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := nodeid.New(test.format, test.template)
+			if err != nil {
+				t.Fatalf("failed to create format: %s", err)
+			}
+
+			name, namespace, ok := f.Parse(test.id)
+			if ok != test.expectedOK {
+				t.Fatalf("unexpected ok: %t", ok)
+			}
+
+			if name != test.expectedName || namespace != test.expectedNamespace {
+				t.Errorf("want: %s/%s, but got %s/%s", test.expectedNamespace, test.expectedName, namespace, name)
+			}
+
+			key := nodeid.Key(f, test.id)
+			if key != test.expectedKey {
+				t.Errorf("key, want: %s, but got %s", test.expectedKey, key)
+			}
+
+			if !ok {
+				return
+			}
+
+			name, namespace, ok = f.Parse(key)
+			if !ok || name != test.expectedName || namespace != test.expectedNamespace {
+				t.Errorf("key must identify the same workload: %s", key)
+			}
+		})
+	}
+}
+
+func TestNewInvalidFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		format   string
+		template string
+	}{
+		"unknown format": {
+			format: "unknown",
+		},
+		"template without namespace": {
+			format:   nodeid.FormatTemplate,
+			template: "{{.Name}}",
+		},
+		"template with duplicated name": {
+			format:   nodeid.FormatTemplate,
+			template: "{{.Name}}.{{.Namespace}}.{{.Name}}",
+		},
+		"broken template": {
+			format:   nodeid.FormatTemplate,
+			template: "{{.Name",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := nodeid.New(test.format, test.template); err == nil {
+				t.Error("expected error, but got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package nodeid
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+var _ Format = (*Template)(nil)
+
+const (
+	templateNameMarker      = "\x00name\x00"
+	templateNamespaceMarker = "\x00namespace\x00"
+)
+
+// Template is the format of node IDs rendered by a text/template with `.Name` and `.Namespace`, e.g. `{{.Namespace}}/{{.Name}}`.
+type Template struct {
+	template       *template.Template
+	pattern        *regexp.Regexp
+	nameIndex      int
+	namespaceIndex int
+}
+
+type templateData struct {
+	Name      string
+	Namespace string
+}
+
+func NewTemplate(text string) (*Template, error) {
+	tmpl, err := template.New("node_id").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse node id template: %w", err)
+	}
+
+	t := &Template{template: tmpl}
+
+	rendered, err := t.render(templateNameMarker, templateNamespaceMarker)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.Count(rendered, templateNameMarker) != 1 || strings.Count(rendered, templateNamespaceMarker) != 1 {
+		return nil, fmt.Errorf("node id template must contain both .Name and .Namespace exactly once: %s", text)
+	}
+
+	// NOTE: namespaces are DNS labels, which lets the name contain any characters but the ones around it.
+	pattern := regexp.QuoteMeta(rendered)
+	pattern = strings.Replace(pattern, templateNameMarker, "(?P<name>.+)", 1)
+	pattern = strings.Replace(pattern, templateNamespaceMarker, "(?P<namespace>[a-z0-9]([-a-z0-9]*[a-z0-9])?)", 1)
+
+	t.pattern, err = regexp.Compile("^" + pattern + "$")
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile node id template: %w", err)
+	}
+
+	for i, n := range t.pattern.SubexpNames() {
+		switch n {
+		case "name":
+			t.nameIndex = i
+		case "namespace":
+			t.namespaceIndex = i
+		}
+	}
+
+	return t, nil
+}
+
+func (t *Template) Parse(id string) (string, string, bool) {
+	match := t.pattern.FindStringSubmatch(id)
+	if match == nil {
+		return "", "", false
+	}
+
+	return match[t.nameIndex], match[t.namespaceIndex], true
+}
+
+func (t *Template) Key(name, namespace string) string {
+	// NOTE: the template has been executed successfully in NewTemplate.
+	key, _ := t.render(name, namespace)
+	return key
+}
+
+func (t *Template) render(name, namespace string) (string, error) {
+	var buf bytes.Buffer
+	if err := t.template.Execute(&buf, templateData{Name: name, Namespace: namespace}); err != nil {
+		return "", fmt.Errorf("failed to execute node id template: %w", err)
+	}
+
+	return buf.String(), nil
+}
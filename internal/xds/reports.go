@@ -0,0 +1,93 @@
+package xds
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// report is a change of acceptance of a type URL by a node, which is either a rejection or a recovery.
+type report struct {
+	node     string
+	typeURL  string
+	message  string
+	rejected bool
+	logger   logr.Logger
+}
+
+// reportQueue sends reports to the reporter one by one in the order they have been enqueued by a single worker,
+// so that a recovery never lands before the rejection preceding it, while streams are not blocked by writes to the API server.
+type reportQueue struct {
+	reporter NACKReporter
+
+	mu      sync.Mutex
+	reports []report
+	notify  chan struct{}
+}
+
+func newReportQueue(r NACKReporter) *reportQueue {
+	return &reportQueue{
+		reporter: r,
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+func (q *reportQueue) enqueue(r report) {
+	q.mu.Lock()
+	q.reports = append(q.reports, r)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run sends enqueued reports until the context is canceled.
+func (q *reportQueue) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.notify:
+		}
+
+		for {
+			r, ok := q.dequeue()
+			if !ok {
+				break
+			}
+
+			q.send(ctx, r)
+		}
+	}
+}
+
+func (q *reportQueue) dequeue() (report, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.reports) == 0 {
+		return report{}, false
+	}
+
+	r := q.reports[0]
+	q.reports[0] = report{}
+	q.reports = q.reports[1:]
+
+	return r, true
+}
+
+func (q *reportQueue) send(ctx context.Context, r report) {
+	if r.rejected {
+		if err := q.reporter.ReportRejected(ctx, r.node, r.typeURL, r.message); err != nil {
+			r.logger.Error(err, "failed to report rejection")
+		}
+		return
+	}
+
+	if err := q.reporter.ReportRecovered(ctx, r.node, r.typeURL); err != nil {
+		r.logger.Error(err, "failed to report recovery")
+	}
+}
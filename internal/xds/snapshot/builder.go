@@ -0,0 +1,190 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/observer/trace"
+	"github.com/110y/bootes/internal/xds/cache"
+)
+
+// Builder builds all resources of a node from the resources in the store, which are selected by the labels of its pod.
+type Builder struct {
+	store store.Store
+	cache cache.Cache
+}
+
+func NewBuilder(s store.Store, c cache.Cache) *Builder {
+	return &Builder{
+		store: s,
+		cache: c,
+	}
+}
+
+// Build replaces all resources of the node at once by the resources visible from the namespace and selected by the labels.
+// The node is marked as being built meanwhile, so that reconcilers enqueue updates of the node which may not be listed by the build.
+func (b *Builder) Build(ctx context.Context, node, namespace string, labels map[string]string) error {
+	ctx, span := trace.NewSpan(ctx, "Builder.Build")
+	defer span.End()
+
+	done := b.cache.BeginBuild(node)
+	defer done()
+
+	return b.build(ctx, node, namespace, labels, false)
+}
+
+// Rebuild is the same as Build except that it does nothing if the node has not been cached,
+// so that resources of the node evicted while they are being built are not restored.
+func (b *Builder) Rebuild(ctx context.Context, node, namespace string, labels map[string]string) error {
+	ctx, span := trace.NewSpan(ctx, "Builder.Rebuild")
+	defer span.End()
+
+	return b.build(ctx, node, namespace, labels, true)
+}
+
+func (b *Builder) build(ctx context.Context, node, namespace string, labels map[string]string, onlyCached bool) error {
+	start := time.Now()
+	defer func() {
+		buildDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	// NOTE: list resources while the lock of the node is held, so that resources updated by others meanwhile are never overwritten by the ones listed earlier.
+	fn := func(s *cache.Snapshot) error {
+		clusters, err := b.listClustersByNodeAndLabels(ctx, namespace, labels)
+		if err != nil {
+			return fmt.Errorf("failed to list cluster configurations: %w", err)
+		}
+
+		listeners, err := b.listListenersByNodeAndLabels(ctx, namespace, labels)
+		if err != nil {
+			return fmt.Errorf("failed to list listener configurations: %w", err)
+		}
+
+		routes, err := b.listRoutesByNodeAndLabels(ctx, namespace, labels)
+		if err != nil {
+			return fmt.Errorf("failed to list route configurations: %w", err)
+		}
+
+		endpoints, err := b.listEndpointsByNodeAndLabels(ctx, namespace, labels)
+		if err != nil {
+			return fmt.Errorf("failed to list endpoint configurations: %w", err)
+		}
+
+		secrets, err := b.listSecretsByNodeAndLabels(ctx, namespace, labels)
+		if err != nil {
+			return fmt.Errorf("failed to list secret configurations: %w", err)
+		}
+
+		runtimes, err := b.listRuntimesByNodeAndLabels(ctx, namespace, labels)
+		if err != nil {
+			return fmt.Errorf("failed to list runtime configurations: %w", err)
+		}
+
+		scopedRoutes, err := b.listScopedRoutesByNodeAndLabels(ctx, namespace, labels)
+		if err != nil {
+			return fmt.Errorf("failed to list scoped route configurations: %w", err)
+		}
+
+		virtualHosts, err := b.listVirtualHostsByNodeAndLabels(ctx, namespace, labels)
+		if err != nil {
+			return fmt.Errorf("failed to list virtual host configurations: %w", err)
+		}
+
+		s.SetClusters(clusters)
+		s.SetListeners(listeners)
+		s.SetRoutes(routes)
+		s.SetEndpoints(endpoints)
+		s.SetSecrets(secrets)
+		s.SetRuntimes(runtimes)
+		s.SetScopedRoutes(scopedRoutes)
+		s.SetVirtualHosts(virtualHosts)
+		return nil
+	}
+
+	var err error
+	if onlyCached {
+		_, err = b.cache.UpdateIfCached(ctx, node, fn)
+	} else {
+		err = b.cache.Update(ctx, node, fn)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update resources: %w", err)
+	}
+
+	return nil
+}
+
+func (b *Builder) listClustersByNodeAndLabels(ctx context.Context, namespace string, labels map[string]string) ([]*api.Cluster, error) {
+	clusters, err := b.store.ListClustersByNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.FilterClustersByLabels(clusters.Items, labels), nil
+}
+
+func (b *Builder) listListenersByNodeAndLabels(ctx context.Context, namespace string, labels map[string]string) ([]*api.Listener, error) {
+	listeners, err := b.store.ListListenersByNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.FilterListenersByLabels(listeners.Items, labels), nil
+}
+
+func (b *Builder) listRoutesByNodeAndLabels(ctx context.Context, namespace string, labels map[string]string) ([]*api.Route, error) {
+	routes, err := b.store.ListRoutesByNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.FilterRoutesByLabels(routes.Items, labels), nil
+}
+
+func (b *Builder) listEndpointsByNodeAndLabels(ctx context.Context, namespace string, labels map[string]string) ([]*api.Endpoint, error) {
+	endpoints, err := b.store.ListEndpointsByNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.FilterEndpointsByLabels(endpoints.Items, labels), nil
+}
+
+func (b *Builder) listSecretsByNodeAndLabels(ctx context.Context, namespace string, labels map[string]string) ([]*api.Secret, error) {
+	secrets, err := b.store.ListSecretsByNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.FilterSecretsByLabels(secrets.Items, labels), nil
+}
+
+func (b *Builder) listRuntimesByNodeAndLabels(ctx context.Context, namespace string, labels map[string]string) ([]*api.Runtime, error) {
+	runtimes, err := b.store.ListRuntimesByNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.FilterRuntimesByLabels(runtimes.Items, labels), nil
+}
+
+func (b *Builder) listScopedRoutesByNodeAndLabels(ctx context.Context, namespace string, labels map[string]string) ([]*api.ScopedRoute, error) {
+	scopedRoutes, err := b.store.ListScopedRoutesByNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.FilterScopedRoutesByLabels(scopedRoutes.Items, labels), nil
+}
+
+func (b *Builder) listVirtualHostsByNodeAndLabels(ctx context.Context, namespace string, labels map[string]string) ([]*api.VirtualHost, error) {
+	virtualHosts, err := b.store.ListVirtualHostsByNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.FilterVirtualHostsByLabels(virtualHosts.Items, labels), nil
+}
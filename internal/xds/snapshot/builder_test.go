@@ -0,0 +1,177 @@
+package snapshot_test
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	envoyapi "github.com/envoyproxy/go-control-plane/envoy/api/v2"
+	clusterv3 "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/cache/types"
+	xdscache "github.com/envoyproxy/go-control-plane/pkg/cache/v2"
+	xdscachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
+	resourcev3 "github.com/envoyproxy/go-control-plane/pkg/resource/v3"
+	"github.com/google/go-cmp/cmp"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/snapshot"
+)
+
+// fakeStore calls onListClusters once when clusters are listed for the first time.
+type fakeStore struct {
+	store.Store
+
+	mu             sync.Mutex
+	clusters       []*api.Cluster
+	onListClusters func()
+}
+
+func (s *fakeStore) setClusters(clusters []*api.Cluster) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clusters = clusters
+}
+
+func (s *fakeStore) ListClustersByNamespace(context.Context, string) (*api.ClusterList, error) {
+	s.mu.Lock()
+	clusters := s.clusters
+	fn := s.onListClusters
+	s.onListClusters = nil
+	s.mu.Unlock()
+
+	if fn != nil {
+		fn()
+	}
+
+	return &api.ClusterList{Items: clusters}, nil
+}
+
+func (s *fakeStore) ListListenersByNamespace(context.Context, string) (*api.ListenerList, error) {
+	return &api.ListenerList{}, nil
+}
+
+func (s *fakeStore) ListRoutesByNamespace(context.Context, string) (*api.RouteList, error) {
+	return &api.RouteList{}, nil
+}
+
+func (s *fakeStore) ListEndpointsByNamespace(context.Context, string) (*api.EndpointList, error) {
+	return &api.EndpointList{}, nil
+}
+
+func (s *fakeStore) ListSecretsByNamespace(context.Context, string) (*api.SecretList, error) {
+	return &api.SecretList{}, nil
+}
+
+func (s *fakeStore) ListRuntimesByNamespace(context.Context, string) (*api.RuntimeList, error) {
+	return &api.RuntimeList{}, nil
+}
+
+func (s *fakeStore) ListScopedRoutesByNamespace(context.Context, string) (*api.ScopedRouteList, error) {
+	return &api.ScopedRouteList{}, nil
+}
+
+func (s *fakeStore) ListVirtualHostsByNamespace(context.Context, string) (*api.VirtualHostList, error) {
+	return &api.VirtualHostList{}, nil
+}
+
+func newCluster(name string) *api.Cluster {
+	return &api.Cluster{
+		Spec: api.ClusterSpec{
+			Config:   &envoyapi.Cluster{Name: name},
+			ConfigV3: &clusterv3.Cluster{Name: name},
+		},
+	}
+}
+
+func resourceNames(resources map[string]types.Resource) []string {
+	var names []string
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestBuilderRebuildDoesNotOverwriteLaterUpdates(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	c := cache.New(
+		xdscache.NewSnapshotCache(false, xdscache.IDHash{}, nil),
+		xdscachev3.NewSnapshotCache(false, xdscachev3.IDHash{}, nil),
+	)
+	if err := c.UpdateAllResources(ctx, "node-1", nil, nil, nil, nil, nil, nil); err != nil {
+		t.Fatalf("failed to cache node: %s", err)
+	}
+
+	s := &fakeStore{clusters: []*api.Cluster{newCluster("cluster-1")}}
+
+	// NOTE: the cluster is updated, and the update of the type is applied by the reconciler, while the builder is listing the old one.
+	updated := make(chan struct{})
+	s.onListClusters = func() {
+		s.setClusters([]*api.Cluster{newCluster("cluster-2")})
+
+		go func() {
+			defer close(updated)
+			if err := c.UpdateClusters(ctx, "node-1", []*api.Cluster{newCluster("cluster-2")}); err != nil {
+				t.Errorf("failed to update clusters: %s", err)
+			}
+		}()
+
+		// NOTE: the update can not complete until the rebuild completes if the builder lists resources under the lock of the node.
+		select {
+		case <-updated:
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	if err := snapshot.NewBuilder(s, c).Rebuild(ctx, "node-1", "ns-1", nil); err != nil {
+		t.Fatalf("failed to rebuild: %s", err)
+	}
+	<-updated
+
+	_, clusters := c.GetResources("node-1", resourcev3.ClusterType)
+	if diff := cmp.Diff([]string{"cluster-2"}, resourceNames(clusters)); diff != "" {
+		t.Errorf("\n(-expected, +actual)\n%s", diff)
+	}
+}
+
+func TestBuilderBuildMarksNodeBuilding(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	c := cache.New(
+		xdscache.NewSnapshotCache(false, xdscache.IDHash{}, nil),
+		xdscachev3.NewSnapshotCache(false, xdscachev3.IDHash{}, nil),
+	)
+
+	// NOTE: reconcilers enqueue updates of the node for changes made after the build has listed resources.
+	var building bool
+	s := &fakeStore{onListClusters: func() {
+		building = c.IsBuildingNode("node-1")
+	}}
+
+	if err := snapshot.NewBuilder(s, c).Build(ctx, "node-1", "ns-1", nil); err != nil {
+		t.Fatalf("failed to build: %s", err)
+	}
+
+	if !building {
+		t.Error("node must be building while resources are listed")
+	}
+
+	if c.IsBuildingNode("node-1") {
+		t.Error("node must not be building after the build has completed")
+	}
+
+	if !c.IsCachedNode("node-1") {
+		t.Error("node must be cached after the build has completed")
+	}
+}
@@ -0,0 +1,25 @@
+package snapshot
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// buildDuration is shared by all builders, which are created for each of the xDS server and the controllers.
+var buildDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+	Namespace: "bootes",
+	Subsystem: "xds",
+	Name:      "snapshot_build_duration_seconds",
+	Help:      "Latency of building all resources of a node.",
+	Buckets:   prometheus.DefBuckets,
+})
+
+// RegisterMetrics registers the metrics of builders.
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	if err := registerer.Register(buildDuration); err != nil {
+		return fmt.Errorf("failed to register metrics: %w", err)
+	}
+
+	return nil
+}
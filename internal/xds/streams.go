@@ -0,0 +1,157 @@
+package xds
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/110y/bootes/internal/xds/ack"
+	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/workload"
+)
+
+// nodeStreams tracks open streams of each node, and clears resources of the node from the cache,
+// as well as its workload declared by node.metadata, its acceptance of responses and its metrics, once the grace period has passed since all of its streams were closed.
+// The grace period keeps resources of nodes which reconnect soon, e.g. on restarts of the control-plane or network blips.
+type nodeStreams struct {
+	mu          sync.Mutex
+	cache       cache.Cache
+	resolver    *workload.Resolver
+	tracker     *ack.Tracker
+	metrics     *metrics
+	gracePeriod time.Duration
+	logger      logr.Logger
+	nodes       map[ack.StreamID]string
+	counts      map[string]int
+	evictions   map[string]*eviction
+}
+
+type eviction struct {
+	timer *time.Timer
+}
+
+func newNodeStreams(c cache.Cache, wr *workload.Resolver, t *ack.Tracker, m *metrics, gracePeriod time.Duration, l logr.Logger) *nodeStreams {
+	return &nodeStreams{
+		cache:       c,
+		resolver:    wr,
+		tracker:     t,
+		metrics:     m,
+		gracePeriod: gracePeriod,
+		logger:      l,
+		nodes:       map[ack.StreamID]string{},
+		counts:      map[string]int{},
+		evictions:   map[string]*eviction{},
+	}
+}
+
+// onRequest records the node of the stream, and cancels the eviction of the node if scheduled.
+func (n *nodeStreams) onRequest(streamID ack.StreamID, node string) {
+	if node == "" {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, ok := n.nodes[streamID]; ok {
+		return
+	}
+
+	n.nodes[streamID] = node
+	n.counts[node]++
+
+	if e, ok := n.evictions[node]; ok {
+		e.timer.Stop()
+		delete(n.evictions, node)
+	}
+}
+
+// onStreamClosed schedules the eviction of the node of the stream if it has no more open streams.
+func (n *nodeStreams) onStreamClosed(streamID ack.StreamID) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	node, ok := n.nodes[streamID]
+	if !ok {
+		return
+	}
+
+	delete(n.nodes, streamID)
+
+	n.counts[node]--
+	if n.counts[node] > 0 {
+		return
+	}
+
+	delete(n.counts, node)
+
+	n.scheduleEviction(node)
+}
+
+// onNodeDeleted schedules the eviction of the node whose workload has been deleted, unless it has open streams,
+// in which case the eviction is scheduled once they are closed.
+func (n *nodeStreams) onNodeDeleted(node string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.counts[node] > 0 {
+		return
+	}
+
+	if _, ok := n.evictions[node]; ok {
+		return
+	}
+
+	n.scheduleEviction(node)
+}
+
+// scheduleEviction evicts the node after the grace period, which must be called with the lock held.
+func (n *nodeStreams) scheduleEviction(node string) {
+	e := &eviction{}
+	e.timer = time.AfterFunc(n.gracePeriod, func() {
+		n.evict(node, e)
+	})
+	n.evictions[node] = e
+}
+
+// streamsByNode returns the open streams of each node, sorted by servers and IDs.
+func (n *nodeStreams) streamsByNode() map[string][]ack.StreamID {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	results := make(map[string][]ack.StreamID, len(n.counts))
+	for streamID, node := range n.nodes {
+		results[node] = append(results[node], streamID)
+	}
+
+	for _, streams := range results {
+		sort.Slice(streams, func(i, j int) bool {
+			if streams[i].Server != streams[j].Server {
+				return streams[i].Server < streams[j].Server
+			}
+			return streams[i].ID < streams[j].ID
+		})
+	}
+
+	return results
+}
+
+func (n *nodeStreams) evict(node string, e *eviction) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	// NOTE: the eviction has been canceled since the node has reconnected.
+	if n.evictions[node] != e {
+		return
+	}
+
+	delete(n.evictions, node)
+
+	n.cache.ClearNode(node)
+	n.resolver.Forget(node)
+	n.tracker.Forget(node)
+	n.metrics.forgetNode(node)
+	n.logger.Info("evicted resources of disconnected node", "node", node)
+}
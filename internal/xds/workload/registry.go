@@ -0,0 +1,62 @@
+package workload
+
+import (
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Registry holds the workloads declared by node.metadata, which can not be found in Kubernetes.
+type Registry struct {
+	mu    sync.RWMutex
+	items map[string]*Workload
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		items: map[string]*Workload{},
+	}
+}
+
+func (r *Registry) Set(w *Workload) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.items[w.Node] = w
+}
+
+func (r *Registry) Get(node string) (*Workload, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.items[node]
+	return w, ok
+}
+
+func (r *Registry) Delete(node string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.items, node)
+}
+
+// List returns the workloads in the namespace whose labels match the selector.
+// metav1.NamespaceAll lists workloads in all namespaces.
+func (r *Registry) List(namespace string, selector labels.Selector) []*Workload {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	workloads := []*Workload{}
+	for _, w := range r.items {
+		if namespace != metav1.NamespaceAll && w.Namespace != namespace {
+			continue
+		}
+
+		if selector.Matches(labels.Set(w.Labels)) {
+			workloads = append(workloads, w)
+		}
+	}
+
+	return workloads
+}
@@ -0,0 +1,219 @@
+package workload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	structpb "github.com/golang/protobuf/ptypes/struct"
+
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/xds/nodeid"
+)
+
+const (
+	// MetadataNamespace is the key of node.metadata which declares the namespace of the node.
+	MetadataNamespace = "NAMESPACE"
+	// MetadataLabels is the key of node.metadata which declares the labels of the node.
+	MetadataLabels = "LABELS"
+	// ClusterLabel is the label which node.cluster of nodes identified by their metadata is matched as.
+	ClusterLabel = "bootes.io/cluster"
+)
+
+var ErrNotFound = errors.New("workload not found by node id")
+
+// Node is the node of Envoy, which both xDS v2 and v3 nodes implement.
+type Node interface {
+	GetId() string
+	GetCluster() string
+	GetMetadata() *structpb.Struct
+}
+
+// Workload identifies a node by the namespace and the labels, against which workloadSelector of resources are matched.
+// Node is the key of the node, by which its resources are cached.
+type Workload struct {
+	Node      string
+	Namespace string
+	Labels    map[string]string
+}
+
+// Resolver finds workloads of nodes from pods, WorkloadEntries and, if allowed, the ones declared by node.metadata,
+// whose keys are produced by the node ID format.
+type Resolver struct {
+	store        store.Store
+	registry     *Registry
+	format       nodeid.Format
+	nodeMetadata bool
+}
+
+type Option func(r *Resolver)
+
+func NewResolver(s store.Store, r *Registry, f nodeid.Format, opts ...Option) *Resolver {
+	resolver := &Resolver{
+		store:    s,
+		registry: r,
+		format:   f,
+	}
+
+	for _, opt := range opts {
+		opt(resolver)
+	}
+
+	return resolver
+}
+
+// WithNodeMetadata allows nodes which are neither pods nor WorkloadEntries to declare their workloads by node.metadata.
+// Since node.metadata is not authenticated, any client which can reach the xDS server can then claim any namespace and labels,
+// and receive the resources of the workload, including Secrets. It must be used only if all clients are trusted.
+func WithNodeMetadata() Option {
+	return func(r *Resolver) {
+		r.nodeMetadata = true
+	}
+}
+
+// Key returns the key of the node of the workload named by the name and the namespace.
+func (r *Resolver) Key(name, namespace string) string {
+	return r.format.Key(name, namespace)
+}
+
+// NodeKey returns the key of the node identified by the node ID.
+func (r *Resolver) NodeKey(id string) string {
+	return nodeid.Key(r.format, id)
+}
+
+// Resolve returns the workload of the node, looking up the pod and then the WorkloadEntry named by the node ID,
+// and falling back to the namespace and the labels declared by node.metadata if allowed by WithNodeMetadata.
+// Workloads declared by node.metadata are registered to the Registry so that reconcilers can find them.
+func (r *Resolver) Resolve(ctx context.Context, node Node) (*Workload, error) {
+	w, err := r.getFromStore(ctx, r.NodeKey(node.GetId()))
+	if !errors.Is(err, ErrNotFound) {
+		return w, err
+	}
+
+	if !r.nodeMetadata {
+		return nil, ErrNotFound
+	}
+
+	w, ok := r.fromMetadata(node)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	r.registry.Set(w)
+
+	return w, nil
+}
+
+// Lookup returns the workload of the node which has already been resolved.
+func (r *Resolver) Lookup(ctx context.Context, key string) (*Workload, error) {
+	w, err := r.getFromStore(ctx, key)
+	if !errors.Is(err, ErrNotFound) {
+		return w, err
+	}
+
+	w, ok := r.registry.Get(key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return w, nil
+}
+
+// Forget removes the workload of the node declared by node.metadata, e.g. when the node has been disconnected.
+func (r *Resolver) Forget(key string) {
+	r.registry.Delete(key)
+}
+
+// List returns the workloads in the namespace selected by the options.
+func (r *Resolver) List(ctx context.Context, namespace string, opts ...store.ListOption) ([]*Workload, error) {
+	selector, err := store.Selector(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := r.store.ListPodsByNamespace(ctx, namespace, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	workloads := []*Workload{}
+	for _, pod := range pods.Items {
+		workloads = append(workloads, &Workload{
+			Node:      r.Key(pod.Name, pod.Namespace),
+			Namespace: pod.Namespace,
+			Labels:    pod.Labels,
+		})
+	}
+
+	entries, err := r.store.ListWorkloadEntriesByNamespace(ctx, namespace, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries.Items {
+		workloads = append(workloads, &Workload{
+			Node:      r.Key(entry.Name, entry.Namespace),
+			Namespace: entry.Namespace,
+			Labels:    entry.Spec.Labels,
+		})
+	}
+
+	return append(workloads, r.registry.List(namespace, selector)...), nil
+}
+
+func (r *Resolver) getFromStore(ctx context.Context, key string) (*Workload, error) {
+	name, namespace, ok := r.format.Parse(key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	pod, err := r.store.GetPod(ctx, name, namespace)
+	if err == nil {
+		return &Workload{
+			Node:      key,
+			Namespace: pod.Namespace,
+			Labels:    pod.Labels,
+		}, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get pod: %w", err)
+	}
+
+	entry, err := r.store.GetWorkloadEntry(ctx, name, namespace)
+	if err == nil {
+		return &Workload{
+			Node:      key,
+			Namespace: entry.Namespace,
+			Labels:    entry.Spec.Labels,
+		}, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get workload entry: %w", err)
+	}
+
+	return nil, ErrNotFound
+}
+
+func (r *Resolver) fromMetadata(node Node) (*Workload, bool) {
+	fields := node.GetMetadata().GetFields()
+
+	namespace := fields[MetadataNamespace].GetStringValue()
+	if namespace == "" {
+		return nil, false
+	}
+
+	labels := map[string]string{}
+	for key, val := range fields[MetadataLabels].GetStructValue().GetFields() {
+		labels[key] = val.GetStringValue()
+	}
+
+	if cluster := node.GetCluster(); cluster != "" {
+		labels[ClusterLabel] = cluster
+	}
+
+	return &Workload{
+		Node:      r.NodeKey(node.GetId()),
+		Namespace: namespace,
+		Labels:    labels,
+	}, true
+}
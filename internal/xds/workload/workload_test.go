@@ -0,0 +1,227 @@
+package workload_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev3 "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	api "github.com/110y/bootes/internal/k8s/api/v1"
+	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/xds/nodeid"
+	"github.com/110y/bootes/internal/xds/workload"
+)
+
+type fakeStore struct {
+	store.Store
+	pods    map[string]*corev1.Pod
+	entries map[string]*api.WorkloadEntry
+}
+
+func (s *fakeStore) GetPod(_ context.Context, name, namespace string) (*corev1.Pod, error) {
+	pod, ok := s.pods[store.ToNodeName(name, namespace)]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	return pod, nil
+}
+
+func (s *fakeStore) GetWorkloadEntry(_ context.Context, name, namespace string) (*api.WorkloadEntry, error) {
+	entry, ok := s.entries[store.ToNodeName(name, namespace)]
+	if !ok {
+		return nil, store.ErrNotFound
+	}
+
+	return entry, nil
+}
+
+func TestResolverResolve(t *testing.T) {
+	t.Parallel()
+
+	s := &fakeStore{
+		pods: map[string]*corev1.Pod{
+			"pod-1.ns-1": {
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "pod-1",
+					Namespace: "ns-1",
+					Labels:    map[string]string{"app": "pod"},
+				},
+			},
+		},
+		entries: map[string]*api.WorkloadEntry{
+			"vm-1.ns-1": {
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "vm-1",
+					Namespace: "ns-1",
+				},
+				Spec: api.WorkloadEntrySpec{
+					Labels: map[string]string{"app": "vm"},
+				},
+			},
+		},
+	}
+
+	metadata := &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			workload.MetadataNamespace: {Kind: &structpb.Value_StringValue{StringValue: "ns-2"}},
+			workload.MetadataLabels: {Kind: &structpb.Value_StructValue{StructValue: &structpb.Struct{
+				Fields: map[string]*structpb.Value{
+					"app": {Kind: &structpb.Value_StringValue{StringValue: "ci"}},
+				},
+			}}},
+		},
+	}
+
+	tests := map[string]struct {
+		format             nodeid.Format
+		nodeMetadata       bool
+		node               *corev3.Node
+		expected           *workload.Workload
+		expectedErr        error
+		expectedRegistered bool
+	}{
+		"should resolve pod": {
+			node: &corev3.Node{Id: "pod-1.ns-1", Metadata: metadata},
+			expected: &workload.Workload{
+				Node:      "pod-1.ns-1",
+				Namespace: "ns-1",
+				Labels:    map[string]string{"app": "pod"},
+			},
+		},
+		"should resolve pod by istio sidecar id": {
+			format: nodeid.Istio{},
+			node:   &corev3.Node{Id: "sidecar~10.0.0.1~pod-1.ns-1~ns-1.svc.cluster.local"},
+			expected: &workload.Workload{
+				Node:      "sidecar~~pod-1.ns-1~ns-1.svc.cluster.local",
+				Namespace: "ns-1",
+				Labels:    map[string]string{"app": "pod"},
+			},
+		},
+		"should resolve workload entry": {
+			node: &corev3.Node{Id: "vm-1.ns-1"},
+			expected: &workload.Workload{
+				Node:      "vm-1.ns-1",
+				Namespace: "ns-1",
+				Labels:    map[string]string{"app": "vm"},
+			},
+		},
+		"should resolve node metadata and cluster": {
+			nodeMetadata: true,
+			node:         &corev3.Node{Id: "ci-runner", Cluster: "ci", Metadata: metadata},
+			expected: &workload.Workload{
+				Node:      "ci-runner",
+				Namespace: "ns-2",
+				Labels: map[string]string{
+					"app":                 "ci",
+					workload.ClusterLabel: "ci",
+				},
+			},
+			expectedRegistered: true,
+		},
+		"should not resolve node without namespace in metadata": {
+			nodeMetadata: true,
+			node:         &corev3.Node{Id: "unknown", Cluster: "ci"},
+			expectedErr:  workload.ErrNotFound,
+		},
+		"should not resolve node metadata unless allowed": {
+			node:        &corev3.Node{Id: "ci-runner", Cluster: "ci", Metadata: metadata},
+			expectedErr: workload.ErrNotFound,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			format := test.format
+			if format == nil {
+				format = nodeid.NameNamespace{}
+			}
+
+			var opts []workload.Option
+			if test.nodeMetadata {
+				opts = append(opts, workload.WithNodeMetadata())
+			}
+
+			r := workload.NewRegistry()
+
+			actual, err := workload.NewResolver(s, r, format, opts...).Resolve(context.Background(), test.node)
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if diff := cmp.Diff(test.expected, actual); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+
+			registered := len(r.List(metav1.NamespaceAll, labels.Everything())) != 0
+			if registered != test.expectedRegistered {
+				t.Errorf("unexpected registered: %t", registered)
+			}
+		})
+	}
+}
+
+func TestRegistryList(t *testing.T) {
+	t.Parallel()
+
+	w1 := &workload.Workload{Node: "node-1", Namespace: "ns-1", Labels: map[string]string{"app": "a"}}
+	w2 := &workload.Workload{Node: "node-2", Namespace: "ns-1", Labels: map[string]string{"app": "b"}}
+	w3 := &workload.Workload{Node: "node-3", Namespace: "ns-2", Labels: map[string]string{"app": "a"}}
+
+	r := workload.NewRegistry()
+	r.Set(w1)
+	r.Set(w2)
+	r.Set(w3)
+	r.Delete(w2.Node)
+
+	tests := map[string]struct {
+		namespace string
+		selector  labels.Selector
+		expected  []*workload.Workload
+	}{
+		"should list workloads in the namespace": {
+			namespace: "ns-1",
+			selector:  labels.Everything(),
+			expected:  []*workload.Workload{w1},
+		},
+		"should list workloads matching the selector in all namespaces": {
+			namespace: metav1.NamespaceAll,
+			selector:  labels.SelectorFromSet(labels.Set{"app": "a"}),
+			expected:  []*workload.Workload{w1, w3},
+		},
+		"should list nothing": {
+			namespace: "ns-2",
+			selector:  labels.SelectorFromSet(labels.Set{"app": "b"}),
+			expected:  []*workload.Workload{},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := r.List(test.namespace, test.selector)
+			byNode := cmp.Transformer("byNode", func(ws []*workload.Workload) map[string]*workload.Workload {
+				m := make(map[string]*workload.Workload, len(ws))
+				for _, w := range ws {
+					m[w.Node] = w
+				}
+				return m
+			})
+
+			if diff := cmp.Diff(test.expected, actual, byNode); diff != "" {
+				t.Errorf("\n(-expected, +actual)\n%s", diff)
+			}
+		})
+	}
+}
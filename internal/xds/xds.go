@@ -2,32 +2,62 @@ package xds
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"sync/atomic"
 
 	"github.com/110y/bootes/internal/k8s/store"
+	"github.com/110y/bootes/internal/xds/ack"
 	"github.com/110y/bootes/internal/xds/cache"
+	"github.com/110y/bootes/internal/xds/internal/delta"
 	xdsgrpc "github.com/110y/bootes/internal/xds/internal/grpc"
+	"github.com/110y/bootes/internal/xds/nodeid"
+	"github.com/110y/bootes/internal/xds/workload"
+	discoveryv3 "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	xdscache "github.com/envoyproxy/go-control-plane/pkg/cache/v2"
+	xdscachev3 "github.com/envoyproxy/go-control-plane/pkg/cache/v3"
 	server "github.com/envoyproxy/go-control-plane/pkg/server/v2"
+	serverv3 "github.com/envoyproxy/go-control-plane/pkg/server/v3"
 	"github.com/go-logr/logr"
 	"google.golang.org/grpc"
 )
 
+const (
+	stateNotStarted int32 = iota
+	stateServing
+	stateStopped
+)
+
 type Server struct {
 	grpcServer *grpc.Server
 	listener   net.Listener
+	streams    *nodeStreams
 	logger     logr.Logger
+	state      int32
 }
 
-func NewServer(ctx context.Context, sc xdscache.SnapshotCache, c cache.Cache, s store.Store, l logr.Logger, config *Config) (*Server, error) {
-	srv := server.NewServer(ctx, sc, newCallbacks(c, s, l.WithName("callbacks")))
+func NewServer(ctx context.Context, sc xdscache.SnapshotCache, scv3 xdscachev3.SnapshotCache, c cache.Cache, s store.Store, wr *workload.Resolver, t *ack.Tracker, r NACKReporter, l logr.Logger, config *Config) (*Server, error) {
+	m, err := newMetrics(config.MetricsRegisterer)
+	if err != nil {
+		return nil, err
+	}
+
+	cb := newCallbacks(c, s, wr, t, r, m, config.NodeEvictionGracePeriod, l.WithName("callbacks"))
+	go cb.reports.run(ctx)
+	srv := server.NewServer(ctx, sc, cb)
+	cbv3 := newCallbacksV3(cb.forServer(serverV3))
+	ds := delta.NewServer(ctx, nodeKeyCache{cache: c, format: config.NodeIDFormat}, newCallbacksV3(cb.forServer(serverDelta)))
+	srvV3 := &aggregatedDiscoveryServerV3{
+		Server: serverv3.NewServer(ctx, scv3, cbv3),
+		delta:  ds,
+	}
 
 	gc := &xdsgrpc.Config{
 		EnableChannelz:   config.EnableGRPCChannelz,
 		EnableReflection: config.EnableGRPCReflection,
 	}
-	gs := xdsgrpc.NewServer(ctx, srv, gc)
+	gs := xdsgrpc.NewServer(ctx, srv, srvV3, ds, gc)
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", config.Port))
 	if err != nil {
@@ -37,19 +67,38 @@ func NewServer(ctx context.Context, sc xdscache.SnapshotCache, c cache.Cache, s
 	return &Server{
 		grpcServer: gs,
 		listener:   lis,
+		streams:    cb.streams,
 		logger:     l,
 	}, nil
 }
 
-func NewSnapshotCache(l logr.Logger) xdscache.SnapshotCache {
-	return xdscache.NewSnapshotCache(true, xdscache.IDHash{}, newSnapshotCacheLogger(l))
+// aggregatedDiscoveryServerV3 serves the incremental xDS protocol by the delta server
+// since serverv3.Server does not implement it.
+type aggregatedDiscoveryServerV3 struct {
+	serverv3.Server
+	delta *delta.Server
+}
+
+func (s *aggregatedDiscoveryServerV3) DeltaAggregatedResources(stream discoveryv3.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
+	return s.delta.DeltaAggregatedResources(stream)
+}
+
+func NewSnapshotCache(f nodeid.Format, l logr.Logger) xdscache.SnapshotCache {
+	return xdscache.NewSnapshotCache(true, nodeHash{format: f}, newSnapshotCacheLogger(l))
+}
+
+func NewSnapshotCacheV3(f nodeid.Format, l logr.Logger) xdscachev3.SnapshotCache {
+	return xdscachev3.NewSnapshotCache(true, nodeHashV3{format: f}, newSnapshotCacheLogger(l))
 }
 
 func (s *Server) Start(stopCh chan struct{}) error {
 	errCh := make(chan error, 1)
 	go func() {
 		s.logger.Info("starting xds server")
-		errCh <- s.grpcServer.Serve(s.listener)
+		atomic.StoreInt32(&s.state, stateServing)
+		err := s.grpcServer.Serve(s.listener)
+		atomic.StoreInt32(&s.state, stateStopped)
+		errCh <- err
 	}()
 
 	select {
@@ -61,3 +110,35 @@ func (s *Server) Start(stopCh chan struct{}) error {
 		return err
 	}
 }
+
+// Ready returns an error unless the server is serving on its listener.
+// The listener is bound and the snapshot caches are initialized when the server is created.
+func (s *Server) Ready() error {
+	switch atomic.LoadInt32(&s.state) {
+	case stateServing:
+		return nil
+	case stateStopped:
+		return errors.New("xds server has stopped")
+	default:
+		return errors.New("xds server has not started yet")
+	}
+}
+
+// Alive returns an error if the server has stopped serving, e.g. since its listener has been closed.
+func (s *Server) Alive() error {
+	if atomic.LoadInt32(&s.state) == stateStopped {
+		return errors.New("xds server has stopped")
+	}
+
+	return nil
+}
+
+// EvictNode evicts the node whose workload has been deleted after the grace period, in the same way as the node which has disconnected.
+func (s *Server) EvictNode(node string) {
+	s.streams.onNodeDeleted(node)
+}
+
+// ConnectedNodes returns the open streams of each node connected to the server over any version of the protocol.
+func (s *Server) ConnectedNodes() map[string][]ack.StreamID {
+	return s.streams.streamsByNode()
+}
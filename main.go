@@ -1,9 +1,12 @@
 package main
 
 import (
-	"github.com/110y/bootes/internal/server"
+	"context"
+	"os"
+
+	"github.com/110y/bootes/internal/cli"
 )
 
 func main() {
-	server.Run()
+	os.Exit(cli.Run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }